@@ -0,0 +1,315 @@
+// agenda.go
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dueIn returns in how many calendar days a task is due: negative when the
+// day has passed, 0 for today. ok is false when it has no due date. Days
+// rather than hours decide, so a task due this morning is due today, not
+// overdue, until the day is over, as Task.IsOverdue has it.
+func dueIn(t Task, now time.Time) (days int, ok bool) {
+	if t.DueDate == nil {
+		return 0, false
+	}
+	return calendarDays(now, *t.DueDate), true
+}
+
+// startsToday reports whether a task's start date falls on today.
+func startsToday(t Task, now time.Time) bool {
+	return t.StartDate != nil && calendarDays(now, *t.StartDate) == 0
+}
+
+var todayFlags = []flagDef{boolFlag("if-any"), jsonFlag, jsonlFlag, colorFlag}
+
+func cmdToday(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, todayFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("today")
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var overdue, due, starting Tasks
+	others := 0
+	for _, t := range ts {
+		if t.Done {
+			continue
+		}
+		days, ok := dueIn(t, now)
+		switch {
+		case t.IsOverdue(now):
+			overdue = append(overdue, t)
+		case ok && days == 0:
+			due = append(due, t)
+		case startsToday(t, now):
+			starting = append(starting, t)
+		default:
+			others++
+		}
+	}
+	if wantsJSON(ca) {
+		return printJSON(ca, append(append(overdue, due...), starting...))
+	}
+	if len(overdue)+len(due)+len(starting) == 0 {
+		if !ca.has("if-any") {
+			fmt.Printf("Nothing due today — %d other pending tasks.\n", others)
+		}
+		return nil
+	}
+	var counts []string
+	for _, s := range []struct {
+		heading, count string
+		ts             Tasks
+	}{
+		{"Overdue", "overdue", overdue},
+		{"Due today", "due today", due},
+		{"Starting today", "starting today", starting},
+	} {
+		if len(s.ts) == 0 {
+			continue
+		}
+		fmt.Println(paint(s.heading+":", ansiBold))
+		sortForDisplay(s.ts)
+		printTree(s.ts)
+		counts = append(counts, fmt.Sprintf("%d %s", len(s.ts), s.count))
+	}
+	fmt.Printf("%s, %d other pending.\n", strings.Join(counts, ", "), others)
+	return nil
+}
+
+// defaultAgendaDays is how many days, today included, agenda shows one by
+// one before the rest go under Later.
+const defaultAgendaDays = 7
+
+var agendaFlags = []flagDef{valueFlag("days", "d"), jsonFlag, jsonlFlag, colorFlag}
+
+func cmdAgenda(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, agendaFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("agenda")
+	}
+	days := defaultAgendaDays
+	if ca.has("days") {
+		if days, err = strconv.Atoi(ca.value("days")); err != nil || days < 1 {
+			return usageErrorf("invalid --days value %q: use a number of days, 1 or more", ca.value("days"))
+		}
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	// one group per day of the window, then overdue, later and undated
+	groups := make([]Tasks, days+3)
+	overdue, later, undated := days, days+1, days+2
+	for _, t := range ts.Filter(func(t Task) bool { return !t.Done }) {
+		d, ok := dueIn(t, now)
+		switch {
+		case !ok:
+			groups[undated] = append(groups[undated], t)
+		case t.IsOverdue(now):
+			groups[overdue] = append(groups[overdue], t)
+		case d >= days:
+			groups[later] = append(groups[later], t)
+		default:
+			groups[d] = append(groups[d], t)
+		}
+	}
+	order := append([]int{overdue}, make([]int, days)...)
+	for d := range days {
+		order[d+1] = d
+	}
+	order = append(order, later, undated)
+	for _, g := range groups {
+		sort.SliceStable(g, func(i, j int) bool {
+			if ri, rj := priorityRank(g[i].Priority), priorityRank(g[j].Priority); ri != rj {
+				return ri < rj
+			}
+			return g[i].ID < g[j].ID
+		})
+	}
+	if wantsJSON(ca) {
+		var all Tasks
+		for _, i := range order {
+			all = append(all, groups[i]...)
+		}
+		return printJSON(ca, all)
+	}
+	shown := false
+	for _, i := range order {
+		if len(groups[i]) == 0 {
+			continue
+		}
+		if shown {
+			fmt.Println()
+		}
+		shown = true
+		fmt.Println(paint(agendaHeading(i, days, now)+":", ansiBold))
+		for _, t := range groups[i] {
+			printTask(t)
+		}
+	}
+	if !shown {
+		fmt.Println("No pending tasks.")
+	}
+	return nil
+}
+
+// agendaHeading names agenda group i: a day of the window, counted from
+// today, or one of the three groups after them.
+func agendaHeading(i, days int, now time.Time) string {
+	switch i {
+	case 0:
+		return "Today"
+	case 1:
+		if days > 1 {
+			return "Tomorrow"
+		}
+	}
+	switch i - days {
+	case 0:
+		return "Overdue"
+	case 1:
+		return "Later"
+	case 2:
+		return "No due date"
+	}
+	day := startOfDay(now).AddDate(0, 0, i)
+	return day.Format("Monday 2006-01-02")
+}
+
+var countFlags = []flagDef{
+	boolFlag("done"), boolFlag("all", "a"), boolFlag("overdue"), boolFlag("due-today"), valueFlag("tag", "t"),
+}
+
+// cmdCount prints a bare number for scripts and status lines. The tasks
+// counted by default are the ones list shows: pending and not deferred.
+// Overdue and due today are the sections of todo today, so the numbers
+// agree with it.
+func cmdCount(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, countFlags...)
+	if err != nil {
+		return err
+	}
+	narrow := ca.has("overdue") || ca.has("due-today")
+	if len(ca.pos) > 0 || (ca.has("done") && (ca.has("all") || narrow)) {
+		return usageError("count")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tag := normalizeTag(ca.value("tag"))
+	n := 0
+	for _, t := range ts {
+		if ca.has("tag") && !t.HasTag(tag) {
+			continue
+		}
+		days, due := dueIn(t, now)
+		switch {
+		case narrow:
+			if t.Done || !due || !(ca.has("overdue") && t.IsOverdue(now) || ca.has("due-today") && days == 0) {
+				continue
+			}
+		case ca.has("all"):
+		case ca.has("done"):
+			if !t.Done {
+				continue
+			}
+		default:
+			if t.Done || t.IsDeferred(now) {
+				continue
+			}
+		}
+		n++
+	}
+	fmt.Println(n)
+	return nil
+}
+
+var nextFlags = []flagDef{valueFlag("n"), boolFlag("random"), jsonFlag, jsonlFlag, colorFlag}
+
+// cmdNext shows the task to do next: the most important pending task that
+// can be started now, by priority, then due date, then age.
+func cmdNext(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, nextFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("next")
+	}
+	n := 1
+	if ca.has("n") {
+		if n, err = strconv.Atoi(ca.value("n")); err != nil || n < 1 {
+			return usageErrorf("invalid -n value %q: use a number, 1 or more", ca.value("n"))
+		}
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	ready := ts.Filter(func(t Task) bool { return !t.Done && !t.Blocked && !t.IsDeferred(now) })
+	if ca.has("random") {
+		rand.Shuffle(len(ready), func(i, j int) { ready[i], ready[j] = ready[j], ready[i] })
+	} else {
+		sort.SliceStable(ready, func(i, j int) bool { return nextBefore(ready[i], ready[j]) })
+	}
+	ready = ready[:min(n, len(ready))]
+	if wantsJSON(ca) {
+		return printJSON(ca, ready)
+	}
+	if len(ready) == 0 {
+		fmt.Println("Nothing to do.")
+		return nil
+	}
+	for _, t := range ready {
+		fmt.Println(taskLine(t))
+	}
+	return nil
+}
+
+// nextBefore orders tasks for next: higher priority, then the earlier due
+// date with undated tasks last, then the older task.
+func nextBefore(a, b Task) bool {
+	if ra, rb := priorityRank(a.Priority), priorityRank(b.Priority); ra != rb {
+		return ra < rb
+	}
+	switch {
+	case a.DueDate != nil && b.DueDate != nil && !a.DueDate.Equal(*b.DueDate):
+		return a.DueDate.Before(*b.DueDate)
+	case (a.DueDate == nil) != (b.DueDate == nil):
+		return a.DueDate != nil
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
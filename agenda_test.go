@@ -0,0 +1,49 @@
+// agenda_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOverdueAgrees checks that a task due today is due today, not
+// overdue, to every command that tells the two apart.
+func TestOverdueAgrees(t *testing.T) {
+	testEnv(t)
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	for _, args := range [][]string{
+		{"add", "Due today", "--due", "today"},
+		{"add", "Due yesterday", "--due", yesterday},
+		{"add", "Due tomorrow", "--due", "tomorrow"},
+	} {
+		if code, _ := runTodo(t, args...); code != exitOK {
+			t.Fatalf("todo %s exited %d", strings.Join(args, " "), code)
+		}
+	}
+	code, out := runTodo(t, "overdue")
+	if code != exitError || !strings.Contains(out, "2) [ ] Due yesterday (1 day overdue)") || strings.Contains(out, "Due today") {
+		t.Errorf("overdue exited %d:\n%s", code, out)
+	}
+	if _, out := runTodo(t, "count", "--overdue"); out != "1\n" {
+		t.Errorf("count --overdue = %q, want 1", out)
+	}
+	if _, out := runTodo(t, "count", "--due-today"); out != "1\n" {
+		t.Errorf("count --due-today = %q, want 1", out)
+	}
+	_, out = runTodo(t, "today")
+	overdue, due, ok := strings.Cut(out, "Due today:")
+	if !ok || !strings.Contains(overdue, "Due yesterday") || strings.Contains(overdue, "Due today") || !strings.Contains(due, "1) [ ] Due today") {
+		t.Errorf("today printed:\n%s", out)
+	}
+	_, out = runTodo(t, "agenda")
+	if overdue, _, _ := strings.Cut(out, "Today"); strings.Contains(overdue, "Due today") {
+		t.Errorf("agenda lists the task due today as overdue:\n%s", out)
+	}
+	_, out = runTodo(t, "list", "--color", "always")
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if red := strings.Contains(line, "\x1b["+ansiRed); red != strings.Contains(line, "Due yesterday") {
+			t.Errorf("list paints red = %v: %q", red, line)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// args.go
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// flagDef declares a flag accepted by a command. The first name is the
+// canonical one used for lookups, the rest are aliases.
+type flagDef struct {
+	names   []string
+	boolean bool
+}
+
+func valueFlag(names ...string) flagDef { return flagDef{names: names} }
+func boolFlag(names ...string) flagDef  { return flagDef{names: names, boolean: true} }
+
+// cmdArgs is the result of parseArgs: positional arguments in order and the
+// flags that were given, keyed by canonical name.
+type cmdArgs struct {
+	pos    []string
+	values map[string][]string
+}
+
+// parseArgs separates flags from positional arguments. Flags may appear
+// anywhere as -x value, --name value or --name=value. "--" ends flag parsing,
+// and a lone "-" or a negative number is treated as positional.
+func parseArgs(args []string, defs ...flagDef) (cmdArgs, error) {
+	lookup := map[string]flagDef{}
+	for _, d := range defs {
+		for _, n := range d.names {
+			lookup[n] = d
+		}
+	}
+	ca := cmdArgs{values: map[string][]string{}}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			ca.pos = append(ca.pos, args[i+1:]...)
+			break
+		}
+		if len(a) < 2 || a[0] != '-' || isNumber(a) {
+			ca.pos = append(ca.pos, a)
+			continue
+		}
+		name := strings.TrimLeft(a, "-")
+		value, hasValue := "", false
+		if k := strings.IndexByte(name, '='); k >= 0 {
+			name, value, hasValue = name[:k], name[k+1:], true
+		}
+		d, ok := lookup[name]
+		if !ok {
+			return ca, usageErrorf("unknown flag %s", a)
+		}
+		key := d.names[0]
+		if d.boolean {
+			if hasValue {
+				return ca, usageErrorf("flag %s takes no value", a)
+			}
+			ca.values[key] = append(ca.values[key], "")
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return ca, usageErrorf("flag %s needs a value", a)
+			}
+			i++
+			value = args[i]
+		}
+		ca.values[key] = append(ca.values[key], value)
+	}
+	return ca, nil
+}
+
+func (c cmdArgs) has(name string) bool {
+	_, ok := c.values[name]
+	return ok
+}
+
+// value returns the last value given for a flag, or "" if it was not set.
+func (c cmdArgs) value(name string) string {
+	vs := c.values[name]
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[len(vs)-1]
+}
+
+func (c cmdArgs) all(name string) []string {
+	return c.values[name]
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
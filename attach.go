@@ -0,0 +1,159 @@
+// attach.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var attachFlags = []flagDef{boolFlag("force", "f")}
+
+// cmdAttach records files that go with a task by their absolute paths, so
+// they are found from any directory. A file must exist unless --force is
+// given, for one on a drive that isn't mounted, say.
+func cmdAttach(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, attachFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) < 2 {
+		return usageError("attach")
+	}
+	id, err := parseID(ca.pos[0])
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for _, p := range ca.pos[1:] {
+		abs, err := attachmentPath(p)
+		if err != nil {
+			return err
+		}
+		if !ca.has("force") {
+			if _, err := os.Stat(abs); err != nil {
+				return fmt.Errorf("%s: %v; --force attaches it anyway", abs, errorText(err))
+			}
+		}
+		paths = append(paths, abs)
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	t := &ts[i]
+	var added []string
+	for _, p := range paths {
+		if slices.Contains(t.Attachments, p) {
+			say("%s is already attached to %d.\n", p, id)
+			continue
+		}
+		t.Attachments = append(t.Attachments, p)
+		added = append(added, p)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	for _, p := range added {
+		say("Attached %s to %d as %d\n", p, id, slices.Index(t.Attachments, p)+1)
+	}
+	return nil
+}
+
+// attachmentPath makes p absolute, reading a leading ~ as the home
+// directory for paths the shell didn't expand.
+func attachmentPath(p string) (string, error) {
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		p = filepath.Join(home, p[1:])
+	}
+	return filepath.Abs(p)
+}
+
+// errorText is the reason in a path error, without the path it repeats.
+func errorText(err error) error {
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		return pe.Err
+	}
+	return err
+}
+
+// cmdDetach removes attachments from a task by their numbers in show.
+// The files themselves are left alone.
+func cmdDetach(args []string) error {
+	_ = args
+	if len(args) < 2 {
+		return usageError("detach")
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	t := &ts[i]
+	var drop []int
+	for _, a := range args[1:] {
+		n, err := attachmentIndex(*t, a)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(drop, n) {
+			drop = append(drop, n)
+		}
+	}
+	var removed []string
+	kept := t.Attachments[:0:0]
+	for n, p := range t.Attachments {
+		if slices.Contains(drop, n) {
+			removed = append(removed, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	t.Attachments = kept
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	for _, p := range removed {
+		say("Detached %s from %d\n", p, id)
+	}
+	return nil
+}
+
+// attachmentIndex reads the number of one of t's attachments, counting
+// from 1 as show does, and returns its index.
+func attachmentIndex(t Task, s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	switch {
+	case len(t.Attachments) == 0:
+		return 0, notFoundErrorf("task %d has no attachments", t.ID)
+	case err != nil || n < 1:
+		return 0, usageErrorf("invalid attachment number %q", s)
+	case n > len(t.Attachments):
+		return 0, notFoundErrorf("task %d has %d attachments; there is no %d", t.ID, len(t.Attachments), n)
+	}
+	return n - 1, nil
+}
@@ -0,0 +1,261 @@
+// backup.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// defaultBackups is how many backups are kept when the backups config key
+// is unset.
+const defaultBackups = 10
+
+// backupLayout is the timestamp in backup names, which sorts in time order.
+const backupLayout = "20060102-150405.000"
+
+// backup is a copy of a list taken before a save.
+type backup struct {
+	stamp string
+	path  string
+}
+
+func keepBackups() int {
+	if config.Backups != nil {
+		return *config.Backups
+	}
+	return defaultBackups
+}
+
+// backupFiles returns the directory holding the current list's backups,
+// the list's name there, and its backups from oldest to newest.
+func backupFiles() (dir, stem string, bs []backup, err error) {
+	path, err := tasksFilePath()
+	if err != nil {
+		return "", "", nil, err
+	}
+	base := filepath.Base(path)
+	stem = strings.TrimSuffix(base, filepath.Ext(base))
+	dir = filepath.Join(filepath.Dir(path), "backups")
+	if state := projectStateDir(path); state != "" {
+		dir, stem = filepath.Join(state, "backups"), "tasks"
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", nil, err
+	}
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), stem+"-")
+		if !ok {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, ".json")
+		// another list whose name starts with this one's doesn't parse
+		if _, err := time.Parse(backupLayout, stamp); !ok || err != nil || e.IsDir() {
+			continue
+		}
+		bs = append(bs, backup{stamp, filepath.Join(dir, e.Name())})
+	}
+	return dir, stem, bs, nil
+}
+
+// backupTasks copies the stored list into the backups directory before a
+// save replaces it, unless it matches the newest backup, then prunes all
+// but the newest keepBackups().
+func backupTasks(s taskStore) error {
+	keep := keepBackups()
+	if keep == 0 {
+		return nil
+	}
+	data, err := storedList(s)
+	if data == nil || err != nil {
+		return err
+	}
+	dir, stem, bs, err := backupFiles()
+	if err != nil {
+		return err
+	}
+	if len(bs) > 0 {
+		if last, err := os.ReadFile(bs[len(bs)-1].path); err == nil && sameContents(last, data) {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	stamp := time.Now().Format(backupLayout)
+	path := filepath.Join(dir, stem+"-"+stamp+".json")
+	if err := todo.WriteFileAtomic(path, data); err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(bs, func(b backup) bool { return b.stamp == stamp }) {
+		bs = append(bs, backup{stamp, path})
+	}
+	for len(bs) > keep {
+		if err := os.Remove(bs[0].path); err != nil {
+			return err
+		}
+		bs = bs[1:]
+	}
+	return nil
+}
+
+// storedList returns the list as it is stored now, encoded as a tasks
+// file, or nil when there is nothing worth keeping.
+func storedList(s taskStore) ([]byte, error) {
+	if fs, ok := s.(*todo.FileStore); ok {
+		b, err := os.ReadFile(fs.Path)
+		// a corrupted file has been backed up by Load already
+		if os.IsNotExist(err) || (err == nil && !todo.IsEncrypted(b) && !json.Valid(b)) {
+			return nil, nil
+		}
+		return b, err
+	}
+	// read through a second store so the one about to save keeps the
+	// state it loaded
+	path, err := tasksFilePath()
+	if err != nil {
+		return nil, err
+	}
+	other := listStore(path)
+	defer closeStore(other)
+	ts, err := other.Load()
+	if err != nil || len(ts) == 0 {
+		return nil, err
+	}
+	b, err := json.MarshalIndent(ts, "", "  ")
+	if err != nil || passphrase == "" {
+		return b, err
+	}
+	return todo.Encrypt(b, passphrase)
+}
+
+// sameContents compares two task files, looking through encryption, whose
+// random nonce makes every copy differ.
+func sameContents(a, b []byte) bool {
+	if todo.IsEncrypted(a) && todo.IsEncrypted(b) {
+		var err1, err2 error
+		a, err1 = todo.Decrypt(a, passphrase)
+		b, err2 = todo.Decrypt(b, passphrase)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+	}
+	return bytes.Equal(a, b)
+}
+
+func findBackup(bs []backup, stamp string) (backup, error) {
+	var found []backup
+	for _, b := range bs {
+		if b.stamp == stamp {
+			return b, nil
+		}
+		if strings.HasPrefix(b.stamp, stamp) {
+			found = append(found, b)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return backup{}, notFoundErrorf("no backup %s (see todo backups)", stamp)
+	case 1:
+		return found[0], nil
+	}
+	return backup{}, usageErrorf("%s matches %d backups; give more of the timestamp", stamp, len(found))
+}
+
+func cmdBackups(args []string) error {
+	_ = args
+	if len(args) > 0 {
+		return usageError("backups")
+	}
+	_, _, bs, err := backupFiles()
+	if err != nil {
+		return err
+	}
+	if len(bs) == 0 {
+		fmt.Println("No backups.")
+		return nil
+	}
+	for _, b := range slices.Backward(bs) {
+		ts, err := readTasksFile(b.path)
+		if err != nil {
+			fmt.Printf("%s  (unreadable)\n", b.stamp)
+			continue
+		}
+		pending := len(ts.Filter(func(t Task) bool { return !t.Done }))
+		fmt.Printf("%s  %d pending / %d total\n", b.stamp, pending, len(ts))
+	}
+	return nil
+}
+
+var restoreBackupFlags = []flagDef{boolFlag("force", "f")}
+
+// cmdRestoreBackup replaces the current list with a backup. The list being
+// replaced is itself backed up by the save, so a restore can be undone.
+func cmdRestoreBackup(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, restoreBackupFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) != 1 {
+		return usageError("restore-backup")
+	}
+	_, _, bs, err := backupFiles()
+	if err != nil {
+		return err
+	}
+	b, err := findBackup(bs, ca.pos[0])
+	if err != nil {
+		return err
+	}
+	ts, err := readTasksFile(b.path)
+	if err != nil {
+		return err
+	}
+	current, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	if !ca.has("force") {
+		if len(current) > 0 {
+			if !isTerminal(os.Stdin) {
+				return errors.New("refusing to restore without --force when stdin is not a terminal")
+			}
+			ok, err := confirm(fmt.Sprintf("Replace %d tasks with the %d from backup %s?", len(current), len(ts), b.stamp))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Aborted.")
+				return nil
+			}
+		}
+		// the lock is only taken once answered; the answer was about current
+		unlock, err := lockTasks()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		answered := current
+		if current, err = loadTasks(); err != nil {
+			return err
+		}
+		if listChanged(answered, current) {
+			return errors.New("the list changed while you were answering; nothing was restored")
+		}
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	say("Restored %d tasks from backup %s.\n", len(ts), b.stamp)
+	return nil
+}
@@ -0,0 +1,152 @@
+// board.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// boardDone is how many of the latest completed tasks board shows.
+const boardDone = 10
+
+var boardFlags = []flagDef{valueFlag("tag", "t"), colorFlag}
+
+// boardColumn is one status on the board, with the number of tasks in it
+// beyond those listed.
+type boardColumn struct {
+	heading string
+	tasks   Tasks
+	more    int
+}
+
+// cmdBoard shows the pending tasks that aren't deferred, split into to
+// do, doing and waiting, beside the latest completed ones. On a terminal
+// the columns sit side by side, clipped to its width; otherwise they are
+// stacked.
+func cmdBoard(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, boardFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("board")
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	if ca.has("tag") {
+		tag := normalizeTag(ca.value("tag"))
+		ts = ts.Filter(func(t Task) bool { return t.HasTag(tag) })
+	}
+	now := time.Now()
+	byState := map[string]Tasks{}
+	for _, t := range ts {
+		if !t.Done && t.IsDeferred(now) {
+			continue
+		}
+		byState[t.State()] = append(byState[t.State()], t)
+	}
+	for _, g := range byState {
+		sortForDisplay(g)
+		pinnedFirst(g)
+	}
+	done := byState[todo.StatusDone]
+	slices.SortStableFunc(done, func(a, b Task) int { return completedAt(b).Compare(completedAt(a)) })
+	cols := []boardColumn{
+		{heading: "To do", tasks: byState[todo.StatusTodo]},
+		{heading: "Doing", tasks: byState[todo.StatusDoing]},
+	}
+	// waiting tasks get a column only when there are some
+	if w := byState[todo.StatusWaiting]; len(w) > 0 {
+		cols = append(cols, boardColumn{heading: "Waiting", tasks: w})
+	}
+	last := boardColumn{heading: "Done", tasks: done}
+	if n := len(done); n > boardDone {
+		last.tasks, last.more = done[:boardDone], n-boardDone
+	}
+	cols = append(cols, last)
+	if !isTerminal(os.Stdout) {
+		for i, c := range cols {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s (%d):\n", c.heading, len(c.tasks)+c.more)
+			for _, t := range c.tasks {
+				fmt.Println(boardLine(t))
+			}
+			if c.more > 0 {
+				fmt.Printf("... %d more\n", c.more)
+			}
+		}
+		return nil
+	}
+	_, width := terminalSize()
+	const gap = 2
+	w := max((width-gap*(len(cols)-1))/len(cols), 8)
+	rows := 0
+	for _, c := range cols {
+		rows = max(rows, len(c.tasks)+min(c.more, 1))
+	}
+	// headings and cells are padded before painting so escape codes don't
+	// count towards the width
+	cell := func(s string, style ...string) string {
+		s = clip(s, w)
+		return paint(s, style...) + strings.Repeat(" ", w-utf8.RuneCountInString(s))
+	}
+	line := make([]string, len(cols))
+	for i, c := range cols {
+		line[i] = cell(fmt.Sprintf("%s (%d)", c.heading, len(c.tasks)+c.more), ansiBold)
+	}
+	fmt.Println(strings.TrimRight(strings.Join(line, strings.Repeat(" ", gap)), " "))
+	for i := range line {
+		line[i] = cell(strings.Repeat("─", w))
+	}
+	fmt.Println(strings.Join(line, strings.Repeat(" ", gap)))
+	for r := range rows {
+		for i, c := range cols {
+			switch {
+			case r < len(c.tasks):
+				var style []string
+				if c.tasks[r].Done {
+					style = append(style, ansiDim)
+				} else if c.tasks[r].IsOverdue(now) {
+					style = append(style, ansiRed)
+				}
+				line[i] = cell(boardLine(c.tasks[r]), style...)
+			case r == len(c.tasks) && c.more > 0:
+				line[i] = cell(fmt.Sprintf("... %d more", c.more), ansiDim)
+			default:
+				line[i] = cell("")
+			}
+		}
+		fmt.Println(strings.TrimRight(strings.Join(line, strings.Repeat(" ", gap)), " "))
+	}
+	return nil
+}
+
+// boardLine is a task on the board: taskLine without the checkbox, which
+// the column already says.
+func boardLine(t Task) string {
+	id, rest, _ := strings.Cut(taskLine(t), " [")
+	_, rest, _ = strings.Cut(rest, "] ")
+	return id + " " + rest
+}
+
+// completedAt is when a task was completed, or the zero time.
+func completedAt(t Task) time.Time {
+	if t.CompletedAt == nil {
+		return time.Time{}
+	}
+	return *t.CompletedAt
+}
@@ -0,0 +1,107 @@
+// cal.go
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var calFlags = []flagDef{boolFlag("next"), boolFlag("prev"), colorFlag}
+
+func cmdCal(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, calFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 1 || (ca.has("next") && ca.has("prev")) {
+		return usageError("cal")
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	now := time.Now()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	if len(ca.pos) == 1 {
+		if month, err = time.ParseInLocation("2006-01", ca.pos[0], time.Local); err != nil {
+			return usageErrorf("invalid month %q: use YYYY-MM", ca.pos[0])
+		}
+	}
+	switch {
+	case ca.has("next"):
+		month = month.AddDate(0, 1, 0)
+	case ca.has("prev"):
+		month = month.AddDate(0, -1, 0)
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	due := map[int]int{}
+	overdue := map[int]bool{}
+	total := 0
+	for _, t := range ts {
+		if t.Done || t.DueDate == nil {
+			continue
+		}
+		d := t.DueDate.In(time.Local)
+		if d.Year() != month.Year() || d.Month() != month.Month() {
+			continue
+		}
+		due[d.Day()]++
+		total++
+		// a task due today isn't overdue until tomorrow
+		if t.IsOverdue(now) {
+			overdue[d.Day()] = true
+		}
+	}
+	printMonth(month, now, due, overdue)
+	fmt.Printf("\n%d tasks due. * today, ! overdue\n", total)
+	return nil
+}
+
+// printMonth draws the grid of a month, a week to a line starting on the
+// configured week_start. A cell holds the day, a mark for today (*) or
+// overdue tasks (!) and the number of pending tasks due, if any.
+func printMonth(month, now time.Time, due map[int]int, overdue map[int]bool) {
+	first := time.Monday
+	if config.WeekStart == "sunday" {
+		first = time.Sunday
+	}
+	const cell = 7
+	title := month.Format("January 2006")
+	fmt.Printf("%*s\n", (7*(cell+1)-1+len(title))/2, title)
+	var names []string
+	for i := range 7 {
+		names = append(names, fmt.Sprintf("%-*s", cell, time.Weekday((int(first) + i) % 7).String()[:2]))
+	}
+	fmt.Println(strings.TrimRight(strings.Join(names, " "), " "))
+	days := time.Date(month.Year(), month.Month()+1, 0, 0, 0, 0, 0, time.Local).Day()
+	col := (int(month.Weekday()) - int(first) + 7) % 7
+	line := strings.Repeat(" ", col*(cell+1))
+	for day := 1; day <= days; day++ {
+		mark, style := " ", []string(nil)
+		isToday := now.Year() == month.Year() && now.Month() == month.Month() && now.Day() == day
+		switch {
+		case overdue[day]:
+			mark, style = "!", []string{ansiRed}
+		case isToday:
+			mark = "*"
+		}
+		if isToday {
+			style = append(style, ansiReverse)
+		}
+		text := fmt.Sprintf("%2d%s", day, mark)
+		if n := due[day]; n > 0 {
+			text += fmt.Sprintf("(%d)", n)
+		}
+		line += paint(fmt.Sprintf("%-*s", cell, text), style...)
+		if col++; col == 7 || day == days {
+			fmt.Println(strings.TrimRight(line, " "))
+			line, col = "", 0
+		} else {
+			line += " "
+		}
+	}
+}
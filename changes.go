@@ -0,0 +1,197 @@
+// changes.go
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// tombstoneDays is how long a deletion is remembered for sync.
+const tombstoneDays = 30
+
+// A tombstone records that the task with UID was removed from the list, so
+// that sync removes it on the other side instead of copying it back.
+// Tombstones written before UIDs existed only have CreatedAt.
+type tombstone struct {
+	UID       string    `json:"uid,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// key is the taskKey of the task the tombstone stands for.
+func (tb tombstone) key() string {
+	return taskKey(Task{UID: tb.UID, CreatedAt: tb.CreatedAt})
+}
+
+// taskKey identifies a task across lists and machines, where IDs differ.
+// Every loaded task has a UID; one that doesn't gets the UID it would be
+// given on load.
+func taskKey(t Task) string {
+	if t.UID == "" {
+		return todo.LegacyUID(t.CreatedAt)
+	}
+	return t.UID
+}
+
+// assignNewUIDs gives tasks created by a command a random UID.
+func assignNewUIDs(ts Tasks) {
+	for i := range ts {
+		if ts[i].UID == "" {
+			ts[i].UID = todo.NewUID()
+		}
+	}
+}
+
+// modifiedAt is when a task last changed; tasks from before UpdatedAt
+// existed count from their creation.
+func modifiedAt(t Task) time.Time {
+	if t.UpdatedAt.IsZero() {
+		return t.CreatedAt
+	}
+	return t.UpdatedAt
+}
+
+// backfillUpdated gives tasks saved before UpdatedAt existed their creation
+// time as the last change.
+func backfillUpdated(ts Tasks) {
+	for i := range ts {
+		if ts[i].UpdatedAt.IsZero() {
+			ts[i].UpdatedAt = ts[i].CreatedAt
+		}
+	}
+}
+
+// loadedTask is a task as loadTasks returned it.
+type loadedTask struct {
+	data      []byte
+	id        int64
+	done      bool
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// loaded holds the list from the last loadTasks, keyed by taskKey, so that
+// saveTasks can tell which tasks a command changed or removed. It is nil
+// until the list is loaded.
+var loaded map[string]loadedTask
+
+// contents encodes what a task says, leaving out UpdatedAt and the ID,
+// which can change without the task changing.
+func contents(t Task) []byte {
+	t.UpdatedAt, t.ID = time.Time{}, 0
+	b, _ := json.Marshal(t)
+	return b
+}
+
+// listChanged reports whether after differs from before in any task, for
+// commands that asked about before and are about to act on after.
+func listChanged(before, after Tasks) bool {
+	if len(before) != len(after) {
+		return true
+	}
+	for i := range before {
+		if before[i].ID != after[i].ID || string(contents(before[i])) != string(contents(after[i])) {
+			return true
+		}
+	}
+	return false
+}
+
+func rememberLoaded(ts Tasks) {
+	loaded = make(map[string]loadedTask, len(ts))
+	for _, t := range ts {
+		loaded[taskKey(t)] = loadedTask{contents(t), t.ID, t.Done, t.CreatedAt, t.UpdatedAt}
+	}
+}
+
+// stampChanges sets UpdatedAt on the tasks in ts that are new or changed
+// since they were loaded, unless the command set it itself, and returns
+// tombstones for the tasks that were removed.
+func stampChanges(ts Tasks, now time.Time) (removed []tombstone) {
+	if loaded == nil {
+		return nil
+	}
+	kept := map[string]bool{}
+	for i := range ts {
+		key := taskKey(ts[i])
+		kept[key] = true
+		old, ok := loaded[key]
+		switch {
+		case !ok:
+			if ts[i].UpdatedAt.IsZero() {
+				ts[i].UpdatedAt = now
+			}
+		case ts[i].UpdatedAt.Equal(old.updatedAt) && string(contents(ts[i])) != string(old.data):
+			ts[i].UpdatedAt = now
+		}
+	}
+	for key, old := range loaded {
+		if !kept[key] {
+			removed = append(removed, tombstone{key, old.createdAt, now})
+		}
+	}
+	return removed
+}
+
+func tombstonesPath() (string, error) {
+	return companionPath("tombstones")
+}
+
+func readTombstones() ([]tombstone, error) {
+	path, err := tombstonesPath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, dataError(err)
+	}
+	var tbs []tombstone
+	if err := json.Unmarshal(b, &tbs); err != nil {
+		return nil, dataError(err)
+	}
+	return tbs, nil
+}
+
+// updateTombstones records removed tasks, forgets tasks that are back in ts
+// (restored from the trash, say) and drops tombstones older than
+// tombstoneDays.
+func updateTombstones(ts Tasks, removed []tombstone, now time.Time) error {
+	tbs, err := readTombstones()
+	if err != nil {
+		return err
+	}
+	if len(tbs) == 0 && len(removed) == 0 {
+		return nil
+	}
+	present := map[string]bool{}
+	for _, t := range ts {
+		present[taskKey(t)] = true
+	}
+	cutoff := now.AddDate(0, 0, -tombstoneDays)
+	var kept []tombstone
+	for _, tb := range tbs {
+		if tb.DeletedAt.After(cutoff) && !present[tb.key()] {
+			kept = append(kept, tb)
+		}
+	}
+	kept = append(kept, removed...)
+	if len(kept) == len(tbs) && len(removed) == 0 {
+		return nil
+	}
+	path, err := tombstonesPath()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return err
+	}
+	return dataError(todo.WriteFileAtomic(path, b))
+}
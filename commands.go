@@ -0,0 +1,658 @@
+// commands.go
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// command is an entry in the command table, which run dispatches through
+// and help, usage errors and the completion scripts are generated from.
+type command struct {
+	name    string
+	aliases []string
+	// args follows the name in the summary table of `todo help`
+	args    string
+	summary string
+	// usage holds the forms the command accepts, without "todo"
+	usage    []string
+	help     string
+	examples []string
+	run      func(args []string) error
+	// flags are the ones the command passes to parseArgs
+	flags []flagDef
+	// ids makes shells complete task IDs as arguments
+	ids bool
+	// hidden commands are left out of help and completion
+	hidden bool
+}
+
+// commands is filled in by init: the commands read it, so it can't be
+// initialized where it is declared.
+var commands []command
+
+func init() {
+	commands = []command{
+		{
+			name: "add", args: "<title>",
+			summary: "Add a task (--due, --start <date>, -p <1-3>, --tag, --every, --under <id>, --editor)",
+			usage: []string{
+				"add <task title> [--and <title>]... [--due <date>] [-p <priority>] [--tag <tag>]... [--every <rule>] [--start <date>] [--under <id>] [--estimate <duration>] [--url <url>] [--editor] [--dup]",
+				"add - [<flags>]",
+			},
+			help: "Add a task to the list. Priorities are 1 (high) to 3 (low), tags are lowercased, and --every makes the task " +
+				"recur: daily, weekly, monthly, yearly or an interval like 3d or 2w, counted from the due date. --start hides the " +
+				"task from list until a date and --under makes it a subtask. --estimate says how long it should take, such as " +
+				"90m, 2h30m or 1d (8 hours). --url gives the link the task is about, which todo open opens; a title with one link in " +
+				"it gives that one. --editor writes the title and notes in $EDITOR. " +
+				"--and adds more tasks in the same save, as does separating titles with ;; unless -- is given. " +
+				"add - adds a task for each line of stdin, skipping blank lines and stripping - and * bullets, with the " +
+				"flags applying to every one. A title matching a pending task, ignoring case and spacing, asks first; " +
+				"--dup adds it anyway. See `todo help dates` for the date forms.",
+			examples: []string{
+				`todo add "Pay rent" --due 2024-07-01 -p 1`, `todo add "Water plants" --every 3d --due today`,
+				`todo add "Buy cake" --under 12`, `todo add "buy milk" --and "buy bread" --tag shopping`,
+				"cat chores.txt | todo add - --tag home",
+			},
+			run: cmdAdd, flags: addFlags,
+		},
+		{
+			name: "dup", args: "<id>", summary: "Add a pending copy of a task (--due, --count)",
+			usage: []string{"dup <id> [--due <date|none>] [--count <n>]"},
+			help: "Add a pending copy of a task, with a new ID and created now, keeping its title, tags, priority, notes " +
+				"and due date. --due gives the copy another due date, or none. --count adds that many copies.",
+			examples: []string{"todo dup 7 --due friday", "todo dup 3 --count 5"},
+			run:      cmdDup, flags: dupFlags, ids: true,
+		},
+		{
+			name: "list", summary: "List pending tasks (--all, --done, --deferred, --archived, --tag <tag>, --changed-since <date>, --sort <key>, --absolute, --porcelain, --watch)",
+			usage: []string{"list [--all | --done | --pending | --deferred | --archived] [--tag <tag>] [--pinned] [--changed-since <date>] [--sort <key> [--reverse]] [--group-by tag|priority|due] [--absolute] [--format <template>] [-v] [--json | --jsonl | --porcelain [-z]] [--watch]"},
+			help: "List the tasks in the current list, pinned ones first, then high priority, then in their saved order. Only " +
+				"pending tasks are shown unless --all, --done or --deferred says otherwise, and --archived lists the archive " +
+				"instead. --pinned keeps only pinned tasks. --group-by prints the tasks in sections by tag, priority or " +
+				"due date, each in the usual or --sort order; a task with several tags is listed under each. --sort orders " +
+				"by due, priority, created, updated, title, completed, estimate or waiting. --format prints each task through a Go text/template, " +
+				"and --porcelain prints tab-separated lines for scripts. --watch redraws the list, with the same flags, " +
+				"whenever the list's files change, until Ctrl-C.",
+			examples: []string{"todo list --tag shopping", "todo list --watch --sort due", "todo list --all --sort completed --reverse", `todo list --format '{{.ID}} {{.Title}}'`},
+			run:      cmdList, flags: listFlags,
+		},
+		{
+			name: "search", args: "<query>", summary: "Find tasks whose title contains every word (--done, --pending)",
+			usage:    []string{"search <query>... [--done | --pending] [--json | --jsonl]"},
+			help:     "List the tasks whose title contains every word of the query, ignoring case.",
+			examples: []string{"todo search tax 2023"},
+			run:      cmdSearch, flags: searchFlags,
+		},
+		{
+			name: "overdue", summary: "List pending tasks past their due date (exits 1 if any)",
+			usage:    []string{"overdue [--json | --jsonl]"},
+			help:     "List pending tasks whose due date has passed. The exit status is 1 when there are any, so it can drive a shell prompt.",
+			examples: []string{"todo overdue || echo 'catch up!'"},
+			run:      cmdOverdue, flags: overdueFlags,
+		},
+		{
+			name: "today", summary: "Show what is overdue, due today or starting today (--if-any)",
+			usage: []string{"today [--if-any] [--json | --jsonl]"},
+			help: "List pending tasks in three sections: overdue, due today and starting today, then a line counting " +
+				"them. Days count rather than hours, so a task due at 9:00 stays under due today until midnight. With " +
+				"nothing to show it says how many other tasks are pending, or with --if-any prints nothing, for use " +
+				"in a shell startup file.",
+			examples: []string{"todo today", "todo today --if-any   # in ~/.bashrc"},
+			run:      cmdToday, flags: todayFlags,
+		},
+		{
+			name: "next", summary: "Show the task to do next (-n 3, --random)",
+			usage: []string{"next [-n <count>] [--random] [--json | --jsonl]"},
+			help: "Print the pending task to work on next, leaving out blocked and deferred tasks: the highest priority, " +
+				"then the earliest due date, then the oldest. -n shows that many, and --random picks among them at random " +
+				"instead. With nothing left it prints Nothing to do.",
+			examples: []string{"todo next", "todo next -n 3", "todo next --random"},
+			run:      cmdNext, flags: nextFlags,
+		},
+		{
+			name: "count", summary: "Print the number of pending tasks (--done, --all, --overdue, --due-today, --tag)",
+			usage: []string{"count [--done | --all] [--overdue] [--due-today] [--tag <tag>]"},
+			help: "Print how many tasks list would show, pending and not deferred, as a bare number and nothing else, " +
+				"0 included. --done counts completed tasks and --all every task. --overdue and --due-today count pending " +
+				"tasks that are overdue or due today as todo today sorts them, either one when both are given, and --tag counts only tasks with the tag.",
+			examples: []string{"todo count", "todo count --overdue --tag work", `echo "$(todo count --due-today) due"`},
+			run:      cmdCount, flags: countFlags,
+		},
+		{
+			name: "agenda", summary: "Show pending tasks grouped by due day (--days 7)",
+			usage: []string{"agenda [--days <n>] [--json | --jsonl]"},
+			help: "List pending tasks under headings by due date: Overdue, Today, Tomorrow and each following day of " +
+				"the window of --days days (7 by default, today included), then Later and No due date. Within a heading " +
+				"tasks go by priority, then ID. Days are counted as today counts them.",
+			examples: []string{"todo agenda", "todo agenda --days 14"},
+			run:      cmdAgenda, flags: agendaFlags,
+		},
+		{
+			name: "cal", args: "[YYYY-MM]", summary: "Show a month with the number of tasks due each day (--next, --prev)",
+			usage: []string{"cal [YYYY-MM] [--next | --prev]"},
+			help: "Print a calendar of this month, or of the month given, with --next or --prev moving one month on or " +
+				"back. Each day shows how many pending tasks are due on it; today is marked * and days with overdue " +
+				"tasks ! (and in color on a terminal). Weeks start on Monday, or on Sunday with week_start = sunday in " +
+				"the config.",
+			examples: []string{"todo cal", "todo cal 2024-07", "todo cal --next"},
+			run:      cmdCal, flags: calFlags,
+		},
+		{
+			name: "ui", summary: "Browse and edit the list in a full-screen terminal view",
+			usage: []string{"ui [--color=auto|always|never]"},
+			help: "Show every task full screen. j/k or the arrow keys move, space completes or reopens the selected task, " +
+				"a adds a task, e edits its title, d deletes it after asking, and / filters by title or tag (Esc clears " +
+				"the filter). q saves the changes and quits; Ctrl-C quits without saving. Other invocations wait for the " +
+				"list while it is open and give up after 10 seconds. Unix only.",
+			run: cmdUI, flags: uiFlags,
+		},
+		{
+			name: "do", aliases: []string{"complete"}, args: "<id>...",
+			summary: "Mark tasks done (ranges like 4-9 allowed, --force with open subtasks)",
+			usage:   []string{"do [--force] <id|from-to>... | <title>", "do [--force] --title <title> | --pick"},
+			help: "Mark tasks done, named by ID or by title (see todo help titles), or chosen from the pending ones with " +
+				"--pick. Completing a recurring task adds its next occurrence. A task with open subtasks or pending " +
+				"dependencies is refused unless --force is given, which completes the open subtasks along with it.",
+			examples: []string{"todo do 3", "todo do 1 4-9", "todo do --pick"},
+			run:      cmdDo, flags: doFlags, ids: true,
+		},
+		{
+			name: "postpone", args: "<id> <by>", summary: "Push due dates back by 3h, 1d, 2w or to a date",
+			usage:    []string{"postpone <id|from-to>... <duration|date>"},
+			help:     "Move the due dates of tasks later by a duration such as 3h, 1d or 2w, or set them to a date. Tasks without a due date are counted from now.",
+			examples: []string{"todo postpone 4 1d", "todo postpone 2-5 friday"},
+			run:      cmdPostpone, ids: true,
+		},
+		{
+			name: "defer", args: "<id> <date>", summary: "Hide a task from list until a date",
+			usage:    []string{"defer <id> <date>"},
+			help:     "Set a task's start date, hiding it from list until then. list --deferred shows the hidden ones.",
+			examples: []string{`todo defer 4 "next week"`},
+			run:      cmdDefer, ids: true,
+		},
+		{
+			name: "block", args: "<id>", summary: "Make a task wait on others (--on <id>; unblock removes)",
+			usage:    []string{"block <id> --on <id>..."},
+			help:     "Make a task depend on others. It is shown as [~] and can't be completed without --force until they are done.",
+			examples: []string{"todo block 7 --on 3"},
+			run:      cmdBlock, flags: blockFlags, ids: true,
+		},
+		{
+			name: "unblock", args: "<id>", summary: "Drop a task's dependencies (--on <id> for one)",
+			usage:    []string{"unblock <id> [--on <id>]..."},
+			help:     "Remove all of a task's dependencies, or only the ones given with --on.",
+			examples: []string{"todo unblock 7", "todo unblock 7 --on 3"},
+			run:      cmdUnblock, flags: unblockFlags, ids: true,
+		},
+		{
+			name: "blocked", summary: "List tasks waiting on pending dependencies",
+			usage: []string{"blocked [--json | --jsonl]"},
+			help:  "List the pending tasks that wait on other pending tasks, with what they wait on.",
+			run:   cmdBlocked, flags: blockedFlags,
+		},
+		{
+			name: "undone", aliases: []string{"reopen"}, args: "<id>...", summary: "Reopen completed tasks (alias: reopen)",
+			usage:    []string{"undone <id|from-to>..."},
+			help:     "Mark completed tasks pending again.",
+			examples: []string{"todo undone 3"},
+			run:      cmdUndone, ids: true,
+		},
+		{
+			name: "renumber", summary: "Give the tasks IDs 1 to N in list order",
+			usage: []string{"renumber [--force]"},
+			help: "Give the tasks IDs 1 to N in the order list --all shows them, in one save, and print each old ID " +
+				"with its new one. UIDs don't change. When the archive or the trash holds tasks numbered 1 to N, " +
+				"restore and list --archived would show two tasks under one ID, so renumber refuses unless --force " +
+				"is given. undo puts the old IDs back.",
+			examples: []string{"todo renumber"},
+			run:      cmdRenumber, flags: renumberFlags,
+		},
+		{
+			name: "pin", args: "<id>...", summary: "Keep tasks at the top of list",
+			usage: []string{"pin <id|from-to>..."},
+			help: "Pin tasks so that list shows them first, marked with *, whatever the sort order. Done tasks stay " +
+				"hidden from list unless asked for, pinned or not. list --pinned shows only pinned tasks.",
+			examples: []string{"todo pin 3", "todo list --pinned"},
+			run:      cmdPin, ids: true,
+		},
+		{
+			name: "unpin", args: "<id>...", summary: "Let pinned tasks sort like the others",
+			usage:    []string{"unpin <id|from-to>..."},
+			help:     "Unpin tasks pinned with pin.",
+			examples: []string{"todo unpin 3"},
+			run:      cmdUnpin, ids: true,
+		},
+		{
+			name: "start", args: "<id>", summary: "Start working on a task and time it",
+			usage: []string{"start <id>"},
+			help: "Move a task from to do to doing and start a work session on it. list shows it with [>] " +
+				"and board puts it in the Doing column. Only one task is timed at a time: starting another " +
+				"stops the first. do completes it as usual, ending the session, and reopen puts a completed " +
+				"task back to to do.",
+			examples: []string{"todo start 3", "todo show 3"},
+			run:      cmdStart, ids: true,
+		},
+		{
+			name: "stop", summary: "Stop timing the running task",
+			usage: []string{"stop [--at <HH:MM|date>]"},
+			help: "End the running work session. The task stays in doing until it is completed. A session " +
+				"is kept running between commands and over restarts, so one left running overnight can be " +
+				"ended when the work stopped with --at.",
+			examples: []string{"todo stop", "todo stop --at 17:30"},
+			run:      cmdStop, flags: stopFlags,
+		},
+		{
+			name: "timesheet", summary: "Sum tracked time per day and per task",
+			usage: []string{"timesheet [--today | --week] [--from <date>] [--to <date>]"},
+			help: "Show the time tracked with start and stop in a period, the last 7 days by default, per " +
+				"day and per task, archived tasks included. A running session counts up to now and one " +
+				"over midnight counts towards both days.",
+			examples: []string{"todo timesheet --week", "todo timesheet --from 2024-03-01 --to 2024-03-31"},
+			run:      cmdTimesheet, flags: timesheetFlags,
+		},
+		{
+			name: "board", summary: "Show tasks in To do, Doing and Done columns",
+			usage: []string{"board [--tag <tag>] [--color auto|always|never]"},
+			help: "Show the pending tasks that aren't deferred, split by status, beside the ten most recently " +
+				"completed. Waiting tasks get a column of their own when there are any. On a " +
+				"terminal the columns sit side by side and are clipped to its width; otherwise they are " +
+				"printed one after another.",
+			examples: []string{"todo board", "todo board --tag work | less"},
+			run:      cmdBoard, flags: boardFlags,
+		},
+		{
+			name: "notify", summary: "Send desktop notifications for tasks due soon",
+			usage: []string{"notify [--within <duration>] [--stdout]"},
+			help: "Notify about each pending task that is overdue or due within the next hour, or --within or " +
+				"notify_window, once per task and due date, so it can run every few minutes from cron or a " +
+				"systemd timer. Notifications go through notify-send, osascript on macOS or a PowerShell toast " +
+				"on Windows; --stdout prints them instead. A notification that can't be sent is reported on " +
+				"stderr and tried again next time, and the exit status stays 0.",
+			examples: []string{"todo notify --within 30m", "*/5 * * * * todo notify"},
+			run:      cmdNotify, flags: notifyFlags,
+		},
+		{
+			name: "remind", summary: "Stay running and notify about tasks as they fall due",
+			usage: []string{"remind --daemon [--within <duration>] [--log <file>] [--stdout]"},
+			help: "Send the notifications notify would, each when its time comes, until SIGTERM or Ctrl-C. The " +
+				"list is read again whenever it changes, and the wall clock is looked at every second, so " +
+				"edits, clock changes and a machine waking from sleep are all caught. --log appends what it " +
+				"did, and when, to a file.",
+			examples: []string{"todo remind --daemon --log ~/.local/state/todo-remind.log"},
+			run:      cmdRemind, flags: remindFlags,
+		},
+		{
+			name: "webhook", summary: "Send a test event to the configured webhook",
+			usage: []string{"webhook test"},
+			help: "Post a sample event to webhook_url and report whether it arrived. With webhook_url set, every " +
+				"command that adds, completes or removes tasks posts {event, task, timestamp, text} for each, " +
+				"limited to webhook_events if set, within 2 seconds in all; a failure is only a warning. The " +
+				"global --no-webhook flag sends nothing, say for a bulk import.",
+			examples: []string{"todo config set webhook_url https://hooks.slack.com/services/...", "todo webhook test",
+				"todo import --no-webhook old.csv"},
+			run: cmdWebhook,
+		},
+		{
+			name: "wait", args: "<id> <who>", summary: "Mark a task as waiting on someone or something",
+			usage: []string{"wait <id> <who>"},
+			help: "Record who or what a task is waiting on, such as a person it was handed to. Waiting tasks " +
+				"show [@], are left out of list unless --all is given and are listed by waiting. unwait, " +
+				"start or completing the task ends the wait.",
+			examples: []string{`todo wait 4 "Alice"`, "todo wait 7 the landlord"},
+			run:      cmdWait, ids: true,
+		},
+		{
+			name: "unwait", args: "<id>...", summary: "Bring waiting tasks back to the list",
+			usage:    []string{"unwait <id|from-to>..."},
+			help:     "Stop waiting on tasks marked with wait, so list shows them again as tasks to do.",
+			examples: []string{"todo unwait 4"},
+			run:      cmdUnwait, ids: true,
+		},
+		{
+			name: "waiting", summary: "List tasks waiting on someone, longest first",
+			usage: []string{"waiting [--json | --jsonl] [--color auto|always|never]"},
+			help:  "List the tasks marked with wait, with who they are waiting on and for how long.",
+			run:   cmdWaiting, flags: waitingFlags,
+		},
+		{
+			name: "rm", aliases: []string{"remove"}, args: "<id>...",
+			summary: "Move tasks to the trash (ranges like 4-9 allowed, --force deletes)",
+			usage:   []string{"rm [--force] <id|from-to>... | <title>", "rm [--force] --title <title> | --pick"},
+			help: "Move tasks to the trash, from where restore brings them back. --force deletes them for good. Removing a " +
+				"task drops it from the dependencies of others. --pick chooses the tasks from the pending ones, as for do.",
+			examples: []string{"todo rm 4", "todo rm 2-5 --force", "todo rm --pick"},
+			run:      cmdRemove, flags: removeFlags, ids: true,
+		},
+		{
+			name: "trash", summary: "List trashed tasks (--empty purges them)",
+			usage: []string{"trash [--empty] [--json | --jsonl]"},
+			help:  "List the tasks in the trash, or delete them all for good with --empty.",
+			run:   cmdTrash, flags: trashFlags,
+		},
+		{
+			name: "dedupe", summary: "Report pending tasks with the same title (--merge merges them)",
+			usage: []string{"dedupe [--merge]"},
+			help: "List pending tasks whose titles are the same, ignoring case and spacing. --merge folds each group " +
+				"into its lowest ID: notes are appended, tags combined, the earlier due date kept, subtasks and " +
+				"dependencies moved over, and the others go to the trash.",
+			run: cmdDedupe, flags: dedupeFlags,
+		},
+		{
+			name: "split", args: "<id> [title]...", summary: "Break a task into new ones (--close-original)",
+			usage: []string{"split [--close-original] <id> <title>...", "split [--close-original] <id>"},
+			help: "Add new tasks with the tags, priority and due date of a pending task, as its subtasks. With " +
+				"--close-original they are added beside it instead, under its parent if it has one, and it is marked " +
+				"done. Without titles, $EDITOR opens for them, one per line.",
+			examples: []string{`todo split 4 "Draft outline" "Write intro" "Edit"`, "todo split 4 --close-original"},
+			run:      cmdSplit, flags: splitFlags, ids: true,
+		},
+		{
+			name: "merge", args: "<keep> <drop>", summary: "Fold one task into another",
+			usage: []string{"merge <keep-id> <drop-id>"},
+			help: "Fold the second task into the first, in one save: its notes are appended and its tags added, the " +
+				"earlier creation time and due date are kept, and its subtasks and the tasks depending on it move over " +
+				"to the kept one. The dropped task goes to the trash. This is what dedupe --merge does for tasks with " +
+				"the same title.",
+			examples: []string{"todo merge 3 8"},
+			run:      cmdMerge, ids: true,
+		},
+		{
+			name: "restore", args: "<id>", summary: "Move a task back from the trash",
+			usage:    []string{"restore <id>"},
+			help:     "Move a task from the trash back into the list. It gets a new ID if its old one has been reused.",
+			examples: []string{"todo restore 4"},
+			run:      cmdRestore,
+		},
+		{
+			name: "edit", args: "<id> [title]",
+			summary: "Change the title or fields (-p, --due <date|none>, --tag +x/-x, --editor), or every pending task with --all",
+			usage: []string{
+				"edit <id|title> [<new title> | --editor] [-p <priority>] [--due <date|none>] [--estimate <duration|none>] [--url <url|none>] [--tag +<tag>|-<tag>]...",
+				"edit --title <title> [<new title> | --editor] [<flags>]",
+				"edit --all",
+			},
+			help: "Change a task's title, priority, due date, estimate, URL or tags. --due none clears the due date, --estimate none " +
+				"the estimate, --url none the URL, and --tag +x adds and -x " +
+				"removes a tag. --editor opens the title and notes in $EDITOR. edit --all opens every pending task in $EDITOR, " +
+				"one per line: change lines to rename tasks, delete them to remove tasks and add lines to add tasks.",
+			examples: []string{`todo edit 2 "Buy oat milk"`, "todo edit 2 --due none --tag -urgent", "todo edit --all"},
+			run:      cmdEdit, flags: editFlags, ids: true,
+		},
+		{
+			name: "archive", summary: "Move completed tasks to the archive file",
+			usage: []string{"archive"},
+			help:  "Move every completed task out of the list into its archive, which list --archived shows.",
+			run:   cmdArchive,
+		},
+		{
+			name: "clear", summary: "Remove all tasks after confirming (--force skips the prompt)",
+			usage: []string{"clear [--force]"},
+			help:  "Remove every task in the list. Without --force it asks first, and refuses when stdin is not a terminal.",
+			run:   cmdClear, flags: clearFlags,
+		},
+		{
+			name: "open", args: "<id>", summary: "Open a task's URL in the browser (--attachment <n> for a file)",
+			usage: []string{"open <id> [--attachment <n>]"},
+			help: "Open the link of a task, given with add or edit --url or found in its title, with the desktop's " +
+				"default browser, and return at once. --attachment opens the task's file numbered n in show instead.",
+			examples: []string{`todo add "Review https://github.com/o/r/pull/7"`, "todo open 7", "todo open 7 --attachment 1"},
+			run:      cmdOpen, flags: openFlags, ids: true,
+		},
+		{
+			name: "attach", args: "<id> <file>...", summary: "Attach files to a task (--force if missing)",
+			usage: []string{"attach [--force] <id> <file>..."},
+			help: "Record files that go with a task by their absolute paths, so they are found from any directory. show lists " +
+				"them, numbered, and open --attachment opens one. A file must exist unless --force is given.",
+			examples: []string{"todo attach 4 ~/Documents/contract.pdf", "todo attach --force 4 /mnt/usb/scan.png"},
+			run:      cmdAttach, flags: attachFlags, ids: true,
+		},
+		{
+			name: "detach", args: "<id> <n>...", summary: "Remove attachments from a task by number",
+			usage:    []string{"detach <id> <n>..."},
+			help:     "Remove the attachments numbered n in show from a task. The files themselves are left alone.",
+			examples: []string{"todo detach 4 1", "todo detach 4 2 3"},
+			run:      cmdDetach, ids: true,
+		},
+		{
+			name: "show", args: "<id>", summary: "Show every detail of a task (--json)",
+			usage:    []string{"show <id|title> [--json]", "show --title <title> [--json]"},
+			help:     "Print every field of a task: title, state, timestamps, due date, priority, tags, links and notes.",
+			examples: []string{"todo show 2", "todo show 2 --json"},
+			run:      cmdShow, flags: showFlags, ids: true,
+		},
+		{
+			name: "note", args: "<id> <text>", summary: "Append to a task's notes (--replace overwrites)",
+			usage:    []string{"note <id> <text> [--replace]"},
+			help:     "Add a line to a task's notes, or replace them with --replace.",
+			examples: []string{`todo note 3 "Called, waiting for a reply"`},
+			run:      cmdNote, flags: noteFlags, ids: true,
+		},
+		{
+			name: "undo", summary: "Revert the last change",
+			usage: []string{"undo"},
+			help:  "Put the list back the way it was before the last command that changed it. Only one step is kept.",
+			run:   cmdUndo,
+		},
+		{
+			name: "init", summary: "Start a project list, .todo.json, in this directory",
+			usage: []string{"init"},
+			help: "Create an empty .todo.json in the working directory. Commands run in it or any directory below, short of " +
+				"your home directory, use that list instead of your own, unless --list, TODO_LIST or TODO_FILE picks one " +
+				"or --global is given. The files todo keeps for it, like its lock, archive and backups, go in .todo.d/, " +
+				"which init adds to .gitignore. todo env shows which list is in use.",
+			examples: []string{"cd ~/src/myapp && todo init", "todo --global list"},
+			run:      cmdInit,
+		},
+		{
+			name: "env", args: "[name]", summary: "Show which tasks file is in use and where todo keeps things",
+			usage: []string{"env [file|source|list|backend|store|data|config|hooks]"},
+			help: "Print the tasks file in use and what chose it (--list, TODO_LIST, TODO_FILE, a project file or the " +
+				"default list), the list's name, the storage backend and the file it stores to, and the data, config and " +
+				"hooks locations. With a name, print only that value.",
+			examples: []string{"todo env", "todo env file"},
+			run:      cmdEnv,
+		},
+		{
+			name: "doctor", summary: "Check the config, tasks file and lock for problems",
+			usage: []string{"doctor [--color auto|always|never]"},
+			help: "Check that the config file is valid, the tasks file parses, has sane permissions and no duplicate IDs, " +
+				"its directory is writable, no interrupted save left a .tmp file behind, and the lock and hooks are in " +
+				"order. Each check prints PASS, WARN or FAIL; doctor exits 1 when any check fails.",
+			examples: []string{"todo doctor"},
+			run:      cmdDoctor, flags: doctorFlags,
+		},
+		{
+			name: "lists", summary: "Show all lists with pending/total counts",
+			usage: []string{"lists"},
+			help:  "Show every list in the data directory with its pending and total task counts. Use --list <name> to work on one.",
+			run:   cmdLists,
+		},
+		{
+			name: "report", summary: "List tasks completed in a date range (--from, --to, --today, --week)",
+			usage:    []string{"report [--from <date>] [--to <date>] [--today | --week]"},
+			help:     "List the tasks completed in a date range, grouped by day.",
+			examples: []string{"todo report --week", "todo report --from 2024-06-01 --to 2024-06-30"},
+			run:      cmdReport, flags: reportFlags,
+		},
+		{
+			name: "estimate-report", summary: "Compare estimates with the time completed tasks took",
+			usage: []string{"estimate-report [--over] [--color auto|always|never]"},
+			help: "List the completed tasks with an estimate, archived ones included, with the time each took: the time " +
+				"tracked with start and stop when there is any, or else the time from creation to completion. Tasks " +
+				"that took more than twice their estimate are flagged; --over lists only those.",
+			examples: []string{"todo estimate-report", "todo estimate-report --over"},
+			run:      cmdEstimateReport, flags: estimateReportFlags,
+		},
+		{
+			name: "stats", summary: "Show task counts and completion times (--json)",
+			usage: []string{"stats [--json]"},
+			help:  "Show how many tasks are pending and done, the completion rate, how long tasks take to finish and the count for each tag.",
+			run:   cmdStats, flags: statsFlags,
+		},
+		{
+			name: "chart", summary: "Chart completions per day (--days 30, --json)",
+			usage: []string{"chart [--days <n>] [--json]"},
+			help: "Draw a bar of # for each of the last --days days (30 by default) with the number of tasks completed " +
+				"that day, archived ones included, scaled to fit the terminal. Days without completions are shown too. " +
+				"A total and the current streak of days with completions follow. --json prints the counts per day.",
+			examples: []string{"todo chart", "todo chart --days 7"},
+			run:      cmdChart, flags: chartFlags,
+		},
+		{
+			name: "streak", summary: "Show the current and longest runs of days with completions (--per-tag <tag>)",
+			usage: []string{"streak [--per-tag <tag>] [--json]"},
+			help: "Count the days in a row, up to today, on which at least one task was completed, and the longest such " +
+				"run with the day it ended. Days are calendar days in the local time zone, and a streak that reached " +
+				"yesterday isn't broken until today is over. Archived tasks count; --per-tag counts only tasks with the tag.",
+			examples: []string{"todo streak", "todo streak --per-tag work"},
+			run:      cmdStreak, flags: streakFlags,
+		},
+		{
+			name: "export", summary: "Write tasks to stdout or --output (--format csv|todotxt|markdown|ics|html, --only-pending)",
+			usage: []string{"export [--format <format>] [--output <file>] [--only-pending] [--due-only]"},
+			help: "Write the list as CSV (the default), todo.txt, a Markdown checklist, an iCalendar file of VTODOs or a " +
+				"self-contained HTML report, to stdout or a file. --due-only leaves out tasks without a due date.",
+			examples: []string{"todo export --format csv --output tasks.csv", "todo export --format markdown --only-pending",
+				"todo export --format ics --due-only --output tasks.ics", "todo export --format html --output report.html"},
+			run: cmdExport, flags: exportFlags,
+		},
+		{
+			name: "import", args: "<file>", summary: "Add tasks from a file (--format csv|todotxt|markdown|taskwarrior, --keep-ids, --dry-run) or GitHub issues",
+			usage: []string{"import [--format <format>] [--keep-ids] [--dry-run] <file>",
+				"import --format github --repo <owner/name> [--state open|closed|all] [--dry-run]"},
+			help: "Add the tasks in a CSV (the default), todo.txt, Markdown or Taskwarrior export file to the list, with new IDs unless --keep-ids " +
+				"is given. --dry-run shows what would be added. --format github makes a task of each open issue of a " +
+				"repository (--state closed or all for others), with GITHUB_TOKEN if set; importing again updates the " +
+				"tasks from issues already imported.",
+			examples: []string{"todo import tasks.csv", "todo import --format todotxt --dry-run todo.txt",
+				"todo import --format github --repo cli/cli --state all"},
+			run: cmdImport, flags: importFlags,
+		},
+		{
+			name: "tags", summary: "List tags with their task counts (tags rename, tags rm)",
+			usage: []string{"tags [--json]", "tags rename <old> <new>", "tags rm [--force] <tag>"},
+			help: "List every tag in use with how many pending and completed tasks carry it. tags rename renames a tag " +
+				"on every task, merging it into the new one where a task has both, and tags rm removes a tag from every " +
+				"task after asking, or right away with --force. Each is a single save that leaves other tasks untouched.",
+			examples: []string{"todo tags", "todo tags rename shoping shopping", "todo tags rm someday"},
+			run:      cmdTags, flags: tagsFlags,
+		},
+		{
+			name: "config", summary: "Show settings (config get <key>, config set <key> <value>)",
+			usage:    []string{"config [get <key> | set <key> <value>]"},
+			help:     "Show every setting with its value, or get or set one in the config file.",
+			examples: []string{"todo config set default_list work", "todo config get date_format"},
+			run:      cmdConfig,
+		},
+		{
+			name: "move", args: "<id>", summary: "Reorder a task (--up, --down, --top, --bottom, --before <id>) or move it (--to <list>)",
+			usage: []string{"move <id> --to <list> | --up | --down | --top | --bottom | --before <id>"},
+			help: "Change a task's place in the saved order, among the tasks with the same priority, or move it to another " +
+				"list with --to.",
+			examples: []string{"todo move 4 --top", "todo move 4 --before 2", "todo move 4 --to work"},
+			run:      cmdMove, flags: moveFlags, ids: true,
+		},
+		{
+			name: "migrate", summary: `Copy the list's JSON file into the SQLite database (storage = "sqlite")`,
+			usage: []string{"migrate"},
+			help:  "Copy the current list's JSON file into the SQLite database, once storage is set to sqlite.",
+			run:   cmdMigrate,
+		},
+		{
+			name: "compact", summary: `Squash the change journal into a snapshot (storage = "journal")`,
+			usage: []string{"compact"},
+			help:  "Rewrite the current list's change journal as a single snapshot, and mirror it to the JSON file.",
+			run:   cmdCompact,
+		},
+		{
+			name: "serve", summary: "Serve the list as a REST API (--addr :8080, --token <bearer token>)",
+			usage:    []string{"serve [--addr <host:port>] [--token <token>]"},
+			help:     "Serve the current list as JSON over HTTP, under the same lock as the command line. With --token every request needs it as a bearer token.",
+			examples: []string{"todo serve --addr 127.0.0.1:8080 --token s3cret"},
+			run:      cmdServe, flags: serveFlags,
+		},
+		{
+			name: "sync", summary: "Sync the data directory with git (--init creates the repo), or merge with a todo serve instance (--remote <url>)",
+			usage: []string{"sync [--init]", "sync --remote <url> [--token <token>] [--dry-run]"},
+			help: "Commit the data directory, pull with rebase and push it, or with --init make it a git repository. With " +
+				"--remote, merge the list both ways with another instance's todo serve instead: the version changed last wins.",
+			examples: []string{"todo sync --init", "todo sync", "todo sync --remote http://laptop:8080 --dry-run"},
+			run:      cmdSync, flags: syncFlags,
+		},
+		{
+			name: "encrypt", summary: "Encrypt the list's files with TODO_PASSPHRASE or key_file",
+			usage: []string{"encrypt"},
+			help:  "Encrypt the current list's files with the passphrase from TODO_PASSPHRASE or the key_file setting.",
+			run:   cmdEncrypt,
+		},
+		{
+			name: "decrypt", summary: "Store the list's files in plain JSON again",
+			usage: []string{"decrypt"},
+			help:  "Decrypt the current list's files, storing them as plain JSON again.",
+			run:   cmdDecrypt,
+		},
+		{
+			name: "backups", summary: "List the list's automatic backups with task counts",
+			usage: []string{"backups"},
+			help:  "List the backups taken before each save, newest first, with their task counts. The backups setting says how many are kept.",
+			run:   cmdBackups,
+		},
+		{
+			name: "restore-backup", args: "<timestamp>", summary: "Replace the list with a backup (--force skips the prompt)",
+			usage:    []string{"restore-backup <timestamp> [--force]"},
+			help:     "Replace the list with a backup from todo backups. Any unique prefix of its timestamp will do.",
+			examples: []string{"todo restore-backup 20240601-0930"},
+			run:      cmdRestoreBackup, flags: restoreBackupFlags,
+		},
+		{
+			name: "shell", summary: "Run commands at a prompt with the list kept in memory (--save-on-exit)",
+			usage: []string{"shell [--save-on-exit]"},
+			help: "Read commands from a todo> prompt and run them as if each were typed after todo, until quit, exit or " +
+				"Ctrl-D. The list is read once and kept in memory, and each change is saved as the command makes it, or " +
+				"only when the shell ends with --save-on-exit. Quotes and backslashes work as in a shell. The arrows edit " +
+				"the line and go through the history, which is kept across sessions, and Ctrl-C drops the line. An error " +
+				"is printed and the shell goes on. Changes other todo processes make meanwhile aren't seen.",
+			examples: []string{"todo shell", "printf 'add milk\nlist\n' | todo shell"},
+			run:      cmdShell, flags: shellFlags,
+		},
+		{
+			name: "completion", args: "<shell>", summary: "Print a completion script for bash, zsh or fish",
+			usage:    []string{"completion bash|zsh|fish"},
+			help:     "Print a script that completes commands, flags and task IDs for a shell.",
+			examples: []string{"source <(todo completion bash)", "todo completion fish | source"},
+			run:      cmdCompletion,
+		},
+		{name: "__complete-ids", usage: []string{"__complete-ids"}, run: cmdCompleteIDs, hidden: true},
+		{
+			name: "help", args: "[command]", summary: "Show this help, or a command's or topic's",
+			usage:    []string{"help [<command> | <topic>]"},
+			help:     "Show the list of commands, or the usage, description and examples of one. `todo <command> --help` does the same.",
+			examples: []string{"todo help add", "todo help dates"},
+			run:      cmdHelp,
+		},
+	}
+}
+
+// findCommand looks a command up by name or alias.
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+		if slices.Contains(c.aliases, name) {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// usageError reports that a command was called wrongly, with its usage.
+func usageError(name string) error {
+	c, _ := findCommand(name)
+	forms := make([]string, len(c.usage))
+	for i, u := range c.usage {
+		forms[i] = "todo " + u
+	}
+	return usageErrorf("usage: %s", strings.Join(forms, " | "))
+}
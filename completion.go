@@ -0,0 +1,229 @@
+// completion.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// completionShells are the shells `todo completion` writes scripts for.
+var completionShells = map[string]func(io.Writer){
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+func cmdCompletion(args []string) error {
+	_ = args
+	if len(args) != 1 {
+		return usageError("completion")
+	}
+	write, ok := completionShells[args[0]]
+	if !ok {
+		return usageErrorf("unknown shell %q; supported: bash, zsh, fish", args[0])
+	}
+	write(os.Stdout)
+	return nil
+}
+
+// cmdCompleteIDs prints "<id>\t<title>" for every task in the list, for the
+// completion scripts to offer as arguments.
+func cmdCompleteIDs(args []string) error {
+	_ = args
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		fmt.Printf("%d\t%s\n", t.ID, strings.Join(strings.Fields(t.Title), " "))
+	}
+	return nil
+}
+
+// flagWords spells out every name of every flag as typed: --name or -n.
+func flagWords(defs []flagDef) []string {
+	var words []string
+	for _, d := range defs {
+		for _, n := range d.names {
+			if len(n) == 1 {
+				words = append(words, "-"+n)
+			} else {
+				words = append(words, "--"+n)
+			}
+		}
+	}
+	return words
+}
+
+func globalFlagWords() string {
+	var defs []flagDef
+	for _, g := range globalFlags {
+		defs = append(defs, g.flagDef)
+	}
+	return strings.Join(flagWords(defs), " ")
+}
+
+// commandWords lists the names and aliases of the commands to complete.
+func commandWords() string {
+	var words []string
+	for _, c := range commands {
+		if !c.hidden {
+			words = append(words, c.name)
+			words = append(words, c.aliases...)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func bashCompletion(w io.Writer) {
+	fmt.Fprintf(w, `# bash completion for todo. Load it with:
+#   source <(todo completion bash)
+_todo() {
+	local cur=${COMP_WORDS[COMP_CWORD]} cmd= list= i
+	COMPREPLY=()
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		case ${COMP_WORDS[i]} in
+		--list) list=${COMP_WORDS[i+1]}; ((i++)) ;;
+		-*) ;;
+		*) cmd=${COMP_WORDS[i]}; break ;;
+		esac
+	done
+	if [[ -z $cmd ]]; then
+		if [[ $cur == -* ]]; then
+			COMPREPLY=($(compgen -W "%[1]s" -- "$cur"))
+		else
+			COMPREPLY=($(compgen -W "%[2]s" -- "$cur"))
+		fi
+		return
+	fi
+	local flags= ids=
+	case $cmd in
+`, globalFlagWords(), commandWords())
+	for _, c := range commands {
+		if c.hidden || (c.flags == nil && !c.ids) {
+			continue
+		}
+		fmt.Fprintf(w, "\t%s) flags=%q", strings.Join(append([]string{c.name}, c.aliases...), "|"),
+			strings.Join(flagWords(c.flags), " "))
+		if c.ids {
+			fmt.Fprint(w, "; ids=1")
+		}
+		fmt.Fprintln(w, " ;;")
+	}
+	fmt.Fprintf(w, `	esac
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "$flags %s" -- "$cur"))
+	elif [[ -n $ids ]]; then
+		COMPREPLY=($(compgen -W "$(todo ${list:+--list "$list"} __complete-ids 2>/dev/null | cut -f1)" -- "$cur"))
+	fi
+}
+complete -o default -F _todo todo
+`, globalFlagWords())
+}
+
+func zshCompletion(w io.Writer) {
+	fmt.Fprintf(w, `#compdef todo
+# zsh completion for todo. Load it with:
+#   source <(todo completion zsh)
+# or save it as _todo in a directory on $fpath.
+_todo() {
+	local cmd list i
+	for ((i = 2; i < CURRENT; i++)); do
+		case ${words[i]} in
+		--list) list=${words[i+1]}; ((i++)) ;;
+		-*) ;;
+		*) cmd=${words[i]}; break ;;
+		esac
+	done
+	if [[ -z $cmd ]]; then
+		if [[ $PREFIX == -* ]]; then
+			compadd -- %[1]s
+		else
+			compadd -- %[2]s
+		fi
+		return
+	fi
+	local -a flags tasks
+	local ids=0
+	case $cmd in
+`, globalFlagWords(), commandWords())
+	for _, c := range commands {
+		if c.hidden || (c.flags == nil && !c.ids) {
+			continue
+		}
+		fmt.Fprintf(w, "\t(%s) flags=(%s)", strings.Join(append([]string{c.name}, c.aliases...), "|"),
+			strings.Join(flagWords(c.flags), " "))
+		if c.ids {
+			fmt.Fprint(w, "; ids=1")
+		}
+		fmt.Fprintln(w, " ;;")
+	}
+	fmt.Fprintf(w, `	esac
+	if [[ $PREFIX == -* ]]; then
+		compadd -- $flags %s
+	elif (( ids )); then
+		tasks=(${(f)"$(todo ${list:+--list "$list"} __complete-ids 2>/dev/null)"})
+		tasks=(${tasks/$'\t'/:})
+		_describe -t tasks task tasks
+	else
+		_files
+	fi
+}
+if [[ $funcstack[1] == _todo ]]; then
+	_todo "$@"
+else
+	compdef _todo todo
+fi
+`, globalFlagWords())
+}
+
+func fishCompletion(w io.Writer) {
+	fmt.Fprintf(w, `# fish completion for todo. Load it with:
+#   todo completion fish | source
+function __todo_ids
+	set -l args (commandline -opc)
+	set -l list
+	for i in (seq (count $args))
+		if test "$args[$i]" = --list; and test $i -lt (count $args)
+			set list --list $args[(math $i + 1)]
+		end
+	end
+	todo $list __complete-ids 2>/dev/null
+end
+complete -c todo -n __fish_use_subcommand -f -a %q
+`, commandWords())
+	for _, g := range globalFlags {
+		fmt.Fprintf(w, "complete -c todo%s\n", fishFlag(g.flagDef))
+	}
+	for _, c := range commands {
+		if c.hidden {
+			continue
+		}
+		cond := fmt.Sprintf("-n '__fish_seen_subcommand_from %s'", strings.Join(append([]string{c.name}, c.aliases...), " "))
+		for _, d := range c.flags {
+			fmt.Fprintf(w, "complete -c todo %s%s\n", cond, fishFlag(d))
+		}
+		if c.ids {
+			fmt.Fprintf(w, "complete -c todo %s -f -a '(__todo_ids)'\n", cond)
+		}
+	}
+}
+
+// fishFlag turns a flag into complete's options: -l for long names, -s for
+// short ones and -r when it takes a value.
+func fishFlag(d flagDef) string {
+	var b strings.Builder
+	for _, n := range d.names {
+		if len(n) == 1 {
+			fmt.Fprintf(&b, " -s %s", n)
+		} else {
+			fmt.Fprintf(&b, " -l %s", n)
+		}
+	}
+	if !d.boolean {
+		b.WriteString(" -r")
+	}
+	return b.String()
+}
@@ -0,0 +1,411 @@
+// config.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// Config holds persistent preferences from config.toml. Environment
+// variables and command-line flags override them.
+type Config struct {
+	DefaultList    string
+	DateFormat     string
+	ShowCompleted  bool
+	Color          string
+	ListFormat     string
+	Storage        string
+	Backups        *int
+	KeyFile        string
+	GitSync        bool
+	Autocorrect    bool
+	KeepWhitespace bool
+	WeekStart      string
+	NotifyWindow   string
+	WebhookURL     string
+	WebhookEvents  string
+	HookTimeout    string
+}
+
+// config is loaded once at startup by main.
+var config Config
+
+// configKey describes one setting of config.toml.
+type configKey struct {
+	name    string
+	help    string
+	boolean bool
+	get     func(*Config) string
+	set     func(*Config, string) error
+}
+
+var configKeys = []configKey{
+	{
+		name: "default_list",
+		help: "list used when --list and TODO_LIST are unset",
+		get:  func(c *Config) string { return c.DefaultList },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if err := validateListName(v); err != nil {
+					return err
+				}
+			}
+			c.DefaultList = v
+			return nil
+		},
+	},
+	{
+		name: "date_format",
+		help: "Go time layout for displayed dates, e.g. 02 Jan 2006 15:04",
+		get:  func(c *Config) string { return c.DateFormat },
+		set: func(c *Config, v string) error {
+			c.DateFormat = v
+			return nil
+		},
+	},
+	{
+		name:    "show_completed",
+		help:    "list completed tasks without --all",
+		boolean: true,
+		get:     func(c *Config) string { return strconv.FormatBool(c.ShowCompleted) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for show_completed: use true or false", v)
+			}
+			c.ShowCompleted = b
+			return nil
+		},
+	},
+	{
+		name: "color",
+		help: "auto, always or never",
+		get:  func(c *Config) string { return c.Color },
+		set: func(c *Config, v string) error {
+			switch v {
+			case "", "auto", "always", "never":
+				c.Color = v
+				return nil
+			}
+			return fmt.Errorf("invalid value %q for color: use auto, always or never", v)
+		},
+	},
+	{
+		name: "list_format",
+		help: "default --format template for list",
+		get:  func(c *Config) string { return c.ListFormat },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if _, err := parseListFormat(v); err != nil {
+					return err
+				}
+			}
+			c.ListFormat = v
+			return nil
+		},
+	},
+	{
+		name: "storage",
+		help: "json, sqlite or journal; TODO_BACKEND overrides it",
+		get:  func(c *Config) string { return c.Storage },
+		set: func(c *Config, v string) error {
+			if err := validateBackend(v); err != nil {
+				return err
+			}
+			c.Storage = v
+			return nil
+		},
+	},
+	{
+		name: "backups",
+		help: "number of automatic backups kept per list; 0 turns them off",
+		get: func(c *Config) string {
+			if c.Backups == nil {
+				return strconv.Itoa(defaultBackups)
+			}
+			return strconv.Itoa(*c.Backups)
+		},
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid value %q for backups: use a number, 0 or more", v)
+			}
+			c.Backups = &n
+			return nil
+		},
+	},
+	{
+		name: "key_file",
+		help: "file holding the passphrase that encrypts tasks; TODO_PASSPHRASE overrides it",
+		get:  func(c *Config) string { return c.KeyFile },
+		set: func(c *Config, v string) error {
+			c.KeyFile = v
+			return nil
+		},
+	},
+	{
+		name:    "git_sync",
+		help:    "commit the data directory to git after every change",
+		boolean: true,
+		get:     func(c *Config) string { return strconv.FormatBool(c.GitSync) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for git_sync: use true or false", v)
+			}
+			c.GitSync = b
+			return nil
+		},
+	},
+	{
+		name:    "autocorrect",
+		help:    "run the command a mistyped one most likely meant",
+		boolean: true,
+		get:     func(c *Config) string { return strconv.FormatBool(c.Autocorrect) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for autocorrect: use true or false", v)
+			}
+			c.Autocorrect = b
+			return nil
+		},
+	},
+	{
+		name:    "keep_whitespace",
+		help:    "keep runs of spaces in task titles instead of collapsing them",
+		boolean: true,
+		get:     func(c *Config) string { return strconv.FormatBool(c.KeepWhitespace) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for keep_whitespace: use true or false", v)
+			}
+			c.KeepWhitespace = b
+			return nil
+		},
+	},
+	{
+		name: "week_start",
+		help: "first day of the week in cal: monday or sunday",
+		get:  func(c *Config) string { return c.WeekStart },
+		set: func(c *Config, v string) error {
+			switch v = strings.ToLower(v); v {
+			case "", "monday", "sunday":
+				c.WeekStart = v
+				return nil
+			}
+			return fmt.Errorf("invalid value %q for week_start: use monday or sunday", v)
+		},
+	},
+	{
+		name: "notify_window",
+		help: "how far ahead notify looks for due tasks, e.g. 30m or 2h (default 1h)",
+		get:  func(c *Config) string { return c.NotifyWindow },
+		set: func(c *Config, v string) error {
+			if d, err := time.ParseDuration(v); v != "" && (err != nil || d < 0) {
+				return fmt.Errorf("invalid value %q for notify_window: use a duration such as 30m or 2h", v)
+			}
+			c.NotifyWindow = v
+			return nil
+		},
+	},
+	{
+		name: "webhook_url",
+		help: "URL to POST task events to, e.g. a Slack incoming webhook",
+		get:  func(c *Config) string { return c.WebhookURL },
+		set: func(c *Config, v string) error {
+			if u, err := url.Parse(v); v != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+				return fmt.Errorf("invalid value %q for webhook_url: use an http or https URL", v)
+			}
+			c.WebhookURL = v
+			return nil
+		},
+	},
+	{
+		name: "webhook_events",
+		help: "events sent to webhook_url, from add, done and rm (default all)",
+		get:  func(c *Config) string { return c.WebhookEvents },
+		set: func(c *Config, v string) error {
+			var events []string
+			for e := range strings.SplitSeq(strings.ToLower(v), ",") {
+				if e = strings.TrimSpace(e); e == "" {
+					continue
+				}
+				if !slices.Contains(webhookEventNames, e) {
+					return fmt.Errorf("invalid event %q in webhook_events: use add, done or rm", e)
+				}
+				if !slices.Contains(events, e) {
+					events = append(events, e)
+				}
+			}
+			c.WebhookEvents = strings.Join(events, ",")
+			return nil
+		},
+	},
+	{
+		name: "hook_timeout",
+		help: "how long a pre-save or post-save hook may run, e.g. 30s (default 10s)",
+		get:  func(c *Config) string { return c.HookTimeout },
+		set: func(c *Config, v string) error {
+			if d, err := time.ParseDuration(v); v != "" && (err != nil || d <= 0) {
+				return fmt.Errorf("invalid value %q for hook_timeout: use a duration such as 10s or 1m", v)
+			}
+			c.HookTimeout = v
+			return nil
+		},
+	},
+}
+
+func findConfigKey(name string) (configKey, error) {
+	names := make([]string, len(configKeys))
+	for i, k := range configKeys {
+		if k.name == name {
+			return k, nil
+		}
+		names[i] = k.name
+	}
+	return configKey{}, usageErrorf("unknown config key %q (valid keys: %s)", name, strings.Join(names, ", "))
+}
+
+// configFilePath returns $XDG_CONFIG_HOME/todo/config.toml. A config file
+// left in the data directory by the old ~/.todo layout is moved there.
+func configFilePath() (string, error) {
+	dir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "config.toml")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		data, err := dataDir()
+		if err != nil {
+			return "", err
+		}
+		old := filepath.Join(data, "config.toml")
+		if _, err := os.Stat(old); err == nil {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return "", err
+			}
+			if err := os.Rename(old, path); err != nil {
+				return old, nil
+			}
+		}
+	}
+	return path, nil
+}
+
+// loadConfig reads config.toml. Only flat `key = value` lines are
+// supported, with quoted strings, booleans and # comments.
+func loadConfig() (Config, error) {
+	var c Config
+	path, err := configFilePath()
+	if err != nil {
+		return c, err
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return c, fmt.Errorf("%s:%d: expected key = value", path, n)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			if value, err = strconv.Unquote(value); err != nil {
+				return c, fmt.Errorf("%s:%d: bad string value", path, n)
+			}
+		}
+		k, err := findConfigKey(name)
+		if err != nil {
+			return c, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		if err := k.set(&c, value); err != nil {
+			return c, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+	}
+	return c, sc.Err()
+}
+
+// saveConfig rewrites config.toml with every key that differs from its
+// default, using the same temp-file-and-rename pattern as saveTasks.
+func saveConfig(c Config) error {
+	path, err := configFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var zero Config
+	var buf bytes.Buffer
+	for _, k := range configKeys {
+		v := k.get(&c)
+		if v == k.get(&zero) {
+			continue
+		}
+		if !k.boolean {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(&buf, "%s = %s\n", k.name, v)
+	}
+	return todo.WriteFileAtomic(path, buf.Bytes())
+}
+
+func cmdConfig(args []string) error {
+	_ = args
+	if len(args) == 0 {
+		for _, k := range configKeys {
+			fmt.Printf("%-16s %-10q %s\n", k.name, k.get(&config), k.help)
+		}
+		return nil
+	}
+	switch {
+	case args[0] == "get" && len(args) == 2:
+		k, err := findConfigKey(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(k.get(&config))
+		return nil
+	case args[0] == "set" && len(args) >= 3:
+		k, err := findConfigKey(args[1])
+		if err != nil {
+			return err
+		}
+		// re-read the file so values overridden on this command line
+		// aren't written back
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := k.set(&c, strings.Join(args[2:], " ")); err != nil {
+			return usageErrorf("%w", err)
+		}
+		if err := saveConfig(c); err != nil {
+			return err
+		}
+		say("Set %s = %s\n", k.name, k.get(&c))
+		return nil
+	}
+	return usageError("config")
+}
@@ -0,0 +1,151 @@
+// crypt.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// passphrase encrypts the JSON task files when set. run loads it from
+// TODO_PASSPHRASE or the key_file config key.
+var passphrase string
+
+func loadPassphrase() (string, error) {
+	if p := os.Getenv("TODO_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	path := config.KeyFile
+	if path == "" {
+		return "", nil
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, rest)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("key_file: %v", err)
+	}
+	p := strings.TrimRight(string(b), "\r\n")
+	if p == "" {
+		return "", fmt.Errorf("key_file %s is empty", path)
+	}
+	return p, nil
+}
+
+// encryptedFiles returns every JSON file kept for the current list: the
+// tasks file, its undo snapshot, the archive, the trash and the backups.
+func encryptedFiles() ([]string, error) {
+	path, err := tasksFilePath()
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path, sideFile(path, "undo")}
+	for _, name := range []string{"archive", "trash"} {
+		p, err := companionPath(name)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, p)
+	}
+	_, _, bs, err := backupFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bs {
+		files = append(files, b.path)
+	}
+	return files, nil
+}
+
+// convertFiles encrypts or decrypts the current list's files in place and
+// returns how many it changed. Files already in the wanted form are left
+// alone, so an interrupted run can be repeated.
+func convertFiles(encrypt bool) (int, error) {
+	if backend() != "json" {
+		return 0, fmt.Errorf("encryption works on JSON files; the storage backend is %s", backend())
+	}
+	if passphrase == "" {
+		return 0, errors.New("set TODO_PASSPHRASE or key_file first")
+	}
+	files, err := encryptedFiles()
+	if err != nil {
+		return 0, err
+	}
+	verb := "Decrypted"
+	if encrypt {
+		verb = "Encrypted"
+	}
+	n := 0
+	for _, path := range files {
+		b, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return n, dataError(err)
+		}
+		if todo.IsEncrypted(b) == encrypt {
+			continue
+		}
+		if encrypt {
+			if !json.Valid(b) {
+				return n, dataError(fmt.Errorf("%s is not a valid tasks file", path))
+			}
+			b, err = todo.Encrypt(b, passphrase)
+		} else {
+			b, err = todo.Decrypt(b, passphrase)
+		}
+		if err != nil {
+			return n, dataError(fmt.Errorf("%s: %w", path, err))
+		}
+		if err := todo.WriteFileAtomic(path, b); err != nil {
+			return n, dataError(err)
+		}
+		say("%s %s\n", verb, filepath.Base(path))
+		n++
+	}
+	return n, nil
+}
+
+func cmdEncrypt(args []string) error {
+	_ = args
+	if len(args) > 0 {
+		return usageError("encrypt")
+	}
+	n, err := convertFiles(true)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		say("Nothing to encrypt.\n")
+	}
+	return nil
+}
+
+func cmdDecrypt(args []string) error {
+	_ = args
+	if len(args) > 0 {
+		return usageError("decrypt")
+	}
+	n, err := convertFiles(false)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		say("Nothing to decrypt.\n")
+		return nil
+	}
+	// the next save would encrypt the file again
+	say("Unset TODO_PASSPHRASE and key_file to keep the files in plain JSON.\n")
+	return nil
+}
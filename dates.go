@@ -0,0 +1,178 @@
+// dates.go
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the formats accepted wherever a date is given on the
+// command line. Dates are interpreted in local time.
+var dateLayouts = []string{"2006-01-02", "2006-01-02 15:04"}
+
+// errDateForms lists every form parseDate understands.
+var errDateForms = errors.New(`expected YYYY-MM-DD, "YYYY-MM-DD HH:MM", today, tomorrow, eod, ` +
+	`a weekday, "next week", "in N days" or "in N weeks"`)
+
+func parseDate(s string) (time.Time, error) {
+	return parseDateAt(s, time.Now())
+}
+
+// parseDateAt parses an absolute date or one relative to now. A weekday
+// means its next occurrence after today, so "friday" said on a Friday is a
+// week away; "next friday" means the same. "next week" is the coming Monday
+// and "eod" is today at 23:59. Relative dates fall on midnight.
+func parseDateAt(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	today := startOfDay(now)
+	switch s {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "eod":
+		return today.Add(23*time.Hour + 59*time.Minute), nil
+	case "next week":
+		return startOfWeek(now).AddDate(0, 0, 7), nil
+	}
+	if wd, ok := parseWeekday(strings.TrimPrefix(s, "next ")); ok {
+		days := (int(wd) - int(now.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+	if rest, ok := strings.CutPrefix(s, "in "); ok {
+		fields := strings.Fields(rest)
+		if len(fields) == 2 {
+			n, err := strconv.Atoi(fields[0])
+			if err == nil && n >= 0 {
+				switch fields[1] {
+				case "day", "days":
+					return today.AddDate(0, 0, n), nil
+				case "week", "weeks":
+					return today.AddDate(0, 0, 7*n), nil
+				}
+			}
+		}
+	}
+	return time.Time{}, errDateForms
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseShift parses a postpone amount such as 3h, 1d or 2w and returns a
+// function applying it to a time.
+func parseShift(s string) (func(time.Time) time.Time, bool) {
+	if len(s) < 2 {
+		return nil, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return nil, false
+	}
+	switch s[len(s)-1] {
+	case 'm':
+		return func(t time.Time) time.Time { return t.Add(time.Duration(n) * time.Minute) }, true
+	case 'h':
+		return func(t time.Time) time.Time { return t.Add(time.Duration(n) * time.Hour) }, true
+	case 'd':
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, n) }, true
+	case 'w':
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, 7*n) }, true
+	}
+	return nil, false
+}
+
+// parseRepeat parses a recurrence rule: daily, weekly, monthly, yearly, or
+// an interval of days or weeks like 3d or 2w. The returned function moves
+// a date forward by n repetitions.
+func parseRepeat(rule string) (func(t time.Time, n int) time.Time, error) {
+	switch rule {
+	case "daily":
+		return func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }, nil
+	case "weekly":
+		return func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }, nil
+	case "monthly":
+		return func(t time.Time, n int) time.Time { return addMonths(t, n) }, nil
+	case "yearly":
+		return func(t time.Time, n int) time.Time { return addMonths(t, 12*n) }, nil
+	}
+	if len(rule) >= 2 {
+		k, err := strconv.Atoi(rule[:len(rule)-1])
+		if err == nil && k > 0 {
+			switch rule[len(rule)-1] {
+			case 'd':
+				return func(t time.Time, n int) time.Time { return t.AddDate(0, 0, k*n) }, nil
+			case 'w':
+				return func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*k*n) }, nil
+			}
+		}
+	}
+	return nil, usageErrorf("invalid repeat rule %q: use daily, weekly, monthly, yearly, Nd or Nw", rule)
+}
+
+// addMonths moves t by n months, clamping to the last day of a shorter
+// month so that Jan 31 is followed by Feb 28 rather than Mar 3.
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
+// formatDate prints a date without the time part when it falls on midnight,
+// which is how date-only input is stored. A configured date_format is used
+// as is.
+func formatDate(t time.Time) string {
+	if config.DateFormat == "" && t.Hour() == 0 && t.Minute() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return formatTime(t)
+}
+
+// formatTime prints a timestamp in the configured date_format.
+func formatTime(t time.Time) string {
+	if config.DateFormat != "" {
+		return t.Format(config.DateFormat)
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// calendarDays counts the days from the day of from to the day of to, so
+// that 23:00 to 01:00 the next morning is one day. DST changes don't count.
+func calendarDays(from, to time.Time) int {
+	y1, m1, d1 := from.Date()
+	y2, m2, d2 := to.In(from.Location()).Date()
+	a := time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC)
+	b := time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// startOfWeek returns midnight of the Monday on or before t.
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -offset)
+}
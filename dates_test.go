@@ -0,0 +1,66 @@
+// dates_test.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateAt(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, zone) }
+	friday := time.Date(2024, time.July, 5, 15, 30, 0, 0, zone)
+	sunday := time.Date(2024, time.July, 7, 23, 0, 0, 0, zone)
+	monday := time.Date(2024, time.July, 8, 0, 10, 0, 0, zone)
+	monthEnd := time.Date(2024, time.July, 30, 12, 0, 0, 0, zone)
+	tests := []struct {
+		in   string
+		now  time.Time
+		want time.Time
+	}{
+		{"2024-08-01", friday, day(2024, time.August, 1)},
+		{"2024-08-01 09:45", friday, time.Date(2024, time.August, 1, 9, 45, 0, 0, zone)},
+		{"today", friday, day(2024, time.July, 5)},
+		{"  Today ", friday, day(2024, time.July, 5)},
+		{"tomorrow", friday, day(2024, time.July, 6)},
+		{"tomorrow", monthEnd.AddDate(0, 0, 1), day(2024, time.August, 1)},
+		{"eod", friday, time.Date(2024, time.July, 5, 23, 59, 0, 0, zone)},
+		// a weekday is its next occurrence, a week away on that day itself
+		{"friday", friday, day(2024, time.July, 12)},
+		{"fri", friday, day(2024, time.July, 12)},
+		{"next friday", friday, day(2024, time.July, 12)},
+		{"saturday", friday, day(2024, time.July, 6)},
+		{"thursday", friday, day(2024, time.July, 11)},
+		{"monday", sunday, day(2024, time.July, 8)},
+		{"sunday", sunday, day(2024, time.July, 14)},
+		// next week is the coming Monday, from any day of this one
+		{"next week", friday, day(2024, time.July, 8)},
+		{"next week", sunday, day(2024, time.July, 8)},
+		{"next week", monday, day(2024, time.July, 15)},
+		{"in 0 days", friday, day(2024, time.July, 5)},
+		{"in 1 day", friday, day(2024, time.July, 6)},
+		{"in 3 days", monthEnd, day(2024, time.August, 2)},
+		{"in 2 weeks", friday, day(2024, time.July, 19)},
+		{"in 1 week", sunday, day(2024, time.July, 14)},
+	}
+	for _, tt := range tests {
+		got, err := parseDateAt(tt.in, tt.now)
+		if err != nil {
+			t.Errorf("parseDateAt(%q, %s): %v", tt.in, tt.now.Format(time.RFC3339), err)
+			continue
+		}
+		if !got.Equal(tt.want) || got.Location() != zone {
+			t.Errorf("parseDateAt(%q, %s) = %s, want %s", tt.in, tt.now.Format("Mon 2006-01-02 15:04"),
+				got.Format(time.RFC3339), tt.want.Format(time.RFC3339))
+		}
+	}
+}
+
+func TestParseDateAtErrors(t *testing.T) {
+	now := time.Date(2024, time.July, 5, 15, 30, 0, 0, time.UTC)
+	for _, in := range []string{"", "soon", "next", "next month", "in days", "in -1 days", "in 3 months", "2024-13-01", "07/05/2024", "fridays"} {
+		if got, err := parseDateAt(in, now); err != errDateForms {
+			t.Errorf("parseDateAt(%q) = %v, %v; want the list of forms", in, got, err)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// dedupe.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// dupKey is what two titles must share to count as the same task: case
+// and spacing are ignored.
+func dupKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// checkDuplicates warns about titles that match existing tasks and, for a
+// pending match, asks before adding them, unless dup is set. Without a
+// terminal to ask on it refuses instead.
+func checkDuplicates(ts Tasks, titles []string, dup, canAsk bool) error {
+	pending := map[string]Task{}
+	done := map[string]Task{}
+	for _, t := range ts {
+		if t.Done {
+			done[dupKey(t.Title)] = t
+		} else if _, ok := pending[dupKey(t.Title)]; !ok {
+			pending[dupKey(t.Title)] = t
+		}
+	}
+	similar := 0
+	for _, title := range titles {
+		if t, ok := pending[dupKey(title)]; ok {
+			fmt.Fprintf(os.Stderr, "A similar task already exists: %d) %s\n", t.ID, t.Title)
+			similar++
+		} else if t, ok := done[dupKey(title)]; ok {
+			fmt.Fprintf(os.Stderr, "Note: %d) %s is already done.\n", t.ID, t.Title)
+		}
+	}
+	if similar == 0 || dup {
+		return nil
+	}
+	if !canAsk {
+		return errors.New("not adding a duplicate; give --dup to add it anyway")
+	}
+	ok, err := confirm("Add it anyway?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("not adding a duplicate")
+	}
+	return nil
+}
+
+// mergeTask folds ts[drop] into ts[keep] and removes it from ts: the notes
+// are appended, the tags combined, the earlier creation time and due date
+// kept, and subtasks and dependencies pointing at the dropped task moved
+// over to the kept one. It returns the new list, the dropped task and
+// notes on the links moved.
+func mergeTask(ts Tasks, keep, drop int) (Tasks, Task, []string) {
+	k, d := &ts[keep], ts[drop]
+	if d.Notes != "" {
+		if k.Notes != "" {
+			k.Notes += "\n"
+		}
+		k.Notes += d.Notes
+	}
+	k.Tags = normalizeTags(append(k.Tags, d.Tags...))
+	if d.CreatedAt.Before(k.CreatedAt) {
+		k.CreatedAt = d.CreatedAt
+	}
+	if d.DueDate != nil && (k.DueDate == nil || d.DueDate.Before(*k.DueDate)) {
+		k.DueDate = d.DueDate
+	}
+	if k.Priority == priorityNone {
+		k.Priority = d.Priority
+	}
+	k.Pinned = k.Pinned || d.Pinned
+	if k.URL == "" {
+		k.URL = d.URL
+	}
+	for _, p := range d.Attachments {
+		if !slices.Contains(k.Attachments, p) {
+			k.Attachments = append(k.Attachments, p)
+		}
+	}
+	if len(d.Sessions) > 0 {
+		k.Sessions = append(slices.Clone(k.Sessions), d.Sessions...)
+		slices.SortStableFunc(k.Sessions, func(a, b todo.Session) int { return a.Start.Compare(b.Start) })
+	}
+	for _, dep := range d.DependsOn {
+		if dep != k.ID && !slices.Contains(k.DependsOn, dep) {
+			k.DependsOn = append(k.DependsOn, dep)
+		}
+	}
+	var notes []string
+	for i := range ts {
+		if i == drop {
+			continue
+		}
+		t := &ts[i]
+		if t.ParentID != nil && *t.ParentID == d.ID && t.ID != k.ID {
+			t.ParentID = &k.ID
+			notes = append(notes, fmt.Sprintf("Moved subtask %d to task %d", t.ID, k.ID))
+		}
+		if j := slices.Index(t.DependsOn, d.ID); j != -1 {
+			t.DependsOn = slices.Delete(t.DependsOn, j, j+1)
+			if t.ID != k.ID && !slices.Contains(t.DependsOn, k.ID) {
+				t.DependsOn = append(t.DependsOn, k.ID)
+				notes = append(notes, fmt.Sprintf("Task %d now depends on %d instead of %d", t.ID, k.ID, d.ID))
+			}
+		}
+	}
+	if k.ParentID != nil && *k.ParentID == d.ID {
+		k.ParentID = d.ParentID
+	}
+	return slices.Delete(ts, drop, drop+1), d, notes
+}
+
+var dedupeFlags = []flagDef{boolFlag("merge")}
+
+// cmdDedupe reports pending tasks with the same title and, with --merge,
+// merges each group into its lowest ID.
+func cmdDedupe(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, dedupeFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("dedupe")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	groups := map[string][]int64{}
+	var keys []string
+	for _, t := range ts {
+		if t.Done {
+			continue
+		}
+		key := dupKey(t.Title)
+		if groups[key] == nil {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], t.ID)
+	}
+	keys = slices.DeleteFunc(keys, func(k string) bool { return len(groups[k]) < 2 })
+	if len(keys) == 0 {
+		fmt.Println("No duplicate tasks.")
+		return nil
+	}
+	var removed Tasks
+	var lines, notes []string
+	for _, key := range keys {
+		ids := groups[key]
+		slices.Sort(ids)
+		keep := ts[ts.Index(ids[0])]
+		dups := make([]string, len(ids)-1)
+		for i, id := range ids[1:] {
+			dups[i] = fmt.Sprint(id)
+		}
+		if !ca.has("merge") {
+			fmt.Printf("%d) %s: duplicated by %s\n", keep.ID, keep.Title, strings.Join(dups, ", "))
+			continue
+		}
+		for _, id := range ids[1:] {
+			var d Task
+			var n []string
+			ts, d, n = mergeTask(ts, ts.Index(keep.ID), ts.Index(id))
+			removed = append(removed, d)
+			notes = append(notes, n...)
+		}
+		lines = append(lines, fmt.Sprintf("Merged %s into %d: %s", strings.Join(dups, ", "), keep.ID, keep.Title))
+	}
+	if !ca.has("merge") {
+		return nil
+	}
+	if err := moveToTrash(removed); err != nil {
+		return err
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	for _, line := range append(lines, notes...) {
+		say("%s\n", line)
+	}
+	return nil
+}
+
+// cmdMerge folds one task into another with mergeTask, sending the dropped
+// one to the trash, and says what the kept task gained.
+func cmdMerge(args []string) error {
+	_ = args
+	if len(args) != 2 {
+		return usageError("merge")
+	}
+	keepID, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	dropID, err := parseID(args[1])
+	if err != nil {
+		return err
+	}
+	if keepID == dropID {
+		return usageErrorf("can't merge task %d into itself", keepID)
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	keep, drop := ts.Index(keepID), ts.Index(dropID)
+	switch {
+	case keep == -1 && drop == -1:
+		return notFoundErrorf("tasks %d, %d not found", keepID, dropID)
+	case keep == -1:
+		return notFoundErrorf("task %d not found", keepID)
+	case drop == -1:
+		return notFoundErrorf("task %d not found", dropID)
+	}
+	before := ts[keep]
+	ts, d, notes := mergeTask(ts, keep, drop)
+	k := ts[ts.Index(keepID)]
+	lines := []string{fmt.Sprintf("Merged %d into %d: %s", d.ID, k.ID, k.Title)}
+	if k.Notes != before.Notes {
+		lines = append(lines, fmt.Sprintf("Appended the notes of %d", d.ID))
+	}
+	var added []string
+	for _, tag := range k.Tags {
+		if !before.HasTag(tag) {
+			added = append(added, "#"+tag)
+		}
+	}
+	if len(added) > 0 {
+		lines = append(lines, "Added tags "+strings.Join(added, " "))
+	}
+	if k.DueDate != before.DueDate {
+		lines = append(lines, "Due "+formatDate(*k.DueDate))
+	}
+	if k.Priority != before.Priority {
+		lines = append(lines, "Priority "+priorityNames[k.Priority])
+	}
+	if !k.CreatedAt.Equal(before.CreatedAt) {
+		lines = append(lines, "Created "+formatTime(k.CreatedAt))
+	}
+	if err := moveToTrash(Tasks{d}); err != nil {
+		return err
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	for _, line := range append(lines, notes...) {
+		say("%s\n", line)
+	}
+	return nil
+}
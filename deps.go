@@ -0,0 +1,159 @@
+// deps.go
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// dependsTransitively reports whether from depends on to, directly or
+// through other tasks.
+func dependsTransitively(ts Tasks, from, to int64) bool {
+	seen := map[int64]bool{}
+	queue := []int64{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == to {
+			return true
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if i := ts.Index(id); i != -1 {
+			queue = append(queue, ts[i].DependsOn...)
+		}
+	}
+	return false
+}
+
+var blockFlags = []flagDef{valueFlag("on")}
+
+func cmdBlock(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, blockFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) != 1 || !ca.has("on") {
+		return usageError("block")
+	}
+	id, err := parseID(ca.pos[0])
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	var added []string
+	for _, v := range ca.all("on") {
+		dep, err := parseID(v)
+		if err != nil {
+			return err
+		}
+		if ts.Index(dep) == -1 {
+			return notFoundErrorf("task %d not found", dep)
+		}
+		if dep == id || dependsTransitively(ts, dep, id) {
+			return fmt.Errorf("task %d already depends on %d; that would be a cycle", dep, id)
+		}
+		if !slices.Contains(ts[i].DependsOn, dep) {
+			ts[i].DependsOn = append(ts[i].DependsOn, dep)
+			added = append(added, v)
+		}
+	}
+	if len(added) == 0 {
+		say("Task %d already depends on those tasks.\n", id)
+		return nil
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	say("Task %d now depends on %s\n", id, strings.Join(added, ", "))
+	return nil
+}
+
+var unblockFlags = []flagDef{valueFlag("on")}
+
+func cmdUnblock(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, unblockFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) != 1 {
+		return usageError("unblock")
+	}
+	id, err := parseID(ca.pos[0])
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	before := len(ts[i].DependsOn)
+	if ca.has("on") {
+		for _, v := range ca.all("on") {
+			dep, err := parseID(v)
+			if err != nil {
+				return err
+			}
+			ts[i].DependsOn = slices.DeleteFunc(ts[i].DependsOn, func(d int64) bool { return d == dep })
+		}
+	} else {
+		ts[i].DependsOn = nil
+	}
+	if len(ts[i].DependsOn) == before {
+		say("Task %d has no such dependencies.\n", id)
+		return nil
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	say("Removed %d dependencies from task %d\n", before-len(ts[i].DependsOn), id)
+	return nil
+}
+
+var blockedFlags = []flagDef{jsonFlag, jsonlFlag, colorFlag}
+
+func cmdBlocked(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, blockedFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("blocked")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	blocked := ts.Filter(func(t Task) bool { return t.Blocked })
+	if wantsJSON(ca) || len(blocked) == 0 {
+		return printTasks(ca, blocked, "No blocked tasks.")
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	sortForDisplay(blocked)
+	for _, t := range blocked {
+		printTask(t)
+		for _, w := range ts.WaitingOn(t) {
+			fmt.Printf("    waiting on: %s\n", taskLine(w))
+		}
+	}
+	return nil
+}
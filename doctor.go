@@ -0,0 +1,238 @@
+// doctor.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+var doctorFlags = []flagDef{colorFlag}
+
+// doctor prints the outcome of each check and counts the failures.
+type doctor struct {
+	failed int
+}
+
+func (d *doctor) pass(format string, a ...any) { d.report(paint("PASS", ansiGreen), format, a...) }
+func (d *doctor) warn(format string, a ...any) { d.report(paint("WARN", ansiYellow), format, a...) }
+
+func (d *doctor) fail(format string, a ...any) {
+	d.failed++
+	d.report(paint("FAIL", ansiRed, ansiBold), format, a...)
+}
+
+func (d *doctor) report(label, format string, a ...any) {
+	fmt.Printf("%s  %s\n", label, fmt.Sprintf(format, a...))
+}
+
+// cmdDoctor checks the things that make tasks seem to go missing or
+// commands fail: the config, the tasks file and its directory, files left
+// behind by interrupted saves, duplicate IDs, the lock and the hooks. It
+// exits non-zero when a check fails; warnings alone don't count.
+func cmdDoctor(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, doctorFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("doctor")
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	path, source, err := resolveTasksFile()
+	if err != nil {
+		return err
+	}
+	store, err := dataFilePath()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Checking %s (%s backend, chosen by %s)\n", store, backend(), source)
+	var d doctor
+	checkConfig(&d)
+	if checkStoreFile(&d, store) {
+		checkTasks(&d, path)
+	}
+	checkWritable(&d, filepath.Dir(store))
+	checkTempFiles(&d, path)
+	checkLock(&d, sideFile(path, "lock"))
+	checkHooks(&d)
+	if d.failed > 0 {
+		fmt.Printf("%d %s failed.\n", d.failed, plural(d.failed, "check", "checks"))
+		return exitStatus(exitError)
+	}
+	fmt.Println("No problems found.")
+	return nil
+}
+
+func checkConfig(d *doctor) {
+	path, err := configFilePath()
+	if err != nil {
+		d.fail("config file: %v", err)
+		return
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		d.pass("no config file at %s; using the defaults", path)
+		return
+	}
+	if _, err := loadConfig(); err != nil {
+		d.fail("config file is ignored: %v", err)
+		return
+	}
+	d.pass("config file %s is valid", path)
+}
+
+// checkStoreFile looks at the file the backend stores to, and reports
+// whether there is anything in it to read.
+func checkStoreFile(d *doctor, store string) bool {
+	fi, err := os.Stat(store)
+	switch {
+	case os.IsNotExist(err):
+		d.pass("%s doesn't exist yet; the first change creates it", store)
+		return false
+	case err != nil:
+		d.fail("%v", err)
+		return false
+	case !fi.Mode().IsRegular():
+		d.fail("%s is not a regular file", store)
+		return false
+	}
+	mode := fi.Mode().Perm()
+	switch {
+	case runtime.GOOS == "windows":
+	case mode&0o600 != 0o600:
+		d.fail("%s is mode %04o, so you can't read and write it; chmod u+rw it", store, mode)
+		return mode&0o400 != 0
+	case mode&0o022 != 0:
+		d.warn("%s is mode %04o, so others can change it; chmod go-w it", store, mode)
+	default:
+		d.pass("%s has mode %04o", store, mode)
+	}
+	return true
+}
+
+// checkTasks reads the list without the salvaging loadTasks does, so a
+// damaged file is reported instead of repaired, and looks for IDs that more
+// than one task has.
+func checkTasks(d *doctor, path string) {
+	ts, err := readList(path)
+	if errors.Is(err, todo.ErrNoPassphrase) {
+		d.fail("%v; set TODO_PASSPHRASE or key_file", err)
+		return
+	}
+	if err != nil {
+		d.fail("tasks file doesn't parse: %v; any change salvages what it can, or see todo restore-backup", err)
+		return
+	}
+	d.pass("tasks file parses: %d %s", len(ts), plural(len(ts), "task", "tasks"))
+	count := map[int64]int{}
+	for _, t := range ts {
+		count[t.ID]++
+	}
+	var dups []string
+	for _, t := range ts {
+		if n := count[t.ID]; n > 1 {
+			dups = append(dups, fmt.Sprintf("%d (%d tasks)", t.ID, n))
+			count[t.ID] = 0
+		}
+	}
+	if len(dups) > 0 {
+		d.fail("IDs used by more than one task, which commands can't tell apart: %s", strings.Join(dups, ", "))
+		return
+	}
+	d.pass("no duplicate IDs")
+}
+
+// checkWritable makes sure saves can create their temp file in dir.
+func checkWritable(d *doctor, dir string) {
+	f, err := os.CreateTemp(dir, ".todo-doctor-*")
+	if err != nil {
+		d.fail("can't write to %s: %v", dir, errorText(err))
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+	d.pass("%s is writable", dir)
+}
+
+// checkTempFiles looks for the .tmp files a save writes before renaming
+// them into place, which stay behind only when it was cut short. A project
+// shares its directory, so only todo's files there are looked at.
+func checkTempFiles(d *doctor, path string) {
+	patterns := []string{filepath.Join(filepath.Dir(path), "*.tmp")}
+	if state := projectStateDir(path); state != "" {
+		patterns = []string{
+			filepath.Join(filepath.Dir(path), projectFileName+"*.tmp"),
+			filepath.Join(state, "*.tmp"),
+			filepath.Join(state, "backups", "*.tmp"),
+		}
+	}
+	var matches []string
+	for _, p := range patterns {
+		found, _ := filepath.Glob(p)
+		matches = append(matches, found...)
+	}
+	now := time.Now()
+	found := 0
+	for _, m := range matches {
+		fi, err := os.Stat(m)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		found++
+		d.warn("%s was left by an interrupted save (%s); remove it once no todo is running",
+			m, humanizeTime(fi.ModTime(), now))
+	}
+	if found == 0 {
+		d.pass("no files left by interrupted saves")
+	}
+}
+
+func checkLock(d *doctor, path string) {
+	switch held, stale := lockState(path); {
+	case stale:
+		d.warn("%s is a stale lock from a todo that didn't finish; the next change breaks it", path)
+	case held:
+		d.warn("%s is held by a todo that is running now", path)
+	default:
+		d.pass("the tasks file is not locked")
+	}
+}
+
+// checkHooks warns about hooks that are present but won't run.
+func checkHooks(d *doctor) {
+	dir, err := hooksDir()
+	if err != nil {
+		return
+	}
+	for _, name := range []string{"pre-save", "post-save"} {
+		path := filepath.Join(dir, name)
+		fi, err := os.Stat(path)
+		switch {
+		case err != nil:
+		case !fi.Mode().IsRegular():
+			d.warn("hook %s is not a regular file and doesn't run", path)
+		case runtime.GOOS != "windows" && fi.Mode().Perm()&0o111 == 0:
+			d.warn("hook %s is not executable and doesn't run; chmod +x it", path)
+		default:
+			d.pass("hook %s is executable", path)
+		}
+	}
+}
+
+// plural picks the word for n things.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,225 @@
+// editor.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// editorCommand returns the user's editor split into program and
+// arguments, so EDITOR="code --wait" works.
+func editorCommand() []string {
+	if f := strings.Fields(os.Getenv("EDITOR")); len(f) > 0 {
+		return f
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editText opens text in the user's editor and returns what was saved. The
+// temp file is removed however the editor exits; a non-zero exit is an
+// error so that nothing half-edited gets applied.
+func editText(text string) (string, error) {
+	f, err := os.CreateTemp("", "todo-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	argv := append(editorCommand(), f.Name())
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			return "", fmt.Errorf("editor exited with status %d; nothing changed", ee.ExitCode())
+		}
+		return "", fmt.Errorf("running editor %s: %v", argv[0], err)
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// editTitleNotes lets the user edit a title and notes as one buffer: the
+// first line is the title and everything after it the notes. ok is false
+// when the buffer was saved unchanged or with an empty first line.
+func editTitleNotes(title, notes string) (newTitle, newNotes string, ok bool, err error) {
+	text := title + "\n"
+	if notes != "" {
+		text += "\n" + notes + "\n"
+	}
+	edited, err := editText(text)
+	if err != nil {
+		return "", "", false, err
+	}
+	if edited == text {
+		say("No changes.\n")
+		return "", "", false, nil
+	}
+	edited = strings.ReplaceAll(edited, "\r\n", "\n")
+	first, rest, _ := strings.Cut(edited, "\n")
+	if first = strings.TrimSpace(first); first == "" {
+		say("Empty title; nothing changed.\n")
+		return "", "", false, nil
+	}
+	return first, strings.Trim(rest, "\n"), true, nil
+}
+
+// waitsForInput reports whether a command line will open an editor, read
+// tasks from stdin, ask which tasks to pick or ask for confirmation. Those
+// commands take the lock themselves once the input is in, so a long editing
+// session or an unanswered prompt doesn't make other invocations time out.
+func waitsForInput(cmd string, args []string) bool {
+	if cmd == "clear" || cmd == "restore-backup" {
+		// they ask before replacing the list unless --force is given
+		return !slices.Contains(args, "--force") && !slices.Contains(args, "-f")
+	}
+	if cmd == "split" {
+		// split with only an ID asks for the new titles in the editor
+		n := 0
+		for _, a := range args {
+			if !strings.HasPrefix(a, "-") {
+				n++
+			}
+		}
+		return n < 2
+	}
+	for _, a := range args {
+		if a == "--" {
+			break
+		}
+		if a == "--editor" || (cmd == "edit" && (a == "--all" || a == "-a")) || (cmd == "add" && a == "-") || a == "--pick" {
+			return true
+		}
+	}
+	return false
+}
+
+// editAll opens every pending task in the editor as "ID<TAB>title" lines.
+// Changed titles are applied, tasks whose lines were deleted go to the
+// trash and lines without an ID become new tasks. The edits are applied
+// to the list as it is once the editor exits, in a single save.
+func editAll() error {
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	pending := ts.Filter(func(t Task) bool { return !t.Done })
+	sortForDisplay(pending)
+	var buf strings.Builder
+	for _, t := range pending {
+		fmt.Fprintf(&buf, "%d\t%s\n", t.ID, t.Title)
+	}
+	edited, err := editText(buf.String())
+	if err != nil {
+		return err
+	}
+	if edited == buf.String() {
+		say("No changes.\n")
+		return nil
+	}
+	unlock, err := lockTasks()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if ts, err = loadTasks(); err != nil {
+		return err
+	}
+	seen := map[int64]bool{}
+	var renamed, added []string
+	var titles []string
+	for n, line := range strings.Split(edited, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		idText, title, ok := strings.Cut(line, "\t")
+		id, err := strconv.ParseInt(strings.TrimSpace(idText), 10, 64)
+		if !ok || err != nil {
+			if title, err := normalizeTitle(line); err != nil {
+				fmt.Fprintf(os.Stderr, "line %d: %v, ignored\n", n+1, err)
+			} else {
+				titles = append(titles, title)
+			}
+			continue
+		}
+		title, err = normalizeTitle(title)
+		i := ts.Index(id)
+		switch {
+		case i == -1:
+			fmt.Fprintf(os.Stderr, "line %d: task %d not found, ignored\n", n+1, id)
+		case seen[id]:
+			fmt.Fprintf(os.Stderr, "line %d: task %d listed twice, ignored\n", n+1, id)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "line %d: task %d: %v, ignored\n", n+1, id, err)
+			seen[id] = true
+		default:
+			seen[id] = true
+			if title != ts[i].Title {
+				ts[i].Title = title
+				renamed = append(renamed, fmt.Sprintf("Renamed %d: %s", id, title))
+			}
+		}
+	}
+	var removed Tasks
+	for _, t := range pending {
+		if seen[t.ID] {
+			continue
+		}
+		if i := ts.Index(t.ID); i != -1 {
+			removed = append(removed, ts[i])
+			ts = append(ts[:i], ts[i+1:]...)
+		}
+	}
+	detached := detachRemoved(ts, removed)
+	now := time.Now()
+	for _, title := range titles {
+		t := Task{ID: ts.NextID(), Title: title, CreatedAt: now}
+		ts = append(ts, t)
+		added = append(added, fmt.Sprintf("Added %d: %s", t.ID, title))
+	}
+	if len(renamed)+len(removed)+len(added) == 0 {
+		say("No changes.\n")
+		return nil
+	}
+	if len(removed) > 0 {
+		if err := moveToTrash(removed); err != nil {
+			return err
+		}
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	for _, line := range renamed {
+		say("%s\n", line)
+	}
+	for _, t := range removed {
+		say("Removed %d\n", t.ID)
+	}
+	for _, line := range detached {
+		say("%s\n", line)
+	}
+	for _, line := range added {
+		say("%s\n", line)
+	}
+	return nil
+}
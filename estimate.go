@@ -0,0 +1,126 @@
+// estimate.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// workDay is what a day means in an estimate such as 1d.
+const workDay = 8 * time.Hour
+
+// overrun is how many times its estimate a task may take before
+// estimate-report flags it. Its summary line says "twice".
+const overrun = 2
+
+var errEstimateForms = errors.New(`expected a duration such as 90m, 2h30m or 1d (8 hours)`)
+
+// parseEstimate reads a duration as time.ParseDuration does, optionally
+// led by a number of working days: 1d, 1.5d, 1d4h.
+func parseEstimate(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var d time.Duration
+	if days, rest, ok := strings.Cut(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, errEstimateForms
+		}
+		d, s = time.Duration(n*float64(workDay)), rest
+	}
+	if s != "" {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, errEstimateForms
+		}
+		d += v
+	}
+	if d < time.Minute {
+		return 0, errors.New("an estimate must be at least a minute")
+	}
+	return d, nil
+}
+
+// formatEstimate renders an estimate in hours and minutes, in a form
+// parseEstimate reads back, or "none".
+func formatEstimate(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h, m := d/time.Hour, (d%time.Hour)/time.Minute
+	switch {
+	case d <= 0:
+		return "none"
+	case h == 0:
+		return fmt.Sprintf("%dm", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	}
+	return fmt.Sprintf("%dh%dm", h, m)
+}
+
+var estimateReportFlags = []flagDef{boolFlag("over"), colorFlag}
+
+// cmdEstimateReport compares the estimate of each completed task, in the
+// list or archived, with the time it took: the time tracked with start and
+// stop when there is any, or else the time from creation to completion.
+func cmdEstimateReport(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, estimateReportFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("estimate-report")
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	done, err := completedHistory()
+	if err != nil {
+		return err
+	}
+	done = done.Filter(func(t Task) bool { return t.Estimate > 0 })
+	if len(done) == 0 {
+		fmt.Println("No completed tasks with an estimate.")
+		return nil
+	}
+	slices.SortStableFunc(done, func(a, b Task) int { return a.CompletedAt.Compare(*b.CompletedAt) })
+	type row struct {
+		line, estimate, took string
+		ratio                float64
+	}
+	var rows []row
+	over := 0
+	w := 0
+	for _, t := range done {
+		took, how := t.Tracked(*t.CompletedAt), "tracked"
+		if len(t.Sessions) == 0 {
+			took, how = t.CompletedAt.Sub(t.CreatedAt), "elapsed"
+		}
+		r := row{line: taskLine(t), estimate: formatEstimate(t.Estimate), took: formatTracked(took) + " " + how,
+			ratio: float64(took) / float64(t.Estimate)}
+		if r.ratio > overrun {
+			over++
+		} else if ca.has("over") {
+			continue
+		}
+		rows = append(rows, r)
+		w = max(w, utf8.RuneCountInString(r.line))
+	}
+	for _, r := range rows {
+		line := fmt.Sprintf("%s%s  %-7s  %-16s  %5.1fx", r.line, strings.Repeat(" ", w-utf8.RuneCountInString(r.line)),
+			r.estimate, r.took, r.ratio)
+		if r.ratio > overrun {
+			line = paint(line+"  over", ansiRed)
+		}
+		fmt.Println(line)
+	}
+	if len(rows) > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("%d of %d tasks took more than twice their estimate.\n", over, len(done))
+	return nil
+}
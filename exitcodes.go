@@ -0,0 +1,73 @@
+// exitcodes.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Exit codes returned by the todo command. They are part of its interface
+// for scripts and must not change meaning.
+const (
+	exitOK       = 0
+	exitError    = 1 // anything not covered below
+	exitUsage    = 2 // bad arguments or unknown command
+	exitNotFound = 3 // a task given by ID doesn't exist
+	exitData     = 4 // a tasks file can't be read or written
+)
+
+// codedError is an error that makes todo exit with a specific code.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e codedError) Error() string { return e.err.Error() }
+func (e codedError) Unwrap() error { return e.err }
+
+func usageErrorf(format string, a ...any) error {
+	return codedError{exitUsage, fmt.Errorf(format, a...)}
+}
+
+func notFoundErrorf(format string, a ...any) error {
+	return codedError{exitNotFound, fmt.Errorf(format, a...)}
+}
+
+// dataError marks err as a problem with a tasks file, unless it is nil or
+// already carries a code.
+func dataError(err error) error {
+	var c codedError
+	if err == nil || errors.As(err, &c) {
+		return err
+	}
+	return codedError{exitData, err}
+}
+
+// exitCode is the exit status for an error returned by a command.
+func exitCode(err error) int {
+	var es exitStatus
+	var c codedError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &es):
+		return int(es)
+	case errors.As(err, &c):
+		return c.code
+	}
+	return exitError
+}
+
+// parseID parses a task ID given on the command line, or the start of a
+// task's UID.
+func parseID(s string) (int64, error) {
+	if uidLike(s) || isUID(s) {
+		return findByUID(s)
+	}
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, usageErrorf("invalid task id %q", s)
+	}
+	return id, nil
+}
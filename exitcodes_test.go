@@ -0,0 +1,59 @@
+// exitcodes_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExitCodes runs commands the way main does and checks the exit code
+// scripts see for each kind of failure.
+func TestExitCodes(t *testing.T) {
+	corrupt := func(t *testing.T, home string) {
+		writeFile(t, filepath.Join(home, "data", "todo", "tasks.json"), "{not json")
+	}
+	encrypted := func(t *testing.T, home string) {
+		t.Setenv("TODO_PASSPHRASE", "s3cret")
+		for _, args := range [][]string{{"add", "Secret"}, {"encrypt"}} {
+			if code, _ := runTodo(t, args...); code != exitOK {
+				t.Fatalf("todo %s exited %d", strings.Join(args, " "), code)
+			}
+		}
+		os.Unsetenv("TODO_PASSPHRASE")
+	}
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, home string)
+		args  []string
+		want  int
+	}{
+		{name: "success", args: []string{"add", "Pay rent"}, want: exitOK},
+		{name: "no command", args: nil, want: exitUsage},
+		{name: "unknown command", args: []string{"frobnicate"}, want: exitUsage},
+		{name: "unknown flag", args: []string{"list", "--frobnicate"}, want: exitUsage},
+		{name: "missing argument", args: []string{"add"}, want: exitUsage},
+		{name: "bad ID", args: []string{"do", "3-1"}, want: exitUsage},
+		{name: "bad sort key", args: []string{"list", "--sort", "bogus"}, want: exitUsage},
+		{name: "task not found", args: []string{"do", "99"}, want: exitNotFound},
+		{name: "edit of a missing task", args: []string{"edit", "99", "New title"}, want: exitNotFound},
+		{name: "save over a corrupted file", setup: corrupt, args: []string{"add", "Pay rent"}, want: exitData},
+		{name: "encrypted without a passphrase", setup: encrypted, args: []string{"list"}, want: exitData},
+		{name: "wrong passphrase", setup: func(t *testing.T, home string) {
+			encrypted(t, home)
+			t.Setenv("TODO_PASSPHRASE", "wrong")
+		}, args: []string{"list"}, want: exitData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := testEnv(t)
+			if tt.setup != nil {
+				tt.setup(t, home)
+			}
+			if code, _ := runTodo(t, tt.args...); code != tt.want {
+				t.Errorf("todo %s exited %d, want %d", strings.Join(tt.args, " "), code, tt.want)
+			}
+		})
+	}
+}
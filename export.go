@@ -0,0 +1,127 @@
+// export.go
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// exporters maps each --format of `todo export` to its writer.
+var exporters = map[string]func(w io.Writer, ts Tasks) error{
+	"csv":      exportCSV,
+	"html":     exportHTML,
+	"ics":      exportICS,
+	"markdown": exportMarkdown,
+	"todotxt":  exportTodotxt,
+}
+
+func formatNames[V any](m map[string]V) string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+var exportFlags = []flagDef{valueFlag("format", "f"), valueFlag("output", "o"), boolFlag("only-pending"), boolFlag("due-only")}
+
+func cmdExport(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, exportFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("export")
+	}
+	format := ca.value("format")
+	if format == "" {
+		format = "csv"
+	}
+	export, ok := exporters[format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q (supported: %s)", format, formatNames(exporters))
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	if ca.has("only-pending") {
+		ts = ts.Filter(func(t Task) bool { return !t.Done })
+	}
+	if ca.has("due-only") {
+		ts = ts.Filter(func(t Task) bool { return t.DueDate != nil })
+	}
+	if !ca.has("output") {
+		return export(os.Stdout, ts)
+	}
+	var buf bytes.Buffer
+	if err := export(&buf, ts); err != nil {
+		return err
+	}
+	if err := todo.WriteFileAtomic(ca.value("output"), buf.Bytes()); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d tasks to %s\n", len(ts), ca.value("output"))
+	return nil
+}
+
+// csvHeader is the column layout shared by CSV export and import.
+var csvHeader = []string{"id", "title", "done", "created_at", "completed_at", "due_date", "priority", "tags", "notes", "pinned", "uid", "status", "estimate", "waiting", "waiting_since", "url"}
+
+func exportCSV(w io.Writer, ts Tasks) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, t := range ts {
+		priority := ""
+		if t.Priority != priorityNone {
+			priority = strconv.Itoa(t.Priority)
+		}
+		estimate := ""
+		if t.Estimate > 0 {
+			estimate = formatEstimate(t.Estimate)
+		}
+		row := []string{
+			strconv.FormatInt(t.ID, 10),
+			t.Title,
+			strconv.FormatBool(t.Done),
+			t.CreatedAt.Format(time.RFC3339),
+			formatRFC3339(t.CompletedAt),
+			formatRFC3339(t.DueDate),
+			priority,
+			strings.Join(t.Tags, ";"),
+			t.Notes,
+			strconv.FormatBool(t.Pinned),
+			t.UID,
+			t.State(),
+			estimate,
+			t.Waiting,
+			formatRFC3339(t.WaitingSince),
+			t.URL,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatRFC3339(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
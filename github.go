@@ -0,0 +1,233 @@
+// github.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// githubAPI is the REST API used unless GITHUB_API_URL names another, as
+// on GitHub Enterprise or in GitHub Actions.
+const githubAPI = "https://api.github.com"
+
+var githubRepoRE = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// githubIssue is the part of an issue from the REST API that makes a task.
+type githubIssue struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	State     string     `json:"state"`
+	HTMLURL   string     `json:"html_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+	Labels    []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	// set on pull requests, which the issues API lists too
+	PullRequest json.RawMessage `json:"pull_request"`
+}
+
+// importGitHub makes a task of each issue of a repository, or updates the
+// task made from it by an earlier import: its title, tags, URL and whether
+// it is done follow the issue, and the rest is left as it is.
+func importGitHub(ca cmdArgs) error {
+	repo := ca.value("repo")
+	if len(ca.pos) > 0 || repo == "" {
+		return usageError("import")
+	}
+	if !githubRepoRE.MatchString(repo) {
+		return usageErrorf("invalid --repo %q: expected owner/name", repo)
+	}
+	state := ca.value("state")
+	switch state {
+	case "":
+		state = "open"
+	case "open", "closed", "all":
+	default:
+		return usageErrorf("invalid --state %q: use open, closed or all", state)
+	}
+	if ca.has("keep-ids") {
+		return usageErrorf("--keep-ids doesn't apply to --format github")
+	}
+	issues, err := fetchGitHubIssues(repo, state)
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	next := ts.NextID()
+	var added, updated Tasks
+	for _, is := range issues {
+		t, err := githubTask(repo, is)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "issue #%d: %v, skipped\n", is.Number, err)
+			continue
+		}
+		i := slices.IndexFunc(ts, func(o Task) bool { return o.UID == t.UID })
+		if i == -1 {
+			t.ID = next
+			next++
+			added = append(added, t)
+			continue
+		}
+		old := ts[i]
+		ts[i].Title, ts[i].Tags, ts[i].URL = t.Title, t.Tags, t.URL
+		if ts[i].Done != t.Done {
+			if t.Done {
+				ts[i].MarkDone(*t.CompletedAt)
+			} else {
+				ts[i].Done, ts[i].CompletedAt, ts[i].Status = false, nil, ""
+			}
+		}
+		if string(contents(ts[i])) != string(contents(old)) {
+			updated = append(updated, ts[i])
+		}
+	}
+	if ca.has("dry-run") {
+		for _, t := range added {
+			printTask(t)
+		}
+		for _, t := range updated {
+			printTask(t)
+		}
+		fmt.Printf("Would import %d issues and update %d.\n", len(added), len(updated))
+		return nil
+	}
+	if len(added) > 0 || len(updated) > 0 {
+		if err := saveTasks(append(ts, added...)); err != nil {
+			return err
+		}
+	}
+	say("Imported %d issues and updated %d from %s.\n", len(added), len(updated), repo)
+	return nil
+}
+
+// githubUID is the UID of the task made from an issue, the same on every
+// import.
+func githubUID(repo string, number int) string {
+	return todo.NamedUID(fmt.Sprintf("github.com/%s/issues/%d", strings.ToLower(repo), number))
+}
+
+func githubTask(repo string, is githubIssue) (Task, error) {
+	title, err := normalizeTitle(fmt.Sprintf("#%d %s", is.Number, is.Title))
+	if err != nil {
+		return Task{}, err
+	}
+	t := Task{UID: githubUID(repo, is.Number), Title: title, CreatedAt: is.CreatedAt.Local(), URL: is.HTMLURL}
+	var tags []string
+	for _, l := range is.Labels {
+		// a tag is one word
+		tags = append(tags, strings.Join(strings.Fields(l.Name), "-"))
+	}
+	t.Tags = normalizeTags(tags)
+	if is.State == "closed" {
+		closed := is.CreatedAt
+		if is.ClosedAt != nil {
+			closed = *is.ClosedAt
+		}
+		t.MarkDone(closed.Local())
+	}
+	return t, nil
+}
+
+// fetchGitHubIssues lists the issues of repo in state, following the Link
+// header from page to page. Pull requests are left out.
+func fetchGitHubIssues(repo, state string) ([]githubIssue, error) {
+	base := os.Getenv("GITHUB_API_URL")
+	if base == "" {
+		base = githubAPI
+	}
+	q := url.Values{"state": {state}, "per_page": {"100"}, "sort": {"created"}, "direction": {"asc"}}
+	next := strings.TrimSuffix(base, "/") + "/repos/" + repo + "/issues?" + q.Encode()
+	client := &http.Client{Timeout: 30 * time.Second}
+	var issues []githubIssue
+	for next != "" {
+		req, err := http.NewRequest(http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page []githubIssue
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&page)
+		} else {
+			err = githubError(repo, resp)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, is := range page {
+			if is.PullRequest == nil {
+				issues = append(issues, is)
+			}
+		}
+		next = nextLink(resp.Header.Get("Link"))
+	}
+	return issues, nil
+}
+
+// githubError explains a failed request, telling a spent rate limit apart
+// from other refusals.
+func githubError(repo string, resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Message == "" {
+		body.Message = resp.Status
+	}
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0"
+	if limited {
+		when := "later"
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			when = "at " + formatTime(time.Unix(reset, 0))
+		} else if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			when = "in " + formatTracked(time.Duration(secs)*time.Second)
+		}
+		if os.Getenv("GITHUB_TOKEN") == "" {
+			return fmt.Errorf("GitHub rate limit reached; try again %s, or set GITHUB_TOKEN for a higher limit", when)
+		}
+		return fmt.Errorf("GitHub rate limit reached; try again %s", when)
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return notFoundErrorf("GitHub repository %s not found (set GITHUB_TOKEN for a private one)", repo)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("GitHub refused GITHUB_TOKEN: %s", body.Message)
+	}
+	return fmt.Errorf("GitHub: %s", body.Message)
+}
+
+// nextLink returns the rel="next" URL of a Link header, or "".
+func nextLink(header string) string {
+	for link := range strings.SplitSeq(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(target), "<>")
+	}
+	return ""
+}
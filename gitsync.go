@@ -0,0 +1,176 @@
+// gitsync.go
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitIgnore keeps the files only one machine needs out of the repository
+// that `todo sync --init` creates.
+const gitIgnore = `*.lock
+*.tmp
+*.undo
+*.broken.*
+backups/
+`
+
+// gitDir is the directory git_sync commits: the one holding the tasks file.
+// A project file is left to the project's own repository.
+func gitDir() (string, error) {
+	path, err := tasksFilePath()
+	if err != nil {
+		return "", err
+	}
+	if usingProjectFile() {
+		return "", fmt.Errorf("%s belongs to its project, which git sync leaves alone; use --global for your own lists", path)
+	}
+	return filepath.Dir(path), nil
+}
+
+// git runs a git command in dir and returns its combined output. A failure
+// carries the output, trimmed, as the error message.
+func git(dir string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(out.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return out.String(), fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return out.String(), nil
+}
+
+func isGitRepo(dir string) bool {
+	out, err := git(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// gitCommit commits every change under dir, if there are any.
+func gitCommit(dir, message string) error {
+	out, err := git(dir, "status", "--porcelain", "--", ".")
+	if err != nil || strings.TrimSpace(out) == "" {
+		return err
+	}
+	if _, err := git(dir, "add", "-A", "--", "."); err != nil {
+		return err
+	}
+	_, err = git(dir, "commit", "-q", "-m", message, "--", ".")
+	return err
+}
+
+// conflicted returns the files with unresolved merge conflicts.
+func conflicted(dir string) []string {
+	out, _ := git(dir, "diff", "--name-only", "--diff-filter=U")
+	return strings.Fields(out)
+}
+
+func conflictError(dir string, files []string) error {
+	return fmt.Errorf("merge conflict in %s; resolve it in %s, run `git rebase --continue` there, then `todo sync` again",
+		strings.Join(files, ", "), dir)
+}
+
+// autoCommit records the change a command made when git_sync is on. It
+// only warns on failure: the change itself is already saved.
+func autoCommit(argv []string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: git_sync is on but git is not installed; change not committed")
+		return
+	}
+	dir, err := gitDir()
+	if err != nil {
+		return
+	}
+	if !isGitRepo(dir) {
+		fmt.Fprintf(os.Stderr, "Warning: git_sync is on but %s is not a git repository; run `todo sync --init`\n", dir)
+		return
+	}
+	if conflicted(dir) != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s has an unresolved merge conflict; change not committed\n", dir)
+		return
+	}
+	if err := gitCommit(dir, "todo: "+strings.Join(argv, " ")); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+	}
+}
+
+var syncFlags = []flagDef{boolFlag("init"), valueFlag("remote"), valueFlag("token"), boolFlag("dry-run", "n")}
+
+// cmdSync commits pending changes, then pulls with rebase and pushes.
+// --init turns the data directory into a repository first. With --remote
+// it merges with another instance's `todo serve` instead.
+func cmdSync(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, syncFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 || ca.has("init") && ca.has("remote") || !ca.has("remote") && (ca.has("token") || ca.has("dry-run")) {
+		return usageError("sync")
+	}
+	if ca.has("remote") {
+		r := &remote{url: ca.value("remote"), token: ca.value("token"), client: &http.Client{Timeout: 30 * time.Second}}
+		return syncRemote(r, ca.has("dry-run"))
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return errors.New("sync needs git, which is not installed")
+	}
+	dir, err := gitDir()
+	if err != nil {
+		return err
+	}
+	if ca.has("init") {
+		return gitInit(dir)
+	}
+	if !isGitRepo(dir) {
+		return fmt.Errorf("%s is not a git repository; run `todo sync --init` to make it one", dir)
+	}
+	if files := conflicted(dir); files != nil {
+		return conflictError(dir, files)
+	}
+	if err := gitCommit(dir, "todo: sync"); err != nil {
+		return err
+	}
+	if _, err := git(dir, "pull", "--rebase", "-q"); err != nil {
+		if files := conflicted(dir); files != nil {
+			return conflictError(dir, files)
+		}
+		return err
+	}
+	if _, err := git(dir, "push", "-q"); err != nil {
+		return err
+	}
+	say("Synced %s.\n", dir)
+	return nil
+}
+
+func gitInit(dir string) error {
+	if isGitRepo(dir) {
+		return fmt.Errorf("%s is already a git repository", dir)
+	}
+	if _, err := git(dir, "init", "-q"); err != nil {
+		return err
+	}
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte(gitIgnore), 0o644); err != nil {
+			return err
+		}
+	}
+	if err := gitCommit(dir, "todo: sync --init"); err != nil {
+		return err
+	}
+	say("Initialized a git repository in %s.\n", dir)
+	say("Add a remote with `git -C %s remote add origin <url>`, then run `todo sync`.\n", dir)
+	return nil
+}
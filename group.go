@@ -0,0 +1,113 @@
+// group.go
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// groupKinds are the values list --group-by takes.
+var groupKinds = []string{"tag", "priority", "due"}
+
+// taskGroup is one section of a grouped listing. shared counts the tasks
+// that also appear in another section.
+type taskGroup struct {
+	name   string
+	tasks  Tasks
+	shared int
+}
+
+// groupTasks sorts tasks into sections, keeping their order within each. By
+// tag, a task is listed under every tag it has, and the untagged ones come
+// last; by priority, high comes first; by due, the sections run from overdue
+// to no due date.
+func groupTasks(ts Tasks, by string, now time.Time) []taskGroup {
+	var names []string
+	members := map[string]Tasks{}
+	add := func(name string, t Task) {
+		if _, ok := members[name]; !ok {
+			names = append(names, name)
+		}
+		members[name] = append(members[name], t)
+	}
+	switch by {
+	case "tag":
+		for _, t := range ts {
+			for _, tag := range t.Tags {
+				add("#"+tag, t)
+			}
+		}
+		slices.Sort(names)
+		for _, t := range ts {
+			if len(t.Tags) == 0 {
+				add("(no tag)", t)
+			}
+		}
+	case "priority":
+		for _, t := range ts {
+			name := "(no priority)"
+			if t.Priority != priorityNone {
+				name = strings.ToUpper(priorityNames[t.Priority][:1]) + priorityNames[t.Priority][1:] + " priority"
+			}
+			add(name, t)
+		}
+		slices.SortFunc(names, func(a, b string) int {
+			return priorityRank(members[a][0].Priority) - priorityRank(members[b][0].Priority)
+		})
+	case "due":
+		order := []string{"Overdue", "Earlier", "Today", "Tomorrow", "Next 7 days", "Later", "No due date"}
+		for _, t := range ts {
+			d, ok := dueIn(t, now)
+			switch {
+			case !ok:
+				add("No due date", t)
+			case t.IsOverdue(now):
+				add("Overdue", t)
+			case d < 0:
+				add("Earlier", t)
+			case d == 0:
+				add("Today", t)
+			case d == 1:
+				add("Tomorrow", t)
+			case d < 7:
+				add("Next 7 days", t)
+			default:
+				add("Later", t)
+			}
+		}
+		slices.SortFunc(names, func(a, b string) int { return slices.Index(order, a) - slices.Index(order, b) })
+	}
+	groups := make([]taskGroup, len(names))
+	for i, name := range names {
+		g := taskGroup{name: name, tasks: members[name]}
+		if by == "tag" {
+			for _, t := range g.tasks {
+				if len(t.Tags) > 1 {
+					g.shared++
+				}
+			}
+		}
+		groups[i] = g
+	}
+	return groups
+}
+
+// printGroups writes each section under a heading with its count.
+func printGroups(ts Tasks, by string, now time.Time) error {
+	for i, g := range groupTasks(ts, by, now) {
+		if i > 0 {
+			fmt.Println()
+		}
+		count := fmt.Sprint(len(g.tasks))
+		if g.shared > 0 {
+			count += fmt.Sprintf(", %d also under other tags", g.shared)
+		}
+		fmt.Println(paint(fmt.Sprintf("%s (%s):", g.name, count), ansiBold))
+		if err := printPlain(g.tasks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
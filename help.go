@@ -0,0 +1,215 @@
+// help.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// helpTopics are the subjects `todo help` explains besides commands.
+var helpTopics = map[string]string{
+	"dates": "Wherever a date is given (--due, --start, --from, defer, postpone, ...) it can be written as YYYY-MM-DD, " +
+		`"YYYY-MM-DD HH:MM", today, tomorrow, eod (today at 23:59), a weekday such as friday (its next occurrence), ` +
+		`"next week" (the coming Monday), "in N days" or "in N weeks". Dates are in local time, and the relative ` +
+		"ones fall on midnight.",
+	"titles": "do, rm, edit and show take a task's title instead of its ID: any argument that isn't made of digits " +
+		"and dashes or the start of a UID, or the value of --title, which also reaches titles that look like IDs. " +
+		"The match ignores case and spacing. A task whose title starts with the text beats one that only contains " +
+		"it, and pending tasks beat completed ones. More than one best match lists them and exits with status 3, as does no match.",
+	"pick": "do --pick and rm --pick list the pending tasks and ask which ones to act on. With fzf installed and " +
+		"output to a terminal, the list goes through fzf to select with tab; otherwise the tasks are numbered and " +
+		"the answer is a list of numbers and ranges such as 1 3 5-7. An empty answer, end of input or escaping " +
+		"fzf changes nothing.",
+	"exit-codes": "0 means success. 1 is any other error, and what overdue returns when tasks are overdue. 2 is a usage " +
+		"error: bad arguments, an unknown flag or an unknown command. 3 means a task given by ID doesn't exist, and 4 " +
+		"that a tasks file can't be read or written, or is corrupted.",
+}
+
+const usageHeader = "Usage: todo [--list <name>] [-q | -v] <command> [args]"
+
+const usageFooter = `--list <name> (or TODO_LIST) works on <name>.json instead of tasks.json in the data directory.
+A .todo.json in the working directory or above it (see todo init) is used instead; --global ignores it.
+--recover allows saving over a corrupted tasks file nothing could be recovered from.
+-q (--quiet) hides success messages; -v (--verbose) shows creation times and notes in listings.
+--no-webhook sends no events to webhook_url.
+Commands that print tasks accept --json (an array) or --jsonl (one object per line),
+and --color=auto|always|never (NO_COLOR disables auto color).
+Run todo help <command> for details, or todo help dates, titles, pick or exit-codes.`
+
+// usage prints the summary table of every command.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, usageHeader)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		if c.hidden {
+			continue
+		}
+		fmt.Fprintf(w, "  %-17s %s\n", strings.TrimSpace(c.name+" "+c.args), c.summary)
+	}
+	if plugins := pluginCommands(); len(plugins) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Plugin commands found on PATH:")
+		for _, name := range plugins {
+			fmt.Fprintf(w, "  %-17s runs %s%s\n", name, pluginPrefix, name)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, usageFooter)
+}
+
+// printHelp prints a command's usage, description and examples.
+func printHelp(w io.Writer, c command) {
+	for i, u := range c.usage {
+		if i == 0 {
+			fmt.Fprintf(w, "usage: todo %s\n", u)
+		} else {
+			fmt.Fprintf(w, "       todo %s\n", u)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, wrapText(c.help, 78))
+	if len(c.aliases) > 0 {
+		fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(c.aliases, ", "))
+	}
+	if len(c.examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, e := range c.examples {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+}
+
+func cmdHelp(args []string) error {
+	_ = args
+	switch len(args) {
+	case 0:
+		usage(os.Stdout)
+		return nil
+	case 1:
+	default:
+		return usageError("help")
+	}
+	if c, ok := findCommand(args[0]); ok && !c.hidden {
+		printHelp(os.Stdout, c)
+		return nil
+	}
+	if text, ok := helpTopics[args[0]]; ok {
+		fmt.Println(wrapText(text, 78))
+		return nil
+	}
+	switch names := suggestCommands(args[0]); len(names) {
+	case 0:
+		return usageErrorf("no help for %q; run todo help for the list of commands", args[0])
+	case 1:
+		return usageErrorf("no help for %q; did you mean %q?", args[0], names[0])
+	default:
+		return usageErrorf("no help for %q; did you mean one of %s?", args[0], strings.Join(names, ", "))
+	}
+}
+
+// wantsHelp reports whether a command's arguments ask for its help, before
+// any "--".
+func wantsHelp(args []string) bool {
+	for _, a := range args {
+		switch a {
+		case "--":
+			return false
+		case "--help", "-h":
+			return true
+		}
+	}
+	return false
+}
+
+// suggestCommands returns the commands a mistyped name most likely meant:
+// the names and aliases within two edits of it, or that it begins, keeping
+// only the nearest. A prefix counts as one edit, and the typo can't be
+// mostly edits, so that "xy" doesn't suggest "do" while "dome" does.
+func suggestCommands(name string) []string {
+	var names []string
+	best := 3
+	typed := utf8.RuneCountInString(name)
+	for _, c := range commands {
+		if c.hidden {
+			continue
+		}
+		for _, n := range append([]string{c.name}, c.aliases...) {
+			d := editDistance(name, n)
+			if d >= typed {
+				d = 3
+			}
+			if strings.HasPrefix(n, name) {
+				d = min(d, 1)
+			}
+			switch {
+			case d < best:
+				names, best = []string{n}, d
+			case d == best && d <= 2:
+				names = append(names, n)
+			}
+		}
+	}
+	return names
+}
+
+// unknownCommand reports a command that doesn't exist, with the ones it
+// may have meant.
+func unknownCommand(name string) {
+	switch names := suggestCommands(name); len(names) {
+	case 0:
+		fmt.Fprintln(os.Stderr, "Unknown command:", name)
+		usage(os.Stderr)
+	case 1:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\nDid you mean %q?\n", name, names[0])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\nDid you mean one of these?\n", name)
+		for _, n := range names {
+			fmt.Fprintf(os.Stderr, "  %s\n", n)
+		}
+	}
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// wrapText breaks s into lines of at most width columns between words.
+func wrapText(s string, width int) string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
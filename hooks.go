@@ -0,0 +1,167 @@
+// hooks.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultHookTimeout is how long a hook may run when hook_timeout is unset.
+const defaultHookTimeout = 10 * time.Second
+
+// commandName is the command being run, which hooks are told about.
+var commandName string
+
+// hookInput is what a hook reads on stdin: the tasks the save adds or
+// changes, as they are saved, and the tasks it removes.
+type hookInput struct {
+	Tasks   Tasks `json:"tasks"`
+	Removed Tasks `json:"removed"`
+}
+
+// hooksDir returns $XDG_CONFIG_HOME/todo/hooks, beside config.toml.
+func hooksDir() (string, error) {
+	dir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hooks"), nil
+}
+
+// hookPath returns the executable hook called name, or "" when there is
+// none.
+func hookPath(name string) string {
+	dir, err := hooksDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(dir, name)
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() || fi.Mode().Perm()&0o111 == 0 {
+		return ""
+	}
+	return path
+}
+
+// affectedTasks lists what saving ts changes since the list was loaded.
+// Without a loaded list, every task is new.
+func affectedTasks(ts Tasks) hookInput {
+	in := hookInput{Tasks{}, Tasks{}}
+	kept := map[string]bool{}
+	for _, t := range ts {
+		key := taskKey(t)
+		kept[key] = true
+		if old, ok := loaded[key]; !ok || string(contents(t)) != string(old.data) {
+			in.Tasks = append(in.Tasks, t)
+		}
+	}
+	for key, old := range loaded {
+		if kept[key] {
+			continue
+		}
+		var t Task
+		if json.Unmarshal(old.data, &t) == nil {
+			t.ID = old.id
+			in.Removed = append(in.Removed, t)
+		}
+	}
+	return in
+}
+
+// dataFilePath is the file the current list is saved to by the configured
+// backend.
+func dataFilePath() (string, error) {
+	path, err := tasksFilePath()
+	if err != nil {
+		return "", err
+	}
+	return backendFile(path), nil
+}
+
+// backendFile is the file the configured backend saves the list whose JSON
+// file is path to.
+func backendFile(path string) string {
+	switch backend() {
+	case "sqlite":
+		return sqlitePath(path)
+	case "journal":
+		return journalPath(path)
+	}
+	return path
+}
+
+// preSaveHook runs the pre-save hook, if there is one, before ts is saved.
+// A hook that exits non-zero, or runs past hook_timeout, stops the save
+// with its stderr as the reason.
+func preSaveHook(ts Tasks) error {
+	path := hookPath("pre-save")
+	if path == "" {
+		return nil
+	}
+	var stderr bytes.Buffer
+	err := runHook(path, affectedTasks(ts), &stderr)
+	if err == nil {
+		return nil
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("pre-save hook refused the change (%v): %s", err, msg)
+	}
+	return fmt.Errorf("pre-save hook refused the change: it %v", err)
+}
+
+// postSaveHook runs the post-save hook, if there is one, after a save.
+// The save has happened, so a failure is only a warning.
+func postSaveHook(in hookInput) {
+	path := hookPath("post-save")
+	if path == "" {
+		return
+	}
+	if err := runHook(path, in, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: post-save hook:", err)
+	}
+}
+
+// runHook runs the hook in path with the command name and the data file as
+// arguments and in as JSON on stdin, for at most hook_timeout. Its output
+// goes to stderr, keeping stdout for the command's own.
+func runHook(path string, in hookInput, stderr io.Writer) error {
+	file, err := dataFilePath()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	timeout := defaultHookTimeout
+	if config.HookTimeout != "" {
+		// validated when the config was read
+		timeout, _ = time.ParseDuration(config.HookTimeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, commandName, file)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout, cmd.Stderr = stderr, stderr
+	// a child the hook left behind holding its output open can't keep
+	// the command waiting either
+	cmd.WaitDelay = time.Second
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return fmt.Errorf("exited with status %d", exit.ExitCode())
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+// hooks_test.go
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// hookFixtures is testdata/hooks, found before testEnv changes directory.
+var hookFixtures, _ = filepath.Abs(filepath.Join("testdata", "hooks"))
+
+// installHooks copies the hook scripts in testdata/hooks into the config
+// directory testEnv set up, and returns the file the post-save hook logs
+// to.
+func installHooks(t *testing.T, names ...string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook fixtures are shell scripts")
+	}
+	dir, err := hooksDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		b, err := os.ReadFile(filepath.Join(hookFixtures, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log := filepath.Join(t.TempDir(), "hook.log")
+	t.Setenv("HOOK_LOG", log)
+	t.Setenv("HOOK_FAIL", "")
+	return log
+}
+
+func TestPreSaveHookRefuses(t *testing.T) {
+	testEnv(t)
+	installHooks(t, "pre-save")
+	if code, _ := runTodo(t, "add", "Pay rent"); code != exitOK {
+		t.Fatalf("add of an allowed task exited %d", code)
+	}
+	path, err := tasksFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"add", "Something forbidden"}, {"edit", "1", "Pay forbidden rent"}} {
+		if code, _ := runTodo(t, args...); code != exitError {
+			t.Errorf("todo %s exited %d, want %d", strings.Join(args, " "), code, exitError)
+		}
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("refused saves changed the file:\n%s\nto\n%s", before, after)
+	}
+
+	resetState()
+	ts, err := loadTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts = append(ts, Task{ID: 2, Title: "Still forbidden"})
+	err = saveTasks(ts)
+	if err == nil || !strings.Contains(err.Error(), "exited with status 1") || !strings.Contains(err.Error(), "no forbidden tasks, please") {
+		t.Errorf("saveTasks error = %v, want the hook's status and stderr", err)
+	}
+}
+
+func TestPostSaveHookRuns(t *testing.T) {
+	testEnv(t)
+	log := installHooks(t, "post-save")
+	for _, args := range [][]string{{"add", "Pay rent"}, {"add", "Call mum"}, {"do", "2"}} {
+		if code, _ := runTodo(t, args...); code != exitOK {
+			t.Fatalf("todo %s exited %d", strings.Join(args, " "), code)
+		}
+	}
+	file, err := dataFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := hookRuns(t, log)
+	if len(runs) != 3 {
+		t.Fatalf("post-save ran %d times, want 3", len(runs))
+	}
+	for i, cmd := range []string{"add", "add", "do"} {
+		if want := "args: " + cmd + " " + file; runs[i].args != want {
+			t.Errorf("run %d got %q, want %q", i+1, runs[i].args, want)
+		}
+	}
+	// each run is told only about the task the save changed
+	last := runs[2].in
+	if len(last.Tasks) != 1 || last.Tasks[0].ID != 2 || !last.Tasks[0].Done || len(last.Removed) != 0 {
+		t.Errorf("do 2 passed %+v", last)
+	}
+
+	if code, _ := runTodo(t, "rm", "--force", "1"); code != exitOK {
+		t.Fatalf("rm exited %d", code)
+	}
+	if runs = hookRuns(t, log); len(runs) != 4 || len(runs[3].in.Removed) != 1 || runs[3].in.Removed[0].Title != "Pay rent" {
+		t.Errorf("rm passed %+v", runs[len(runs)-1].in)
+	}
+
+	// the save has happened by then, so a failing hook doesn't fail it
+	t.Setenv("HOOK_FAIL", "1")
+	if code, _ := runTodo(t, "add", "Water plants"); code != exitOK {
+		t.Errorf("add with a failing post-save hook exited %d", code)
+	}
+	resetState()
+	if ts, err := loadTasks(); err != nil || len(ts) != 2 {
+		t.Errorf("after a failing post-save hook the list is %+v, %v", ts, err)
+	}
+}
+
+type hookRun struct {
+	args string
+	in   hookInput
+}
+
+// hookRuns reads what the post-save fixture logged: an args line and the
+// JSON it got for each run.
+func hookRuns(t *testing.T, log string) []hookRun {
+	t.Helper()
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs []hookRun
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		r := hookRun{args: lines[i]}
+		if err := json.Unmarshal([]byte(lines[i+1]), &r.in); err != nil {
+			t.Fatalf("hook input %q: %v", lines[i+1], err)
+		}
+		runs = append(runs, r)
+	}
+	return runs
+}
@@ -0,0 +1,106 @@
+// html.go
+package main
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// reportFS holds the page that --format html fills in, kept as a file of
+// its own so that it is easy to change.
+//
+//go:embed templates/report.html
+var reportFS embed.FS
+
+var reportTemplate = template.Must(template.ParseFS(reportFS, "templates/report.html"))
+
+// reportRow is a task as the report shows it.
+type reportRow struct {
+	ID        int64
+	Title     string
+	State     string
+	Tags      []string
+	Notes     string
+	URL       string
+	Priority  string
+	Due       string
+	Completed string
+	Overdue   bool
+	Pinned    bool
+}
+
+// reportPage is what the report template is given.
+type reportPage struct {
+	List      string
+	Pending   int
+	Overdue   int
+	Open      []reportRow
+	Done      []reportRow
+	Generated string
+}
+
+// exportHTML writes a self-contained page with the pending tasks in a
+// table, overdue ones highlighted, and the completed ones, most recent
+// first, in a section that starts collapsed. The template escapes every
+// title and note.
+func exportHTML(w io.Writer, ts Tasks) error {
+	now := time.Now()
+	list := activeList()
+	if list == "" {
+		list = currentDefaultList()
+	}
+	page := reportPage{List: list, Generated: formatTime(now)}
+	open := ts.Filter(func(t Task) bool { return !t.Done })
+	sortForDisplay(open)
+	pinnedFirst(open)
+	for _, t := range open {
+		r := reportTaskRow(t)
+		if t.DueDate != nil {
+			r.Due = formatDate(*t.DueDate)
+		}
+		if r.Overdue = t.IsOverdue(now); r.Overdue {
+			page.Overdue++
+		}
+		if s := t.State(); s != todo.StatusTodo {
+			r.State = s
+		}
+		page.Open = append(page.Open, r)
+	}
+	page.Pending = len(open)
+	done := ts.Filter(func(t Task) bool { return t.Done })
+	slices.SortStableFunc(done, func(a, b Task) int { return completedAt(b).Compare(completedAt(a)) })
+	for _, t := range done {
+		r := reportTaskRow(t)
+		if t.CompletedAt != nil {
+			r.Completed = formatTime(*t.CompletedAt)
+		}
+		page.Done = append(page.Done, r)
+	}
+	return reportTemplate.Execute(w, page)
+}
+
+// Class is the row's class attribute: overdue, pinned, both or neither.
+func (r reportRow) Class() string {
+	var cs []string
+	if r.Overdue {
+		cs = append(cs, "overdue")
+	}
+	if r.Pinned {
+		cs = append(cs, "pinned")
+	}
+	return strings.Join(cs, " ")
+}
+
+func reportTaskRow(t Task) reportRow {
+	r := reportRow{ID: t.ID, Title: t.Title, Tags: t.Tags, Notes: t.Notes, URL: t.URL, Pinned: t.Pinned}
+	if t.Priority != priorityNone {
+		r.Priority = priorityNames[t.Priority]
+	}
+	return r
+}
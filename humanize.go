@@ -0,0 +1,56 @@
+// humanize.go
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// humanizeTime describes t relative to now, like "2 hours ago", "yesterday"
+// or "in 3 days". Times within a day are given in minutes or hours, except
+// for date-only values (midnight), which are always counted in days.
+func humanizeTime(t, now time.Time) string {
+	d := t.Sub(now)
+	dateOnly := t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+	if !dateOnly && d.Abs() < 24*time.Hour {
+		switch {
+		case d.Abs() < time.Minute:
+			return "just now"
+		case d.Abs() < time.Hour:
+			return relative(int(d.Abs()/time.Minute), "minute", d > 0)
+		}
+		return relative(int(d.Abs()/time.Hour), "hour", d > 0)
+	}
+	// count calendar days, rounding so a DST change doesn't lose one
+	days := int(math.Round(startOfDay(t).Sub(startOfDay(now)).Hours() / 24))
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "tomorrow"
+	case -1:
+		return "yesterday"
+	}
+	future, n := days > 0, max(days, -days)
+	switch {
+	case n < 7:
+		return relative(n, "day", future)
+	case n < 30:
+		return relative(n/7, "week", future)
+	case n < 365:
+		return relative(n/30, "month", future)
+	}
+	return relative(n/365, "year", future)
+}
+
+func relative(n int, unit string, future bool) string {
+	s := fmt.Sprintf("%d %s", n, unit)
+	if n != 1 {
+		s += "s"
+	}
+	if future {
+		return "in " + s
+	}
+	return s + " ago"
+}
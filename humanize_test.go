@@ -0,0 +1,67 @@
+// humanize_test.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanizeTime(t *testing.T) {
+	now := time.Date(2024, time.July, 5, 15, 30, 0, 0, time.UTC)
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{now, "just now"},
+		{now.Add(-59 * time.Second), "just now"},
+		{now.Add(30 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(2 * time.Minute), "in 2 minutes"},
+		{now.Add(-59*time.Minute - 59*time.Second), "59 minutes ago"},
+		{now.Add(-time.Hour), "1 hour ago"},
+		{now.Add(5 * time.Hour), "in 5 hours"},
+		{now.Add(-23*time.Hour - 59*time.Minute), "23 hours ago"},
+		// past a day, calendar days count rather than hours
+		{now.Add(-24 * time.Hour), "yesterday"},
+		{now.Add(-40 * time.Hour), "2 days ago"},
+		{now.Add(24 * time.Hour), "tomorrow"},
+		// a date with no time of day is always counted in days
+		{day(time.July, 5), "today"},
+		{day(time.July, 6), "tomorrow"},
+		{day(time.July, 4), "yesterday"},
+		{day(time.July, 2), "3 days ago"},
+		{day(time.July, 11), "in 6 days"},
+		{day(time.July, 12), "in 1 week"},
+		{day(time.June, 6), "4 weeks ago"},
+		{day(time.August, 4), "in 1 month"},
+		{time.Date(2025, time.July, 4, 0, 0, 0, 0, time.UTC), "in 12 months"},
+		{time.Date(2025, time.July, 5, 0, 0, 0, 0, time.UTC), "in 1 year"},
+		{time.Date(2021, time.July, 1, 0, 0, 0, 0, time.UTC), "3 years ago"},
+	}
+	for _, tt := range tests {
+		if got := humanizeTime(tt.t, now); got != tt.want {
+			t.Errorf("humanizeTime(%s) = %q, want %q", tt.t.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
+
+// TestHumanizeTimeDST checks that a day made shorter or longer by a clock
+// change still counts as one.
+func TestHumanizeTimeDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	for _, tt := range []struct {
+		now, t time.Time
+		want   string
+	}{
+		{time.Date(2024, time.March, 9, 12, 0, 0, 0, loc), time.Date(2024, time.March, 11, 0, 0, 0, 0, loc), "in 2 days"},
+		{time.Date(2024, time.November, 4, 12, 0, 0, 0, loc), time.Date(2024, time.November, 2, 0, 0, 0, 0, loc), "2 days ago"},
+	} {
+		if got := humanizeTime(tt.t, tt.now); got != tt.want {
+			t.Errorf("humanizeTime(%s, %s) = %q, want %q", tt.t.Format(time.RFC3339), tt.now.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
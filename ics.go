@@ -0,0 +1,131 @@
+// ics.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// icsLineOctets is the longest a content line may be before it is folded,
+// not counting the CRLF (RFC 5545, section 3.1).
+const icsLineOctets = 75
+
+// icsWriter writes iCalendar content lines, folded and ended with CRLF.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes "name:value", folding it into lines of at most icsLineOctets
+// octets without splitting a UTF-8 sequence. Continuation lines start
+// with a space, which counts towards their length.
+func (iw *icsWriter) line(name, value string) {
+	s := name + ":" + value
+	limit := icsLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		iw.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = icsLineOctets - 1
+	}
+	iw.write(s + "\r\n")
+}
+
+func (iw *icsWriter) write(s string) {
+	if iw.err == nil {
+		_, iw.err = iw.w.WriteString(s)
+	}
+}
+
+// icsText escapes a TEXT value.
+var icsText = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icsTime formats a DATE-TIME in UTC.
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// date writes DUE or DTSTART: a date-only value when t falls on midnight,
+// as dates given without a time do, and otherwise a time in UTC.
+func (iw *icsWriter) date(name string, t time.Time) {
+	if t.Equal(startOfDay(t)) {
+		iw.line(name+";VALUE=DATE", t.Format("20060102"))
+		return
+	}
+	iw.line(name, icsTime(t))
+}
+
+// exportICS writes a VCALENDAR with a VTODO for each task, which calendar
+// apps show as reminders or tasks. A task keeps its UID across exports, so
+// importing again updates it instead of adding a copy.
+func exportICS(w io.Writer, ts Tasks) error {
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", "-//EternalKnight002//todo-cli//EN")
+	iw.line("CALSCALE", "GREGORIAN")
+	for _, t := range ts {
+		iw.line("BEGIN", "VTODO")
+		iw.line("UID", taskKey(t)+"@todo-cli")
+		iw.line("DTSTAMP", icsTime(modifiedAt(t)))
+		iw.line("CREATED", icsTime(t.CreatedAt))
+		iw.line("LAST-MODIFIED", icsTime(modifiedAt(t)))
+		iw.line("SUMMARY", icsText.Replace(t.Title))
+		if t.Notes != "" {
+			iw.line("DESCRIPTION", icsText.Replace(t.Notes))
+		}
+		if t.URL != "" {
+			iw.line("URL", t.URL)
+		}
+		// DTSTART must not come after DUE
+		if t.StartDate != nil && (t.DueDate == nil || !t.StartDate.After(*t.DueDate)) {
+			iw.date("DTSTART", *t.StartDate)
+		}
+		if t.DueDate != nil {
+			iw.date("DUE", *t.DueDate)
+		}
+		if t.Priority != priorityNone {
+			// 1 is the highest of iCalendar's 1 to 9, 5 medium and 9 lowest
+			iw.line("PRIORITY", fmt.Sprint(4*t.Priority-3))
+		}
+		if len(t.Tags) > 0 {
+			tags := make([]string, len(t.Tags))
+			for i, tag := range t.Tags {
+				tags[i] = icsText.Replace(tag)
+			}
+			iw.line("CATEGORIES", strings.Join(tags, ","))
+		}
+		if t.Pinned {
+			// iCalendar has no pinning; an X- property keeps it for tools
+			// that look
+			iw.line("X-TODO-PINNED", "TRUE")
+		}
+		switch t.State() {
+		case todo.StatusDone:
+			iw.line("STATUS", "COMPLETED")
+			iw.line("PERCENT-COMPLETE", "100")
+			if t.CompletedAt != nil {
+				iw.line("COMPLETED", icsTime(*t.CompletedAt))
+			}
+		case todo.StatusDoing:
+			iw.line("STATUS", "IN-PROCESS")
+		default:
+			iw.line("STATUS", "NEEDS-ACTION")
+		}
+		iw.line("END", "VTODO")
+	}
+	iw.line("END", "VCALENDAR")
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
@@ -0,0 +1,276 @@
+// import.go
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// importers maps each --format of `todo import` to its parser. Records that
+// can't be used are reported with skipRecord and left out; an error aborts
+// the whole import.
+var importers = map[string]func(r io.Reader) (ts Tasks, skipped int, err error){
+	"csv":         importCSV,
+	"markdown":    importMarkdown,
+	"taskwarrior": importTaskwarrior,
+	"todotxt":     importTodotxt,
+}
+
+// skipRecord reports a record an importer is leaving out.
+func skipRecord(line int, format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "line %d: %s, skipped\n", line, fmt.Sprintf(format, args...))
+}
+
+var importFlags = []flagDef{valueFlag("format", "f"), boolFlag("keep-ids"), boolFlag("dry-run", "n"), valueFlag("repo"),
+	valueFlag("state")}
+
+func cmdImport(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, importFlags...)
+	if err != nil {
+		return err
+	}
+	format := ca.value("format")
+	if format == "github" {
+		return importGitHub(ca)
+	}
+	if ca.has("repo") || ca.has("state") {
+		return usageErrorf("--repo and --state are for --format github")
+	}
+	if len(ca.pos) != 1 {
+		return usageError("import")
+	}
+	if format == "" {
+		format = "csv"
+	}
+	parse, ok := importers[format]
+	if !ok {
+		return fmt.Errorf("unknown import format %q (supported: %s, github)", format, formatNames(importers))
+	}
+	f, err := os.Open(ca.pos[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	imported, skipped, err := parse(f)
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	imported, known := dropKnownUIDs(ts, imported)
+	skipped += known
+	imported = assignImportIDs(ts, imported, ca.has("keep-ids"))
+	if ca.has("dry-run") {
+		for _, t := range imported {
+			printTask(t)
+		}
+		fmt.Printf("Would import %d tasks, skipped %d.\n", len(imported), skipped)
+		return nil
+	}
+	if len(imported) > 0 {
+		if err := saveTasks(append(ts, imported...)); err != nil {
+			return err
+		}
+	}
+	say("Imported %d tasks, skipped %d.\n", len(imported), skipped)
+	return nil
+}
+
+// dropKnownUIDs leaves out imported tasks that are already in the list, or
+// earlier in the file, by UID, returning how many it dropped.
+func dropKnownUIDs(existing, imported Tasks) (Tasks, int) {
+	known := map[string]bool{}
+	for _, t := range existing {
+		known[t.UID] = true
+	}
+	var out Tasks
+	for _, t := range imported {
+		if t.UID == "" {
+			out = append(out, t)
+			continue
+		}
+		if known[t.UID] {
+			fmt.Fprintf(os.Stderr, "%q: UID %s is already in the list, skipped\n", t.Title, t.UID)
+			continue
+		}
+		known[t.UID] = true
+		out = append(out, t)
+	}
+	return out, len(imported) - len(out)
+}
+
+// assignImportIDs gives imported tasks fresh IDs after the existing ones. With
+// keep set, a task keeps its own ID unless that is already taken.
+func assignImportIDs(existing, imported Tasks, keep bool) Tasks {
+	taken := map[int64]bool{}
+	for _, t := range existing {
+		taken[t.ID] = true
+	}
+	next := existing.NextID()
+	for i, t := range imported {
+		if keep && t.ID > 0 && !taken[t.ID] {
+			taken[t.ID] = true
+			next = max(next, t.ID+1)
+			continue
+		}
+		imported[i].ID = 0
+	}
+	for i := range imported {
+		if imported[i].ID == 0 {
+			imported[i].ID = next
+			next++
+		}
+	}
+	return imported
+}
+
+// importCSV reads the column layout written by exportCSV. Columns are found
+// by header name, so only title is required.
+func importCSV(r io.Reader) (Tasks, int, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading CSV header: %v", err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["title"]; !ok {
+		return nil, 0, errors.New("CSV header has no title column")
+	}
+	var ts Tasks
+	skipped := 0
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			skipRecord(perr.StartLine, "%v", perr.Err)
+			skipped++
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := col[name]; ok {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		t, err := csvTask(field)
+		if err != nil {
+			skipRecord(line, "%v", err)
+			skipped++
+			continue
+		}
+		ts = append(ts, t)
+	}
+	return ts, skipped, nil
+}
+
+func csvTask(field func(string) string) (Task, error) {
+	t := Task{CreatedAt: time.Now()}
+	title, err := normalizeTitle(field("title"))
+	if err != nil {
+		return t, err
+	}
+	t.Title = title
+	if v := field("id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return t, fmt.Errorf("invalid id %q", v)
+		}
+		t.ID = id
+	}
+	if v := field("uid"); v != "" {
+		if !uidLike(v) {
+			return t, fmt.Errorf("invalid uid %q", v)
+		}
+		t.UID = strings.ToLower(v)
+	}
+	if v := field("done"); v != "" {
+		done, err := strconv.ParseBool(v)
+		if err != nil {
+			return t, fmt.Errorf("invalid done value %q", v)
+		}
+		t.Done = done
+	}
+	t.Waiting = strings.Join(strings.Fields(field("waiting")), " ")
+	switch v := strings.ToLower(field("status")); v {
+	case "", todo.StatusTodo:
+	case todo.StatusDoing:
+		t.Status = v
+	case todo.StatusWaiting:
+		if t.Waiting == "" {
+			return t, errors.New("status waiting without a waiting column")
+		}
+	case todo.StatusDone:
+		t.Done = true
+	default:
+		return t, fmt.Errorf("invalid status %q", v)
+	}
+	if v := field("pinned"); v != "" {
+		pinned, err := strconv.ParseBool(v)
+		if err != nil {
+			return t, fmt.Errorf("invalid pinned value %q", v)
+		}
+		t.Pinned = pinned
+	}
+	if v := field("estimate"); v != "" {
+		d, err := parseEstimate(v)
+		if err != nil {
+			return t, fmt.Errorf("invalid estimate %q", v)
+		}
+		t.Estimate = d
+	}
+	for name, dst := range map[string]**time.Time{"completed_at": &t.CompletedAt, "due_date": &t.DueDate, "waiting_since": &t.WaitingSince} {
+		if v := field(name); v != "" {
+			ts, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return t, fmt.Errorf("invalid %s %q", name, v)
+			}
+			*dst = &ts
+		}
+	}
+	if v := field("created_at"); v != "" {
+		ts, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return t, fmt.Errorf("invalid created_at %q", v)
+		}
+		t.CreatedAt = ts
+	}
+	if v := field("priority"); v != "" {
+		p, err := parsePriority(v)
+		if err != nil {
+			return t, err
+		}
+		t.Priority = p
+	}
+	if v := field("tags"); v != "" {
+		t.Tags = normalizeTags(strings.Split(v, ";"))
+	}
+	t.Notes = field("notes")
+	if v := field("url"); v != "" {
+		if err := checkURL(v); err != nil {
+			return t, err
+		}
+		t.URL = v
+	}
+	return t, nil
+}
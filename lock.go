@@ -0,0 +1,33 @@
+// lock.go
+package main
+
+import "time"
+
+// lockTimeout is how long a command waits for another invocation to finish
+// with the tasks file before giving up.
+const lockTimeout = 10 * time.Second
+
+// mutatingCommands take the tasks file lock for their whole
+// load-modify-save sequence so concurrent invocations can't lose writes.
+var mutatingCommands = map[string]bool{
+	"add": true, "do": true, "complete": true, "undone": true, "reopen": true,
+	"rm": true, "remove": true, "restore": true, "trash": true, "edit": true,
+	"note": true, "move": true, "archive": true, "clear": true, "undo": true,
+	"import": true, "postpone": true, "defer": true,
+	"block": true, "unblock": true, "migrate": true, "compact": true,
+	"restore-backup": true, "encrypt": true, "decrypt": true,
+	"sync": true, "dedupe": true, "ui": true, "pin": true, "unpin": true,
+	"renumber": true, "dup": true, "merge": true, "split": true, "tags": true,
+	"start": true, "stop": true, "wait": true, "unwait": true,
+	"attach": true, "detach": true,
+}
+
+// lockTasks takes the lock guarding the current tasks file. The returned
+// function releases it.
+func lockTasks() (func(), error) {
+	path, err := tasksFilePath()
+	if err != nil {
+		return nil, err
+	}
+	return lockFile(sideFile(path, "lock"))
+}
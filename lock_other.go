@@ -0,0 +1,52 @@
+// lock_other.go
+
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// staleLockAge is how old a lock file may get before it is assumed to
+// belong to a crashed invocation and is broken.
+const staleLockAge = 5 * time.Second
+
+// lockFile takes the lock by creating path exclusively. Unlike flock the
+// file survives a crash, so locks older than staleLockAge are removed.
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > staleLockAge {
+			fmt.Fprintf(os.Stderr, "Warning: breaking stale lock %s\n", path)
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("tasks file is locked by another todo process")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// lockState reports whether the lock file at path exists, and whether it is
+// old enough that lockFile will break it.
+func lockState(path string) (held, stale bool) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, false
+	}
+	return true, time.Since(fi.ModTime()) > staleLockAge
+}
@@ -0,0 +1,126 @@
+// lock_test.go
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// TestLockKeepsConcurrentAdds has goroutines each add a task through the
+// lock and a store of their own, as separate todo invocations would, and
+// checks that no add is lost.
+func TestLockKeepsConcurrentAdds(t *testing.T) {
+	testEnv(t)
+	path, err := tasksFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for k := range n {
+		wg.Go(func() {
+			unlock, err := lockFile(sideFile(path, "lock"))
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer unlock()
+			s := &todo.FileStore{Path: path}
+			ts, err := s.Load()
+			if err != nil {
+				errs <- err
+				return
+			}
+			ts = append(ts, Task{ID: ts.NextID(), Title: fmt.Sprintf("task %d", k)})
+			errs <- s.Save(ts)
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkAllAdded(t, path, n)
+}
+
+// TestLockKeepsConcurrentProcesses runs todo add in parallel processes.
+func TestLockKeepsConcurrentProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts processes")
+	}
+	testEnv(t)
+	path, err := tasksFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 8
+	var wg sync.WaitGroup
+	for k := range n {
+		wg.Go(func() {
+			if out, err := todoCommand("-q", "add", "--dup", fmt.Sprintf("task %d", k)).CombinedOutput(); err != nil {
+				t.Errorf("todo add: %v\n%s", err, out)
+			}
+		})
+	}
+	wg.Wait()
+	checkAllAdded(t, path, n)
+}
+
+// checkAllAdded checks that the list in path holds tasks 0 to n-1, with
+// distinct IDs.
+func checkAllAdded(t *testing.T, path string, n int) {
+	t.Helper()
+	ts, err := todo.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	ids := map[int64]bool{}
+	for _, task := range ts {
+		titles = append(titles, task.Title)
+		ids[task.ID] = true
+	}
+	for k := range n {
+		if !slices.Contains(titles, fmt.Sprintf("task %d", k)) {
+			t.Errorf("task %d was lost; the list holds %q", k, titles)
+		}
+	}
+	if len(ids) != len(ts) {
+		t.Errorf("%d tasks share %d IDs", len(ts), len(ids))
+	}
+}
+
+// TestPromptsLockAfterAnswer checks that clear and restore-backup, which
+// ask before replacing the list, are left to take the lock themselves
+// unless --force skips the question, and that either way they don't wait
+// on a lock they already hold.
+func TestPromptsLockAfterAnswer(t *testing.T) {
+	for _, tt := range []struct {
+		cmd  string
+		args []string
+		want bool
+	}{
+		{"clear", nil, true},
+		{"clear", []string{"--force"}, false},
+		{"clear", []string{"-f"}, false},
+		{"restore-backup", []string{"20240101T000000"}, true},
+		{"restore-backup", []string{"--force", "20240101T000000"}, false},
+	} {
+		if got := waitsForInput(tt.cmd, tt.args); got != tt.want {
+			t.Errorf("waitsForInput(%q, %q) = %v, want %v", tt.cmd, tt.args, got, tt.want)
+		}
+	}
+	testEnv(t)
+	for _, args := range [][]string{{"clear"}, {"add", "Pay rent"}, {"clear", "--force"}, {"clear"}} {
+		if code, _ := runTodo(t, args...); code != exitOK {
+			t.Fatalf("todo %s exited %d", args, code)
+		}
+	}
+}
@@ -2,155 +2,1941 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// Task and Tasks come from the todo package; the aliases keep the CLI code
+// short.
+type (
+	Task  = todo.Task
+	Tasks = todo.Tasks
+)
+
+// exitStatus is returned by commands that report their result through the
+// process exit status; main exits with it without printing an error.
+type exitStatus int
+
+func (e exitStatus) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+
+// defaultList is the list used when none is selected; it lives in tasks.json.
+const defaultList = "tasks"
+
+// listName is the list selected with the global --list flag.
+var listName string
+
+// recoverFlag is set by the global --recover flag and allows saving over a
+// tasks file from which nothing could be salvaged.
+var recoverFlag bool
+
+// reservedListNames are file names in the data directory that belong to
+// the default list's companion files rather than to a list.
+var reservedListNames = map[string]bool{"archive": true, "trash": true}
+
+// activeList returns the selected list name, or "" when none was chosen
+// with --list or TODO_LIST.
+func activeList() string {
+	if listName != "" {
+		return listName
+	}
+	return os.Getenv("TODO_LIST")
+}
+
+func validateListName(name string) error {
+	if name == "" {
+		return errors.New("list name must not be empty")
+	}
+	if reservedListNames[name] {
+		return fmt.Errorf("invalid list name %q: the name is reserved", name)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("invalid list name %q: use letters, digits, '-' and '_'", name)
+		}
+	}
+	return nil
+}
+
+// xdgDir returns $<env>/todo, or ~/<fallback>/todo when the variable is unset.
+func xdgDir(env, fallback string) (string, error) {
+	if base := os.Getenv(env); base != "" {
+		return filepath.Join(base, "todo"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fallback, "todo"), nil
+}
+
+// legacyDataDir is where tasks lived before the XDG layout.
+func legacyDataDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".todo"), nil
+}
+
+// dataDir returns $XDG_DATA_HOME/todo (~/.local/share/todo by default). An
+// existing ~/.todo is moved there the first time; if that fails it keeps
+// being used.
+func dataDir() (string, error) {
+	dir, err := xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+	if err != nil {
+		return "", err
+	}
+	legacy, err := legacyDataDir()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if fi, err := os.Stat(legacy); err == nil && fi.IsDir() {
+			if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil || os.Rename(legacy, dir) != nil {
+				return legacy, nil
+			}
+			fmt.Fprintf(os.Stderr, "Moved your tasks from %s to %s.\n", legacy, dir)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// tasksFilePath resolves the tasks file: a selected list wins, then
+// TODO_FILE, then a project file, then the configured default list.
+func tasksFilePath() (string, error) {
+	path, _, err := resolveTasksFile()
+	return path, err
+}
+
+// resolveTasksFile is tasksFilePath, also saying what chose the file.
+func resolveTasksFile() (path, source string, err error) {
+	switch {
+	case listName != "":
+		path, err = listFilePath(listName)
+		return path, "--list", err
+	case os.Getenv("TODO_LIST") != "":
+		path, err = listFilePath(os.Getenv("TODO_LIST"))
+		return path, "TODO_LIST", err
+	case os.Getenv("TODO_FILE") != "":
+		return os.Getenv("TODO_FILE"), "TODO_FILE", nil
+	case projectFile() != "":
+		return projectFile(), "project file", nil
+	}
+	path, err = listFilePath(currentDefaultList())
+	return path, "default list", err
+}
+
+// currentDefaultList is the list used when none is selected.
+func currentDefaultList() string {
+	if config.DefaultList != "" {
+		return config.DefaultList
+	}
+	return defaultList
+}
+
+func listFilePath(name string) (string, error) {
+	if err := validateListName(name); err != nil {
+		return "", err
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+func cmdLists(args []string) error {
+	_ = args
+	if len(args) > 0 {
+		return usageError("lists")
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	current := activeList()
+	if current == "" {
+		current = currentDefaultList()
+	}
+	// a journal list may have only its journal until the first compact
+	exts := []string{".json"}
+	switch backend() {
+	case "sqlite":
+		exts = []string{".db"}
+	case "journal":
+		exts = append(exts, ".jsonl")
+	}
+	found := false
+	seen := map[string]bool{}
+	for _, e := range entries {
+		name, ext := e.Name(), filepath.Ext(e.Name())
+		name = strings.TrimSuffix(name, ext)
+		if !slices.Contains(exts, ext) || seen[name] || e.IsDir() || validateListName(name) != nil {
+			continue
+		}
+		seen[name] = true
+		ts, err := readList(filepath.Join(dir, name+".json"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Warning:", err)
+			continue
+		}
+		pending := len(ts.Filter(func(t Task) bool { return !t.Done }))
+		mark := " "
+		if name == current {
+			mark = "*"
+		}
+		fmt.Printf("%s %-16s %d pending / %d total\n", mark, name, pending, len(ts))
+		found = true
+	}
+	if !found {
+		fmt.Println("No lists.")
+	}
+	return nil
+}
+
+// taskStore is a todo.Store that can also take back its last save.
+type taskStore interface {
+	todo.Store
+	Undo() (bool, error)
+}
+
+// store holds the current list. It is created on first use, once --list
+// and --recover have been applied, and kept so that a corrupted file seen
+// by loadTasks is still refused by saveTasks.
+var store taskStore
+
+func tasksStore() (taskStore, error) {
+	if store == nil {
+		path, err := tasksFilePath()
+		if err != nil {
+			return nil, err
+		}
+		store = openStore(path)
+	}
+	return store, nil
+}
+
+// openStore creates the store for the list in path. todo shell swaps it
+// for one that keeps the list in memory between commands.
+var openStore = listStore
+
+// listStore returns the store holding the list whose JSON file is path in
+// the configured backend.
+func listStore(path string) taskStore {
+	switch backend() {
+	case "sqlite":
+		return todo.NewSQLiteStore(sqlitePath(path))
+	case "journal":
+		return todo.NewJournalStore(journalPath(path), path)
+	}
+	return &todo.FileStore{Path: path, UndoPath: sideFile(path, "undo"), Recover: recoverFlag, Warnings: os.Stderr, Passphrase: passphrase}
+}
+
+// closeStore releases a store that holds resources, like a database.
+func closeStore(s taskStore) {
+	if c, ok := s.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// backend returns the storage backend: TODO_BACKEND, then the storage
+// config key, then json.
+func backend() string {
+	if b := os.Getenv("TODO_BACKEND"); b != "" {
+		return b
+	}
+	if config.Storage != "" {
+		return config.Storage
+	}
+	return "json"
+}
+
+func validateBackend(b string) error {
+	switch b {
+	case "", "json", "sqlite", "journal":
+		return nil
+	}
+	return usageErrorf("invalid storage backend %q: use json, sqlite or journal", b)
+}
+
+// sqlitePath returns the database that replaces a list's JSON file with
+// the sqlite backend: tasks.db for tasks.json.
+func sqlitePath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".db"
+}
+
+// journalPath returns the journal kept beside a list's JSON file with the
+// journal backend: tasks.jsonl for tasks.json.
+func journalPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".jsonl"
+}
+
+// readList loads a list other than the current one, given by its JSON
+// path, from the configured backend.
+func readList(path string) (Tasks, error) {
+	if backend() == "json" {
+		return readTasksFile(path)
+	}
+	s := listStore(path)
+	defer closeStore(s)
+	ts, err := s.Load()
+	return ts, dataError(err)
+}
+
+func writeList(path string, ts Tasks) error {
+	if backend() == "json" {
+		return writeTasksFile(path, ts)
+	}
+	s := listStore(path)
+	defer closeStore(s)
+	return dataError(s.Save(ts))
+}
+
+// cmdCompact squashes the journal of the current list into a snapshot.
+func cmdCompact(args []string) error {
+	_ = args
+	if len(args) > 0 {
+		return usageError("compact")
+	}
+	s, err := tasksStore()
+	if err != nil {
+		return err
+	}
+	j, ok := s.(*todo.JournalStore)
+	if !ok {
+		return fmt.Errorf("compact needs the journal backend (storage = \"journal\")")
+	}
+	n, err := j.Compact()
+	if err != nil {
+		return dataError(err)
+	}
+	say("Compacted %d journal entries into %s.\n", n, j.Path)
+	return nil
+}
+
+// cmdMigrate copies the current list from its JSON file into the SQLite
+// database the sqlite backend reads. It runs once: a database that already
+// holds tasks is left alone.
+func cmdMigrate(args []string) error {
+	_ = args
+	if len(args) > 0 {
+		return usageError("migrate")
+	}
+	path, err := tasksFilePath()
+	if err != nil {
+		return err
+	}
+	ts, err := readTasksFile(path)
+	if err != nil {
+		return err
+	}
+	s := todo.NewSQLiteStore(sqlitePath(path))
+	defer s.Close()
+	existing, err := s.Load()
+	if err != nil {
+		return dataError(err)
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("%s already holds %d tasks; nothing migrated", s.Path, len(existing))
+	}
+	if err := s.Save(ts); err != nil {
+		return dataError(err)
+	}
+	say("Migrated %d tasks from %s to %s.\n", len(ts), path, s.Path)
+	if backend() != "sqlite" {
+		say("Run `todo config set storage sqlite` to use it.\n")
+	}
+	return nil
+}
+
+func loadTasks() (Tasks, error) {
+	s, err := tasksStore()
+	if err != nil {
+		return nil, err
+	}
+	ts, err := s.Load()
+	if errors.Is(err, todo.ErrNoPassphrase) {
+		err = fmt.Errorf("%v; set TODO_PASSPHRASE or key_file", err)
+	}
+	if err == nil {
+		ts.AssignUIDs()
+		backfillUpdated(ts)
+		rememberLoaded(ts)
+	}
+	return ts, dataError(err)
+}
+
+// saveTasks stores ts, stamping the tasks the command changed with
+// UpdatedAt and recording the ones it removed for sync.
+func saveTasks(ts Tasks) error {
+	s, err := tasksStore()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	assignNewUIDs(ts)
+	ts.SettleStatus()
+	ts.SettleSessions()
+	removed := stampChanges(ts, now)
+	events := taskEvents(ts, now)
+	if err := preSaveHook(ts); err != nil {
+		return err
+	}
+	changed := affectedTasks(ts)
+	if err := backupTasks(s); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: could not back up tasks:", err)
+	}
+	err = s.Save(ts)
+	if errors.Is(err, todo.ErrCorrupt) {
+		err = fmt.Errorf("%v; run again with --recover to start a new list", err)
+	}
+	if err != nil {
+		return dataError(err)
+	}
+	if err := updateTombstones(ts, removed, now); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: could not record deleted tasks for sync:", err)
+	}
+	pendingEvents = append(pendingEvents, events...)
+	rememberLoaded(ts)
+	postSaveHook(changed)
+	return nil
+}
+
+// companionPath returns the path of a file kept next to the tasks file:
+// archive.json beside tasks.json, notes.archive.json beside notes.json, or
+// .todo.d/archive.json for a project.
+func companionPath(name string) (string, error) {
+	path, err := tasksFilePath()
+	if err != nil {
+		return "", err
+	}
+	if dir := projectStateDir(path); dir != "" {
+		return filepath.Join(dir, name+".json"), nil
+	}
+	dir, base := filepath.Split(path)
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+	if stem == "tasks" {
+		return filepath.Join(dir, name+".json"), nil
+	}
+	return filepath.Join(dir, stem+"."+name+".json"), nil
+}
+
+// readTasksFile loads a task file other than the main one. A missing file is
+// an empty list; unlike loadTasks, a corrupted file is an error.
+func readTasksFile(path string) (Tasks, error) {
+	ts, err := todo.ReadEncrypted(path, passphrase)
+	ts.AssignUIDs()
+	backfillUpdated(ts)
+	return ts, dataError(err)
+}
+
+func writeTasksFile(path string, ts Tasks) error {
+	return dataError(todo.WriteEncrypted(path, ts, passphrase))
+}
+
+func cmdArchive(args []string) error {
+	_ = args
+	if len(args) > 0 {
+		return usageError("archive")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	done := ts.Filter(func(t Task) bool { return t.Done })
+	if len(done) == 0 {
+		say("Nothing to archive.\n")
+		return nil
+	}
+	path, err := companionPath("archive")
+	if err != nil {
+		return err
+	}
+	archived, err := readTasksFile(path)
+	if err != nil {
+		return err
+	}
+	// a crash after the archive write leaves tasks in both files; don't
+	// append them a second time when archive is run again
+	for _, t := range done {
+		if !containsTask(archived, t) {
+			archived = append(archived, t)
+		}
+	}
+	// archive first so a failure before tasks.json is rewritten never loses tasks
+	if err := writeTasksFile(path, archived); err != nil {
+		return err
+	}
+	if err := saveTasks(ts.Filter(func(t Task) bool { return !t.Done })); err != nil {
+		return err
+	}
+	say("Archived %d tasks.\n", len(done))
+	return nil
+}
+
+// containsTask reports whether ts holds the same task, matched by ID and
+// creation time since IDs can be reused once tasks leave the main file.
+func containsTask(ts Tasks, t Task) bool {
+	for _, o := range ts {
+		if o.ID == t.ID && o.CreatedAt.Equal(t.CreatedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func cmdUndo(args []string) error {
+	_ = args
+	if len(args) > 0 {
+		return usageError("undo")
+	}
+	s, err := tasksStore()
+	if err != nil {
+		return err
+	}
+	undone, err := s.Undo()
+	if err != nil {
+		return dataError(err)
+	}
+	if !undone {
+		say("Nothing to undo.\n")
+		return nil
+	}
+	say("Undid last change.\n")
+	return nil
+}
+
+// Priorities run from 1 (high) to 3 (low); 0 means none and sorts last.
+const (
+	priorityNone = 0
+	priorityHigh = 1
+	priorityLow  = 3
 )
 
-type Task struct {
-	ID          int64      `json:"id"`
-	Title       string     `json:"title"`
-	Done        bool       `json:"done"`
-	CreatedAt   time.Time  `json:"created_at"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
+func parsePriority(s string) (int, error) {
+	p, err := strconv.Atoi(s)
+	if err != nil || p < priorityNone || p > priorityLow {
+		return 0, usageErrorf("invalid priority %q: must be 0-3 (1 = high, 2 = medium, 3 = low, 0 = none)", s)
+	}
+	return p, nil
+}
+
+// priorityMarker renders a priority as a todo.txt style (A)/(B)/(C) marker.
+func priorityMarker(p int) string {
+	if p == priorityNone {
+		return ""
+	}
+	return fmt.Sprintf("(%c) ", 'A'+p-1)
+}
+
+// priorityRank orders tasks for display: high priority first, none last.
+func priorityRank(p int) int {
+	if p == priorityNone {
+		return priorityLow + 1
+	}
+	return p
+}
+
+// normalizeTag lowercases a tag and drops a leading '#'.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// normalizeTags normalizes each tag and removes empties and duplicates while
+// keeping the original order.
+func normalizeTags(tags []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+var addFlags = []flagDef{
+	valueFlag("due"), valueFlag("priority", "p"), valueFlag("tag", "t"), valueFlag("every"),
+	valueFlag("start"), valueFlag("under"), boolFlag("editor"), valueFlag("and"), boolFlag("dup"),
+	valueFlag("estimate", "e"), valueFlag("url"),
+}
+
+func cmdAdd(args []string) error {
+	_ = args // silence linter if you don't use args directly here
+	ca, err := parseArgs(args, addFlags...)
+	if err != nil {
+		return err
+	}
+	// "todo add -- -" adds a task titled "-"
+	fromStdin := len(ca.pos) == 1 && ca.pos[0] == "-" && !slices.Contains(args, "--")
+	if (len(ca.pos) == 0 && !ca.has("editor")) || (fromStdin && ca.has("editor")) ||
+		(ca.has("and") && (fromStdin || ca.has("editor"))) {
+		return usageError("add")
+	}
+	title := strings.Join(ca.pos, " ")
+	titles := batchTitles(append([]string{title}, ca.all("and")...), !slices.Contains(args, "--"))
+	if len(titles) == 0 && !ca.has("editor") {
+		return usageError("add")
+	}
+	var due *time.Time
+	if ca.has("due") {
+		d, err := parseDate(ca.value("due"))
+		if err != nil {
+			return usageErrorf("invalid due date %q: %v", ca.value("due"), err)
+		}
+		due = &d
+	}
+	priority := priorityNone
+	if ca.has("priority") {
+		if priority, err = parsePriority(ca.value("priority")); err != nil {
+			return err
+		}
+	}
+	var start *time.Time
+	if ca.has("start") {
+		d, err := parseDate(ca.value("start"))
+		if err != nil {
+			return usageErrorf("invalid start date %q: %v", ca.value("start"), err)
+		}
+		start = &d
+	}
+	repeat := strings.ToLower(ca.value("every"))
+	if repeat != "" {
+		if _, err := parseRepeat(repeat); err != nil {
+			return err
+		}
+	}
+	var estimate time.Duration
+	if ca.has("estimate") {
+		if estimate, err = parseEstimate(ca.value("estimate")); err != nil {
+			return usageErrorf("invalid estimate %q: %v", ca.value("estimate"), err)
+		}
+	}
+	if ca.has("url") {
+		if err := checkURL(ca.value("url")); err != nil {
+			return err
+		}
+	}
+	var notes string
+	if ca.has("editor") {
+		var ok bool
+		if title, notes, ok, err = editTitleNotes(title, ""); err != nil || !ok {
+			return err
+		}
+		titles = []string{title}
+	}
+	if fromStdin {
+		if isTerminal(os.Stdin) {
+			fmt.Fprintln(os.Stderr, "Enter one task per line, then end of file (Ctrl-D):")
+		}
+		if titles, err = readTitles(os.Stdin); err != nil {
+			return err
+		}
+		if len(titles) == 0 {
+			say("No tasks to add.\n")
+			return nil
+		}
+	}
+	for i := range titles {
+		if titles[i], err = normalizeTitle(titles[i]); err != nil {
+			return err
+		}
+	}
+	if ca.has("editor") || fromStdin {
+		unlock, err := lockTasks()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	var parent *int64
+	if ca.has("under") {
+		p, err := parseID(ca.value("under"))
+		if err != nil {
+			return err
+		}
+		if ts.Index(p) == -1 {
+			return notFoundErrorf("task %d not found", p)
+		}
+		parent = &p
+	}
+	if err := checkDuplicates(ts, titles, ca.has("dup"), isTerminal(os.Stdin) && !fromStdin); err != nil {
+		return err
+	}
+	first := ts.NextID()
+	now := time.Now()
+	for i, title := range titles {
+		link := ca.value("url")
+		if link == "" {
+			link = titleURL(title)
+		}
+		// creation times differ so --sort created keeps the order given
+		ts = append(ts, Task{
+			ID:        first + int64(i),
+			Title:     title,
+			Done:      false,
+			CreatedAt: now.Add(time.Duration(i)),
+			DueDate:   due,
+			Priority:  priority,
+			Tags:      normalizeTags(ca.all("tag")),
+			Repeat:    repeat,
+			StartDate: start,
+			ParentID:  parent,
+			Notes:     notes,
+			Order:     nextOrder(ts),
+			Estimate:  estimate,
+			URL:       link,
+		})
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	if fromStdin && len(titles) > 1 {
+		say("Added %d tasks (%d-%d)\n", len(titles), first, first+int64(len(titles))-1)
+		return nil
+	}
+	for i, title := range titles {
+		say("Added %d: %s\n", first+int64(i), title)
+	}
+	return nil
+}
+
+var dupFlags = []flagDef{valueFlag("due"), valueFlag("count", "n")}
+
+// cmdDup adds pending copies of a task, with its title, tags, priority,
+// notes, estimate and due date, or the due date given with --due.
+func cmdDup(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, dupFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) != 1 {
+		return usageError("dup")
+	}
+	id, err := parseID(ca.pos[0])
+	if err != nil {
+		return err
+	}
+	count := 1
+	if ca.has("count") {
+		if count, err = strconv.Atoi(ca.value("count")); err != nil || count < 1 || count > maxRangeIDs {
+			return usageErrorf("invalid --count %q: use a number from 1 to %d", ca.value("count"), maxRangeIDs)
+		}
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	src := ts[i]
+	due := src.DueDate
+	// --due none leaves the copies without one
+	if ca.has("due") {
+		due = nil
+		if ca.value("due") != "none" {
+			d, err := parseDate(ca.value("due"))
+			if err != nil {
+				return usageErrorf("invalid due date %q: %v", ca.value("due"), err)
+			}
+			due = &d
+		}
+	}
+	first := ts.NextID()
+	now := time.Now()
+	for k := range count {
+		ts = append(ts, Task{
+			ID:          first + int64(k),
+			Title:       src.Title,
+			CreatedAt:   now.Add(time.Duration(k)),
+			DueDate:     due,
+			Priority:    src.Priority,
+			Tags:        slices.Clone(src.Tags),
+			Notes:       src.Notes,
+			Order:       nextOrder(ts),
+			Estimate:    src.Estimate,
+			URL:         src.URL,
+			Attachments: slices.Clone(src.Attachments),
+		})
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	if count > 1 {
+		say("Added %d copies of %d (%d-%d)\n", count, id, first, first+int64(count)-1)
+		return nil
+	}
+	say("Added %d: %s\n", first, src.Title)
+	return nil
+}
+
+// batchTitles returns the titles of an add given several with --and or,
+// when split is set, separated by ";;", leaving out empty ones.
+func batchTitles(given []string, split bool) []string {
+	var titles []string
+	for _, g := range given {
+		parts := []string{g}
+		if split {
+			parts = strings.Split(g, ";;")
+		}
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				titles = append(titles, p)
+			}
+		}
+	}
+	return titles
+}
+
+// readTitles reads one task title per line for add -, skipping blank lines
+// and stripping "- " and "* " bullets.
+func readTitles(r io.Reader) ([]string, error) {
+	var titles []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if rest, ok := strings.CutPrefix(line, "- "); ok {
+			line = strings.TrimSpace(rest)
+		} else if rest, ok := strings.CutPrefix(line, "* "); ok {
+			line = strings.TrimSpace(rest)
+		}
+		if line != "" {
+			titles = append(titles, line)
+		}
+	}
+	return titles, sc.Err()
+}
+
+// taskLine is the one-line form of a task: ID, checkbox, a * when pinned,
+// priority, title, tags and ↗ for a link the title doesn't show. A task waiting on a dependency shows [~], one
+// being worked on [>] and one waiting on someone [@].
+func taskLine(t Task) string {
+	check := " "
+	if t.Done {
+		check = "x"
+	} else if t.Blocked {
+		check = "~"
+	} else if s := t.State(); s == todo.StatusDoing {
+		check = ">"
+	} else if s == todo.StatusWaiting {
+		check = "@"
+	}
+	title := priorityMarker(t.Priority) + t.Title
+	if t.Pinned {
+		title = "* " + title
+	}
+	for _, tag := range t.Tags {
+		title += " #" + tag
+	}
+	if t.URL != "" && !strings.Contains(t.Title, t.URL) {
+		title += " ↗"
+	}
+	return fmt.Sprintf("%*d) [%s] %s", idWidth, t.ID, check, title)
+}
+
+func repeatSuffix(t Task) string {
+	switch t.Repeat {
+	case "":
+		return ""
+	case "daily", "weekly", "monthly", "yearly":
+		return " (repeats " + t.Repeat + ")"
+	}
+	return " (repeats every " + t.Repeat + ")"
+}
+
+// printTask writes a task in the list format used by every listing command.
+func printTask(t Task) {
+	printTaskIndent(t, "")
+}
+
+// printTaskIndent is printTask with every line prefixed by indent, for
+// subtasks shown beneath their parent.
+func printTaskIndent(t Task, indent string) {
+	var style []string
+	if t.Done {
+		style = append(style, ansiDim)
+	} else if t.IsOverdue(time.Now()) {
+		style = append(style, ansiRed)
+	}
+	if t.Priority == priorityHigh {
+		style = append(style, ansiBold)
+	}
+	fmt.Println(indent + paint(taskLine(t), style...))
+	if t.DueDate != nil {
+		fmt.Printf(indent+"    due: %s%s\n", listDate(*t.DueDate, formatDate), repeatSuffix(t))
+	}
+	if t.IsDeferred(time.Now()) {
+		fmt.Printf(indent+"    starts: %s\n", formatDate(*t.StartDate))
+	}
+	if t.Waiting != "" && !t.Done {
+		fmt.Printf(indent+"    waiting for: %s (%s)\n", t.Waiting, waitedFor(t, time.Now()))
+	}
+	if t.Running() {
+		fmt.Printf(indent+"    running: %s\n", formatTracked(time.Since(t.Sessions[len(t.Sessions)-1].Start)))
+	}
+	if t.CompletedAt != nil {
+		fmt.Printf(indent+"    completed: %s\n", listDate(*t.CompletedAt, formatTime))
+	}
+	if t.DeletedAt != nil {
+		fmt.Printf(indent+"    deleted: %s\n", formatTime(*t.DeletedAt))
+	}
+	if verbose {
+		fmt.Printf(indent+"    created: %s\n", listDate(t.CreatedAt, formatTime))
+	}
+	if verbose && t.Estimate > 0 {
+		fmt.Printf(indent+"    estimate: %s\n", formatEstimate(t.Estimate))
+	}
+	if verbose && t.URL != "" {
+		fmt.Printf(indent+"    url: %s\n", t.URL)
+	}
+	if verbose && t.Notes != "" {
+		for _, line := range strings.Split(t.Notes, "\n") {
+			fmt.Println(indent + "    " + line)
+		}
+	}
+}
+
+var listFlags = []flagDef{
+	valueFlag("tag", "t"), boolFlag("all", "a"), boolFlag("done"), boolFlag("pending"), boolFlag("archived"),
+	boolFlag("deferred"), boolFlag("pinned"), valueFlag("sort"), valueFlag("group-by"), boolFlag("reverse", "r"), boolFlag("absolute"),
+	valueFlag("format"), boolFlag("porcelain"), boolFlag("z"), valueFlag("changed-since"), boolFlag("watch", "w"),
+	jsonFlag, jsonlFlag, colorFlag,
+}
+
+func cmdList(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, listFlags...)
+	if err != nil {
+		return err
+	}
+	if ca.has("z") && !ca.has("porcelain") {
+		return usageErrorf("-z needs --porcelain")
+	}
+	relativeTimes = !ca.has("absolute")
+	if format := config.ListFormat; ca.has("format") || format != "" {
+		if ca.has("format") {
+			format = ca.value("format")
+		}
+		if listTemplate, err = parseListFormat(format); err != nil {
+			return err
+		}
+	}
+	if ca.has("sort") {
+		if err := checkSortKey(ca.value("sort")); err != nil {
+			return err
+		}
+	} else if ca.has("reverse") {
+		return usageErrorf("--reverse needs --sort")
+	}
+	if ca.has("group-by") {
+		if !slices.Contains(groupKinds, ca.value("group-by")) {
+			return usageErrorf("unknown --group-by %q (use %s)", ca.value("group-by"), strings.Join(groupKinds, ", "))
+		}
+		if wantsJSON(ca) || ca.has("porcelain") {
+			return usageErrorf("--group-by doesn't go with --json, --jsonl or --porcelain")
+		}
+	}
+	var since time.Time
+	if ca.has("changed-since") {
+		if since, err = parseDate(ca.value("changed-since")); err != nil {
+			return usageErrorf("invalid --changed-since date %q: %v", ca.value("changed-since"), err)
+		}
+	}
+	if len(ca.pos) > 0 || (ca.has("all") && ca.has("done")) || (ca.has("deferred") && ca.has("done")) {
+		return usageError("list")
+	}
+	if ca.has("watch") {
+		return watchList(func() error { return showList(ca, since) })
+	}
+	return showList(ca, since)
+}
+
+// showList prints the tasks list selects once its flags have been checked.
+func showList(ca cmdArgs, since time.Time) error {
+	var err error
+	var ts Tasks
+	showAll := ca.has("all") || (config.ShowCompleted && !ca.has("pending"))
+	if ca.has("archived") {
+		path, err := companionPath("archive")
+		if err != nil {
+			return err
+		}
+		if ts, err = readTasksFile(path); err != nil {
+			return err
+		}
+		// everything in the archive is completed
+		showAll = true
+	} else if ts, err = loadTasks(); err != nil {
+		return err
+	}
+	if ca.has("tag") {
+		tag := normalizeTag(ca.value("tag"))
+		ts = ts.Filter(func(t Task) bool { return t.HasTag(tag) })
+	}
+	if ca.has("changed-since") {
+		ts = ts.Filter(func(t Task) bool { return !t.UpdatedAt.Before(since) })
+	}
+	if ca.has("pinned") {
+		ts = ts.Filter(func(t Task) bool { return t.Pinned })
+	}
+	now := time.Now()
+	empty := "No tasks."
+	switch {
+	case ca.has("done"):
+		// completed tasks show here even if they were deferred
+		ts = ts.Filter(func(t Task) bool { return t.Done })
+	case ca.has("deferred"):
+		ts = ts.Filter(func(t Task) bool { return !t.Done && t.IsDeferred(now) })
+		empty = "No deferred tasks."
+	case !showAll:
+		completed := len(ts.Filter(func(t Task) bool { return t.Done }))
+		deferred := len(ts.Filter(func(t Task) bool { return !t.Done && t.IsDeferred(now) }))
+		// waiting tasks are with someone else; todo waiting lists them
+		waiting := len(ts.Filter(func(t Task) bool { return !t.Done && !t.IsDeferred(now) && t.Waiting != "" }))
+		ts = ts.Filter(func(t Task) bool { return !t.Done && !t.IsDeferred(now) && t.Waiting == "" })
+		if len(ts) == 0 && completed+deferred+waiting > 0 {
+			// say why the list looks empty so it isn't mistaken for data loss
+			why := []string{fmt.Sprintf("%d completed", completed)}
+			if deferred > 0 {
+				why = append(why, fmt.Sprintf("%d deferred", deferred))
+			}
+			if waiting > 0 {
+				why = append(why, fmt.Sprintf("%d waiting", waiting))
+			}
+			empty = fmt.Sprintf("No pending tasks (%s, use --all).", strings.Join(why, ", "))
+		}
+	}
+	return printTasks(ca, ts, empty)
+}
+
+// sortForDisplay orders tasks the way list shows them: by priority, then
+// the order set with move, then ID.
+func sortForDisplay(ts Tasks) {
+	sort.SliceStable(ts, func(i, j int) bool {
+		ri, rj := priorityRank(ts[i].Priority), priorityRank(ts[j].Priority)
+		if ri != rj {
+			return ri < rj
+		}
+		if oi, oj := orderRank(ts[i]), orderRank(ts[j]); oi != oj {
+			return oi < oj
+		}
+		return ts[i].ID < ts[j].ID
+	})
+}
+
+// checkSortKey accepts the orders list --sort knows.
+func checkSortKey(name string) error {
+	if !slices.Contains(todo.SortKeys(), name) {
+		return usageErrorf("unknown sort key %q (valid keys: %s)", name, strings.Join(todo.SortKeys(), ", "))
+	}
+	return nil
+}
+
+var searchFlags = []flagDef{boolFlag("done"), boolFlag("pending"), jsonFlag, jsonlFlag, colorFlag}
+
+func cmdSearch(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, searchFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) == 0 || (ca.has("done") && ca.has("pending")) {
+		return usageError("search")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	var matches Tasks
+	for _, t := range ts {
+		if (ca.has("done") && !t.Done) || (ca.has("pending") && t.Done) {
+			continue
+		}
+		if matchesAll(t.Title, ca.pos) {
+			matches = append(matches, t)
+		}
+	}
+	return printTasks(ca, matches, "No matching tasks.")
+}
+
+// matchesAll reports whether s contains every word, ignoring case.
+func matchesAll(s string, words []string) bool {
+	s = strings.ToLower(s)
+	for _, w := range words {
+		if !strings.Contains(s, strings.ToLower(w)) {
+			return false
+		}
+	}
+	return true
+}
+
+var overdueFlags = []flagDef{jsonFlag, jsonlFlag, colorFlag}
+
+func cmdOverdue(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, overdueFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("overdue")
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var overdue Tasks
+	for _, t := range ts {
+		if t.IsOverdue(now) {
+			overdue = append(overdue, t)
+		}
+	}
+	sort.SliceStable(overdue, func(i, j int) bool {
+		return overdue[i].DueDate.Before(*overdue[j].DueDate)
+	})
+	if wantsJSON(ca) {
+		if err := printJSON(ca, overdue); err != nil {
+			return err
+		}
+		if len(overdue) == 0 {
+			return nil
+		}
+		return exitStatus(1)
+	}
+	if len(overdue) == 0 {
+		fmt.Println("No overdue tasks.")
+		return nil
+	}
+	for _, t := range overdue {
+		late := "1 day overdue"
+		if days := -calendarDays(now, *t.DueDate); days > 1 {
+			late = fmt.Sprintf("%d days overdue", days)
+		}
+		fmt.Println(paint(fmt.Sprintf("%d) [ ] %s%s (%s)", t.ID, priorityMarker(t.Priority), t.Title, late), ansiRed))
+	}
+	return exitStatus(1)
+}
+
+// maxRangeIDs bounds how many IDs a single range like 4-9 may expand to.
+const maxRangeIDs = 10000
+
+// idList is the parsed form of the task ID arguments of batch commands.
+type idList struct {
+	ids    []int64
+	ranged map[int64]bool // IDs that came from a range rather than being named
+}
+
+// parseIDs parses task ID arguments of the form 3 or 4-9, or the start of
+// a task's UID, dropping repeats.
+func parseIDs(args []string) (idList, error) {
+	l := idList{ranged: map[int64]bool{}}
+	seen := map[int64]bool{}
+	add := func(id int64, ranged bool) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		l.ids = append(l.ids, id)
+		if ranged {
+			l.ranged[id] = true
+		}
+	}
+	for _, a := range args {
+		if uidLike(a) || isUID(a) {
+			id, err := parseID(a)
+			if err != nil {
+				return l, err
+			}
+			add(id, false)
+			continue
+		}
+		if lo, hi, ok := strings.Cut(a, "-"); ok && lo != "" {
+			start, err1 := strconv.ParseInt(lo, 10, 64)
+			end, err2 := strconv.ParseInt(hi, 10, 64)
+			if err1 != nil || err2 != nil {
+				return l, usageErrorf("invalid task id range %q", a)
+			}
+			if start > end {
+				return l, usageErrorf("invalid task id range %q: start is greater than end", a)
+			}
+			if end-start >= maxRangeIDs {
+				return l, usageErrorf("task id range %q is too large", a)
+			}
+			for id := start; id <= end; id++ {
+				add(id, true)
+			}
+			continue
+		}
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return l, usageErrorf("invalid task id %q", a)
+		}
+		add(id, false)
+	}
+	return l, nil
+}
+
+// notFound reports the IDs a batch command could not find. IDs that only
+// came from a range are noted and skipped; named IDs make it an error.
+func (l idList) notFound(missing []int64) error {
+	var skipped, named []string
+	for _, id := range missing {
+		if l.ranged[id] {
+			skipped = append(skipped, strconv.FormatInt(id, 10))
+		} else {
+			named = append(named, strconv.FormatInt(id, 10))
+		}
+	}
+	if len(skipped) > 0 {
+		say("Skipped %s (not found)\n", strings.Join(skipped, ", "))
+	}
+	switch len(named) {
+	case 0:
+		return nil
+	case 1:
+		return notFoundErrorf("task %s not found", named[0])
+	}
+	return notFoundErrorf("tasks %s not found", strings.Join(named, ", "))
+}
+
+var doFlags = []flagDef{boolFlag("force", "f"), valueFlag("title"), boolFlag("pick")}
+
+func cmdDo(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, doFlags...)
+	if err != nil {
+		return err
+	}
+	if !namedOnce(ca) {
+		return usageError("do")
+	}
+	var ids idList
+	if ca.has("pick") {
+		if ids, err = pickIDs("complete"); err != nil || len(ids.ids) == 0 {
+			return err
+		}
+		unlock, err := lockTasks()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	} else if ids, err = targetIDs(ca); err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	if !ca.has("force") {
+		// subtasks completed in the same call don't count as open
+		closing := map[int64]bool{}
+		for _, id := range ids.ids {
+			closing[id] = true
+		}
+		for _, id := range ids.ids {
+			if err := checkCompletable(ts, id, closing); err != nil {
+				return fmt.Errorf("%v or use --force", err)
+			}
+		}
+	} else {
+		ids.ids = withOpenSubtasks(ts, ids.ids)
+	}
+	now := time.Now()
+	var done, missing []int64
+	var next []string
+	for _, id := range ids.ids {
+		i := ts.Index(id)
+		if i == -1 {
+			missing = append(missing, id)
+			continue
+		}
+		if ts[i].Done {
+			say("Task %d is already completed.\n", id)
+			continue
+		}
+		ts[i].Done = true
+		ts[i].CompletedAt = &now
+		done = append(done, id)
+		if ts[i].Repeat != "" {
+			n, err := nextOccurrence(ts[i], ts.NextID(), now)
+			if err != nil {
+				return fmt.Errorf("task %d: %v", id, err)
+			}
+			ts = append(ts, n)
+			next = append(next, fmt.Sprintf("Next occurrence: %d due %s", n.ID, formatDate(*n.DueDate)))
+		}
+	}
+	if len(done) > 0 {
+		if err := saveTasks(ts); err != nil {
+			return err
+		}
+	}
+	for _, id := range done {
+		say("Marked %d done\n", id)
+	}
+	for _, line := range next {
+		say("%s\n", line)
+	}
+	return ids.notFound(missing)
+}
+
+// checkCompletable refuses to complete a task with open subtasks or pending
+// dependencies, other than the tasks in closing.
+func checkCompletable(ts Tasks, id int64, closing map[int64]bool) error {
+	open := ts.OpenChildren(id).Filter(func(t Task) bool { return !closing[t.ID] })
+	if len(open) > 0 {
+		return fmt.Errorf("task %d has %d open subtasks; complete them first", id, len(open))
+	}
+	if i := ts.Index(id); i != -1 {
+		waiting := ts.WaitingOn(ts[i]).Filter(func(t Task) bool { return !closing[t.ID] })
+		if len(waiting) > 0 {
+			return fmt.Errorf("task %d is blocked by %d pending tasks; see todo blocked", id, len(waiting))
+		}
+	}
+	return nil
+}
+
+// nextOccurrence creates the follow-up of a completed recurring task. Its
+// due date is the first repetition after now counted from the old due date
+// (or from today without one), so a long-overdue chore isn't born overdue
+// and monthly tasks don't drift after a short month.
+func nextOccurrence(t Task, id int64, now time.Time) (Task, error) {
+	advance, err := parseRepeat(t.Repeat)
+	if err != nil {
+		return Task{}, err
+	}
+	base := startOfDay(now)
+	if t.DueDate != nil {
+		base = *t.DueDate
+	}
+	due := advance(base, 1)
+	for n := 2; !due.After(now); n++ {
+		due = advance(base, n)
+	}
+	return Task{
+		ID:        id,
+		Title:     t.Title,
+		CreatedAt: now,
+		DueDate:   &due,
+		Priority:  t.Priority,
+		Tags:      t.Tags,
+		Notes:     t.Notes,
+		Repeat:    t.Repeat,
+		Order:     t.Order,
+		Pinned:    t.Pinned,
+		Estimate:  t.Estimate,
+		URL:       t.URL,
+	}, nil
+}
+
+func cmdPostpone(args []string) error {
+	_ = args
+	if len(args) < 2 {
+		return usageError("postpone")
+	}
+	ids, err := parseIDs(args[:len(args)-1])
+	if err != nil {
+		return err
+	}
+	when := args[len(args)-1]
+	shift, relative := parseShift(strings.ToLower(when))
+	var target time.Time
+	if !relative {
+		if target, err = parseDate(when); err != nil {
+			return fmt.Errorf("invalid postpone amount %q: use 3h, 1d, 2w or a date (%v)", when, err)
+		}
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var changed []string
+	var missing, closed []int64
+	for _, id := range ids.ids {
+		i := ts.Index(id)
+		if i == -1 {
+			missing = append(missing, id)
+			continue
+		}
+		if ts[i].Done {
+			closed = append(closed, id)
+			continue
+		}
+		old := "no due date"
+		base := now
+		if ts[i].DueDate != nil {
+			old = formatDate(*ts[i].DueDate)
+			base = *ts[i].DueDate
+		}
+		due := target
+		if relative {
+			due = shift(base)
+		}
+		ts[i].DueDate = &due
+		changed = append(changed, fmt.Sprintf("Postponed %d: %s -> %s", id, old, formatDate(due)))
+	}
+	if len(changed) > 0 {
+		if err := saveTasks(ts); err != nil {
+			return err
+		}
+	}
+	for _, line := range changed {
+		say("%s\n", line)
+	}
+	if len(closed) > 0 {
+		for _, id := range closed {
+			fmt.Fprintf(os.Stderr, "Task %d is completed and can't be postponed.\n", id)
+		}
+		if err := ids.notFound(missing); err != nil {
+			return err
+		}
+		return exitStatus(1)
+	}
+	return ids.notFound(missing)
+}
+
+func cmdDefer(args []string) error {
+	_ = args
+	if len(args) < 2 {
+		return usageError("defer")
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	start, err := parseDate(strings.Join(args[1:], " "))
+	if err != nil {
+		return usageErrorf("invalid start date %q: %v", strings.Join(args[1:], " "), err)
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	ts[i].StartDate = &start
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	say("Deferred %d until %s\n", id, formatDate(start))
+	return nil
+}
+
+func cmdUndone(args []string) error {
+	_ = args
+	if len(args) == 0 {
+		return usageError("undone")
+	}
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	var reopened, missing []int64
+	for _, id := range ids.ids {
+		i := ts.Index(id)
+		if i == -1 {
+			missing = append(missing, id)
+			continue
+		}
+		if !ts[i].Done {
+			say("Task %d is not completed.\n", id)
+			continue
+		}
+		ts[i].Done = false
+		ts[i].CompletedAt = nil
+		reopened = append(reopened, id)
+	}
+	if len(reopened) > 0 {
+		if err := saveTasks(ts); err != nil {
+			return err
+		}
+	}
+	for _, id := range reopened {
+		say("Reopened %d\n", id)
+	}
+	return ids.notFound(missing)
+}
+
+func cmdPin(args []string) error {
+	_ = args
+	if len(args) == 0 {
+		return usageError("pin")
+	}
+	return setPinned(args, true)
 }
 
-type Tasks []Task
+func cmdUnpin(args []string) error {
+	_ = args
+	if len(args) == 0 {
+		return usageError("unpin")
+	}
+	return setPinned(args, false)
+}
 
-func tasksFilePath() (string, error) {
-	if p := os.Getenv("TODO_FILE"); p != "" {
-		return p, nil
+// setPinned pins or unpins tasks. A completed task can be pinned, but list
+// hides it like any other, so that gets a warning.
+func setPinned(args []string, pinned bool) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
 	}
-	home, err := os.UserHomeDir()
+	ts, err := loadTasks()
 	if err != nil {
-		return "", err
+		return err
 	}
-	dir := filepath.Join(home, ".todo")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return "", err
+	var changed, missing []int64
+	for _, id := range ids.ids {
+		i := ts.Index(id)
+		if i == -1 {
+			missing = append(missing, id)
+			continue
+		}
+		if ts[i].Pinned == pinned {
+			if pinned {
+				say("Task %d is already pinned.\n", id)
+			} else {
+				say("Task %d is not pinned.\n", id)
+			}
+			continue
+		}
+		if pinned && ts[i].Done {
+			fmt.Fprintf(os.Stderr, "Warning: task %d is completed; list shows it only with --all or --done\n", id)
+		}
+		ts[i].Pinned = pinned
+		changed = append(changed, id)
+	}
+	if len(changed) > 0 {
+		if err := saveTasks(ts); err != nil {
+			return err
+		}
+	}
+	for _, id := range changed {
+		if pinned {
+			say("Pinned %d\n", id)
+		} else {
+			say("Unpinned %d\n", id)
+		}
 	}
-	return filepath.Join(dir, "tasks.json"), nil
+	return ids.notFound(missing)
 }
 
-func loadTasks() (Tasks, error) {
-	path, err := tasksFilePath()
+var removeFlags = []flagDef{boolFlag("force", "f"), valueFlag("title"), boolFlag("pick")}
+
+func cmdRemove(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, removeFlags...)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// If file doesn't exist, return empty list
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return Tasks{}, nil
+	if !namedOnce(ca) {
+		return usageError("rm")
 	}
-
-	b, err := os.ReadFile(path)
+	var ids idList
+	if ca.has("pick") {
+		if ids, err = pickIDs("remove"); err != nil || len(ids.ids) == 0 {
+			return err
+		}
+		unlock, err := lockTasks()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	} else if ids, err = targetIDs(ca); err != nil {
+		return err
+	}
+	ts, err := loadTasks()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	var removed Tasks
+	var missing []int64
+	for _, id := range ids.ids {
+		i := ts.Index(id)
+		if i == -1 {
+			missing = append(missing, id)
+			continue
+		}
+		removed = append(removed, ts[i])
+		ts = append(ts[:i], ts[i+1:]...)
+	}
+	reparented := detachRemoved(ts, removed)
+	if len(removed) > 0 {
+		if !ca.has("force") {
+			if err := moveToTrash(removed); err != nil {
+				return err
+			}
+		}
+		if err := saveTasks(ts); err != nil {
+			return err
+		}
+	}
+	for _, t := range removed {
+		say("Removed %d\n", t.ID)
+	}
+	for _, line := range reparented {
+		say("%s\n", line)
 	}
+	return ids.notFound(missing)
+}
 
-	var ts Tasks
-	if err := json.Unmarshal(b, &ts); err != nil {
-		// backup the corrupted file so user can inspect
-		backup := fmt.Sprintf("%s.broken.%d", path, time.Now().Unix())
-		_ = os.WriteFile(backup, b, 0o644) // best-effort
-		// Inform user and start fresh
-		fmt.Fprintf(os.Stderr, "Warning: tasks file corrupted. Backed up to %s and starting with empty list.\n", backup)
-		return Tasks{}, nil
+// detachRemoved updates the tasks left in ts after removed were taken out.
+// Subtasks of a removed task move up to its parent rather than being removed
+// along with it, and dependencies on it are dropped. It returns a line
+// describing each change.
+func detachRemoved(ts, removed Tasks) []string {
+	var notes []string
+	for _, r := range removed {
+		for k := range ts {
+			if p := ts[k].ParentID; p != nil && *p == r.ID {
+				ts[k].ParentID = r.ParentID
+				to := "top level"
+				if r.ParentID != nil {
+					to = fmt.Sprintf("task %d", *r.ParentID)
+				}
+				notes = append(notes, fmt.Sprintf("Moved subtask %d to %s", ts[k].ID, to))
+			}
+			if slices.Contains(ts[k].DependsOn, r.ID) {
+				ts[k].DependsOn = slices.DeleteFunc(ts[k].DependsOn, func(d int64) bool { return d == r.ID })
+				notes = append(notes, fmt.Sprintf("Task %d no longer depends on %d", ts[k].ID, r.ID))
+			}
+		}
 	}
-	return ts, nil
+	return notes
 }
 
-func saveTasks(ts Tasks) error {
-	path, err := tasksFilePath()
+// moveToTrash appends removed tasks to the trash file. It runs before the
+// main file is saved so a failure in between can't lose a task.
+func moveToTrash(removed Tasks) error {
+	path, err := companionPath("trash")
 	if err != nil {
 		return err
 	}
-	b, err := json.MarshalIndent(ts, "", "  ")
+	trash, err := readTasksFile(path)
 	if err != nil {
 		return err
 	}
-	tmp := path + ".tmp"
-	// write temp file
-	if err := os.WriteFile(tmp, b, 0o644); err != nil {
-		return err
+	now := time.Now()
+	for _, t := range removed {
+		t.DeletedAt = &now
+		trash = append(trash, t)
 	}
-	// atomic move
-	return os.Rename(tmp, path)
+	return writeTasksFile(path, trash)
 }
 
-func nextID(ts Tasks) int64 {
-	var max int64
-	for _, t := range ts {
-		if t.ID > max {
-			max = t.ID
+var trashFlags = []flagDef{boolFlag("empty"), jsonFlag, jsonlFlag, colorFlag}
+
+func cmdTrash(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, trashFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("trash")
+	}
+	path, err := companionPath("trash")
+	if err != nil {
+		return err
+	}
+	trash, err := readTasksFile(path)
+	if err != nil {
+		return err
+	}
+	if ca.has("empty") {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
 		}
+		say("Purged %d tasks from the trash.\n", len(trash))
+		return nil
 	}
-	return max + 1
+	return printTasks(ca, trash, "Trash is empty.")
 }
 
-func findIndexByID(ts Tasks, id int64) int {
-	for i, t := range ts {
+func cmdRestore(args []string) error {
+	_ = args
+	if len(args) != 1 {
+		return usageError("restore")
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	path, err := companionPath("trash")
+	if err != nil {
+		return err
+	}
+	trash, err := readTasksFile(path)
+	if err != nil {
+		return err
+	}
+	// the same ID may have been trashed more than once; take the latest
+	j := -1
+	for k, t := range trash {
 		if t.ID == id {
-			return i
+			j = k
 		}
 	}
-	return -1
-}
-
-func cmdAdd(args []string) error {
-	_ = args // silence linter if you don't use args directly here
-	if len(args) == 0 {
-		return errors.New("usage: todo add <task title>")
+	if j == -1 {
+		return notFoundErrorf("task %d not found in trash", id)
 	}
-	title := strings.Join(args, " ")
 	ts, err := loadTasks()
 	if err != nil {
 		return err
 	}
-	id := nextID(ts)
-	t := Task{ID: id, Title: title, Done: false, CreatedAt: time.Now()}
-	ts = append(ts, t)
-	if err := saveTasks(ts); err != nil {
+	t := trash[j]
+	t.DeletedAt = nil
+	if ts.Index(t.ID) != -1 {
+		t.ID = ts.NextID()
+	}
+	// save the live list first: a crash before the trash is rewritten
+	// leaves a copy in both places rather than in neither
+	if err := saveTasks(append(ts, t)); err != nil {
+		return err
+	}
+	if err := writeTasksFile(path, append(trash[:j], trash[j+1:]...)); err != nil {
 		return err
 	}
-	fmt.Printf("Added %d: %s\n", id, title)
+	if t.ID != id {
+		say("Restored %d as %d\n", id, t.ID)
+	} else {
+		say("Restored %d\n", id)
+	}
 	return nil
 }
 
-func cmdList(args []string) error {
+var editFlags = []flagDef{
+	valueFlag("priority", "p"), valueFlag("due"), valueFlag("tag", "t"), boolFlag("editor"),
+	boolFlag("all", "a"), valueFlag("title"), valueFlag("estimate", "e"), valueFlag("url"),
+}
+
+func cmdEdit(args []string) error {
 	_ = args
+	ca, err := parseArgs(args, editFlags...)
+	if err != nil {
+		return err
+	}
+	if ca.has("all") {
+		if len(ca.pos) > 0 || len(ca.values) > 1 {
+			return usageError("edit")
+		}
+		return editAll()
+	}
+	// the task is the first argument, or given by --title
+	target, rest := "", ca.pos
+	if !ca.has("title") && len(rest) > 0 {
+		target, rest = rest[0], rest[1:]
+	}
+	if (target == "" && !ca.has("title")) || (len(rest) == 0 && !ca.has("priority") && !ca.has("due") && !ca.has("tag") && !ca.has("editor") && !ca.has("estimate") && !ca.has("url")) ||
+		(ca.has("editor") && len(rest) > 0) {
+		return usageError("edit")
+	}
+	id, err := targetID(ca, target)
+	if err != nil {
+		return err
+	}
+	newTitle := strings.Join(rest, " ")
+	priority := priorityNone
+	if ca.has("priority") {
+		if priority, err = parsePriority(ca.value("priority")); err != nil {
+			return err
+		}
+	}
+	// --due none clears the due date
+	var due *time.Time
+	if ca.has("due") && ca.value("due") != "none" {
+		d, err := parseDate(ca.value("due"))
+		if err != nil {
+			return usageErrorf("invalid due date %q: %v", ca.value("due"), err)
+		}
+		due = &d
+	}
+	// --estimate none clears the estimate
+	var estimate time.Duration
+	if ca.has("estimate") && ca.value("estimate") != "none" {
+		if estimate, err = parseEstimate(ca.value("estimate")); err != nil {
+			return usageErrorf("invalid estimate %q: %v", ca.value("estimate"), err)
+		}
+	}
+	// --url none clears the link
+	link := ca.value("url")
+	if link == "none" {
+		link = ""
+	} else if ca.has("url") {
+		if err := checkURL(link); err != nil {
+			return err
+		}
+	}
+	var newNotes string
+	if ca.has("editor") {
+		ts, err := loadTasks()
+		if err != nil {
+			return err
+		}
+		i := ts.Index(id)
+		if i == -1 {
+			return notFoundErrorf("task %d not found", id)
+		}
+		old := ts[i]
+		title, notes, ok, err := editTitleNotes(old.Title, old.Notes)
+		if err != nil || !ok {
+			return err
+		}
+		unlock, err := lockTasks()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		// the task was read without the lock; refuse if it changed meanwhile
+		if ts, err = loadTasks(); err != nil {
+			return err
+		}
+		if j := ts.Index(id); j == -1 || ts[j].Title != old.Title || ts[j].Notes != old.Notes {
+			return fmt.Errorf("task %d changed while it was being edited; nothing changed", id)
+		}
+		newTitle, newNotes = title, notes
+	}
 	ts, err := loadTasks()
 	if err != nil {
 		return err
 	}
-	if len(ts) == 0 {
-		fmt.Println("No tasks.")
-		return nil
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
 	}
-	for _, t := range ts {
-		check := " "
-		if t.Done {
-			check = "x"
+	t := &ts[i]
+	var changes []string
+	if newTitle != "" {
+		if newTitle, err = normalizeTitle(newTitle); err != nil {
+			return err
+		}
+	}
+	if newTitle != "" && newTitle != t.Title {
+		changes = append(changes, fmt.Sprintf("title: %q -> %q", t.Title, newTitle))
+		t.Title = newTitle
+	}
+	// without --url, a link in a new title is picked up as by add
+	if !ca.has("url") {
+		link = t.URL
+		if link == "" && newTitle != "" {
+			link = titleURL(newTitle)
+		}
+	}
+	if link != t.URL {
+		changes = append(changes, fmt.Sprintf("url: %s -> %s", cmp.Or(t.URL, "none"), cmp.Or(link, "none")))
+		t.URL = link
+	}
+	if ca.has("editor") && newNotes != t.Notes {
+		changes = append(changes, "notes updated")
+		t.Notes = newNotes
+	}
+	if ca.has("priority") && priority != t.Priority {
+		changes = append(changes, fmt.Sprintf("priority: %s -> %s", priorityNames[t.Priority], priorityNames[priority]))
+		t.Priority = priority
+	}
+	if ca.has("due") {
+		old, now := "none", "none"
+		if t.DueDate != nil {
+			old = formatDate(*t.DueDate)
+		}
+		if due != nil {
+			now = formatDate(*due)
+		}
+		if old != now {
+			changes = append(changes, fmt.Sprintf("due: %s -> %s", old, now))
 		}
-		fmt.Printf("%d) [%s] %s\n", t.ID, check, t.Title)
-		if t.CompletedAt != nil {
-			fmt.Printf("    completed: %s\n", t.CompletedAt.Format("2006-01-02 15:04"))
+		t.DueDate = due
+	}
+	if ca.has("estimate") && estimate != t.Estimate {
+		changes = append(changes, fmt.Sprintf("estimate: %s -> %s", formatEstimate(t.Estimate), formatEstimate(estimate)))
+		t.Estimate = estimate
+	}
+	// +tag adds, -tag removes; a bare tag adds
+	for _, v := range ca.all("tag") {
+		remove := strings.HasPrefix(v, "-")
+		tag := normalizeTag(strings.TrimLeft(v, "+-"))
+		if tag == "" {
+			return fmt.Errorf("invalid tag %q", v)
 		}
+		switch {
+		case remove && t.HasTag(tag):
+			t.Tags = slices.DeleteFunc(t.Tags, func(s string) bool { return s == tag })
+			changes = append(changes, "tag removed: #"+tag)
+		case !remove && !t.HasTag(tag):
+			t.Tags = normalizeTags(append(t.Tags, tag))
+			changes = append(changes, "tag added: #"+tag)
+		}
+	}
+	if len(changes) == 0 {
+		say("Task %d unchanged\n", id)
+		return nil
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	say("Updated %d\n", id)
+	for _, c := range changes {
+		say("    %s\n", c)
 	}
 	return nil
 }
 
-func cmdDo(args []string) error {
+// priorityNames are the words show uses for each priority level.
+var priorityNames = []string{"none", "high", "medium", "low"}
+
+var showFlags = []flagDef{jsonFlag, valueFlag("title")}
+
+func cmdShow(args []string) error {
 	_ = args
-	if len(args) == 0 {
-		return errors.New("usage: todo do <id>")
+	ca, err := parseArgs(args, showFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) != 1 && !(len(ca.pos) == 0 && ca.has("title")) {
+		return usageError("show")
 	}
-	id, err := strconv.ParseInt(args[0], 10, 64)
+	id, err := targetID(ca, strings.Join(ca.pos, ""))
 	if err != nil {
 		return err
 	}
@@ -158,131 +1944,389 @@ func cmdDo(args []string) error {
 	if err != nil {
 		return err
 	}
-	i := findIndexByID(ts, id)
+	i := ts.Index(id)
 	if i == -1 {
-		return fmt.Errorf("task %d not found", id)
+		return notFoundErrorf("task %d not found", id)
 	}
-	if ts[i].Done {
-		fmt.Println("Already completed.")
+	t := ts[i]
+	if ca.has("json") {
+		b, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
 		return nil
 	}
-	now := time.Now()
-	ts[i].Done = true
-	ts[i].CompletedAt = &now
-	if err := saveTasks(ts); err != nil {
-		return err
+	status := "pending"
+	if s := t.State(); s != todo.StatusTodo {
+		status = s
+	}
+	fmt.Printf("ID:        %d\n", t.ID)
+	fmt.Printf("UID:       %s\n", t.UID)
+	fmt.Printf("Title:     %s\n", t.Title)
+	fmt.Printf("Status:    %s\n", status)
+	if t.Waiting != "" {
+		fmt.Printf("Waiting:   %s (%s)\n", t.Waiting, waitedFor(t, time.Now()))
+	}
+	if t.Pinned {
+		fmt.Println("Pinned:    yes")
+	}
+	fmt.Printf("Created:   %s\n", formatTime(t.CreatedAt))
+	fmt.Printf("Updated:   %s\n", formatTime(t.UpdatedAt))
+	if t.ParentID != nil {
+		if k := ts.Index(*t.ParentID); k != -1 {
+			fmt.Printf("Parent:    %s\n", taskLine(ts[k]))
+		}
+	}
+	if t.CompletedAt != nil {
+		fmt.Printf("Completed: %s\n", formatTime(*t.CompletedAt))
+	}
+	if t.StartDate != nil {
+		fmt.Printf("Starts:    %s\n", formatDate(*t.StartDate))
+	}
+	if t.DueDate != nil {
+		fmt.Printf("Due:       %s\n", formatDate(*t.DueDate))
+	}
+	if t.Repeat != "" {
+		fmt.Printf("Repeats:   %s\n", t.Repeat)
+	}
+	if t.Priority != priorityNone {
+		fmt.Printf("Priority:  %s%s\n", priorityMarker(t.Priority), priorityNames[t.Priority])
+	}
+	if t.Estimate > 0 {
+		fmt.Printf("Estimate:  %s\n", formatEstimate(t.Estimate))
+	}
+	if t.URL != "" {
+		fmt.Printf("URL:       %s\n", t.URL)
+	}
+	for n, p := range t.Attachments {
+		label := ""
+		if n == 0 {
+			label = "Files:"
+		}
+		fmt.Printf("%-10s %d. %s\n", label, n+1, p)
+	}
+	if len(t.Sessions) > 0 {
+		running := ""
+		if t.Running() {
+			running = fmt.Sprintf(" (running since %s)", formatTime(t.Sessions[len(t.Sessions)-1].Start))
+		}
+		fmt.Printf("Tracked:   %s in %d sessions%s\n", formatTracked(t.Tracked(time.Now())), len(t.Sessions), running)
+	}
+	if len(t.Tags) > 0 {
+		fmt.Printf("Tags:      #%s\n", strings.Join(t.Tags, " #"))
+	}
+	if t.Notes != "" {
+		fmt.Println("Notes:")
+		for _, line := range strings.Split(t.Notes, "\n") {
+			fmt.Println("    " + line)
+		}
+	}
+	if len(t.DependsOn) > 0 {
+		fmt.Println("Depends on:")
+		for _, id := range t.DependsOn {
+			if k := ts.Index(id); k != -1 {
+				fmt.Println("    " + taskLine(ts[k]))
+			}
+		}
+	}
+	if children := ts.Children(t.ID); len(children) > 0 {
+		fmt.Println("Subtasks:")
+		for _, c := range children {
+			fmt.Println("    " + taskLine(c))
+		}
 	}
-	fmt.Printf("Marked %d done\n", id)
 	return nil
 }
 
-func cmdRemove(args []string) error {
+var moveFlags = []flagDef{
+	valueFlag("to"), boolFlag("up"), boolFlag("down"), boolFlag("top"), boolFlag("bottom"),
+	valueFlag("before"),
+}
+
+func cmdMove(args []string) error {
 	_ = args
-	if len(args) == 0 {
-		return errors.New("usage: todo rm <id>")
+	ca, err := parseArgs(args, moveFlags...)
+	if err != nil {
+		return err
 	}
-	id, err := strconv.ParseInt(args[0], 10, 64)
+	if len(ca.pos) != 1 || len(ca.values) != 1 {
+		return usageError("move")
+	}
+	id, err := parseID(ca.pos[0])
+	if err != nil {
+		return err
+	}
+	if !ca.has("to") {
+		return reorderTask(ca, id)
+	}
+	dest, err := listFilePath(ca.value("to"))
+	if err != nil {
+		return err
+	}
+	src, err := tasksFilePath()
 	if err != nil {
 		return err
 	}
+	if filepath.Clean(dest) == filepath.Clean(src) {
+		return fmt.Errorf("task %d is already in list %s", id, ca.value("to"))
+	}
 	ts, err := loadTasks()
 	if err != nil {
 		return err
 	}
-	i := findIndexByID(ts, id)
+	i := ts.Index(id)
 	if i == -1 {
-		return fmt.Errorf("task %d not found", id)
+		return notFoundErrorf("task %d not found", id)
 	}
-	ts = append(ts[:i], ts[i+1:]...)
-	if err := saveTasks(ts); err != nil {
+	other, err := readList(dest)
+	if err != nil {
+		return err
+	}
+	t := ts[i]
+	t.ID = other.NextID()
+	t.ParentID = nil
+	t.Order = nextOrder(other)
+	// write the destination first so a crash in between duplicates the
+	// task instead of dropping it
+	if err := writeList(dest, append(other, t)); err != nil {
+		return err
+	}
+	if err := saveTasks(append(ts[:i], ts[i+1:]...)); err != nil {
 		return err
 	}
-	fmt.Printf("Removed %d\n", id)
+	say("Moved %d to %s as %d\n", id, ca.value("to"), t.ID)
 	return nil
 }
 
-func cmdEdit(args []string) error {
+var noteFlags = []flagDef{boolFlag("replace")}
+
+func cmdNote(args []string) error {
 	_ = args
-	if len(args) < 2 {
-		return errors.New("usage: todo edit <id> <new title>")
+	ca, err := parseArgs(args, noteFlags...)
+	if err != nil {
+		return err
 	}
-	id, err := strconv.ParseInt(args[0], 10, 64)
+	if len(ca.pos) == 0 || (len(ca.pos) < 2 && !ca.has("replace")) {
+		return usageError("note")
+	}
+	id, err := parseID(ca.pos[0])
 	if err != nil {
 		return err
 	}
-	newTitle := strings.Join(args[1:], " ")
+	text := strings.Join(ca.pos[1:], " ")
 	ts, err := loadTasks()
 	if err != nil {
 		return err
 	}
-	i := findIndexByID(ts, id)
+	i := ts.Index(id)
 	if i == -1 {
-		return fmt.Errorf("task %d not found", id)
+		return notFoundErrorf("task %d not found", id)
+	}
+	if ca.has("replace") || ts[i].Notes == "" {
+		ts[i].Notes = text
+	} else {
+		ts[i].Notes += "\n" + text
 	}
-	ts[i].Title = newTitle
 	if err := saveTasks(ts); err != nil {
 		return err
 	}
-	fmt.Printf("Updated %d\n", id)
+	say("Updated notes for %d\n", id)
 	return nil
 }
 
+var clearFlags = []flagDef{boolFlag("force", "f")}
+
 func cmdClear(args []string) error {
 	_ = args
-	path, err := tasksFilePath()
+	ca, err := parseArgs(args, clearFlags...)
 	if err != nil {
 		return err
 	}
-	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+	if len(ca.pos) > 0 {
+		return usageError("clear")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	if !ca.has("force") {
+		if len(ts) > 0 {
+			if !isTerminal(os.Stdin) {
+				return errors.New("refusing to clear without --force when stdin is not a terminal")
+			}
+			ok, err := confirm(fmt.Sprintf("Delete %d tasks?", len(ts)))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Aborted.")
+				return nil
+			}
+		}
+		// the lock is only taken once answered; the answer was about ts
+		unlock, err := lockTasks()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		answered := ts
+		if ts, err = loadTasks(); err != nil {
+			return err
+		}
+		if listChanged(answered, ts) {
+			return errors.New("the list changed while you were answering; nothing was deleted")
+		}
+	}
+	if err := saveTasks(Tasks{}); err != nil {
 		return err
 	}
-	fmt.Println("All tasks cleared.")
+	say("Deleted %d tasks.\n", len(ts))
 	return nil
 }
 
-func usage() {
-	fmt.Println(`Usage: todo <command> [args]
-Commands:
-  add <title>       Add a task
-  list              List tasks
-  do <id>           Mark task done
-  rm <id>           Remove task
-  edit <id> <title> Edit task title
-  clear             Remove all tasks
-  help              Show this help`)
+// globalFlag is a flag accepted anywhere on the command line, before or
+// after the command name. apply records its value.
+type globalFlag struct {
+	flagDef
+	apply func(value string) error
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		usage()
-		return
+var globalFlags = []globalFlag{
+	{valueFlag("list"), func(v string) error {
+		if err := validateListName(v); err != nil {
+			return err
+		}
+		listName = v
+		return nil
+	}},
+	{boolFlag("recover"), func(string) error { recoverFlag = true; return nil }},
+	{boolFlag("quiet", "q"), func(string) error { quiet = true; return nil }},
+	{boolFlag("verbose", "v"), func(string) error { verbose = true; return nil }},
+	{boolFlag("no-webhook"), func(string) error { noWebhook = true; return nil }},
+	{boolFlag("global"), func(string) error { globalOnly = true; return nil }},
+}
+
+// extractGlobalFlags applies the global flags in args and returns the
+// rest. Like parseArgs it stops at "--".
+func extractGlobalFlags(args []string) ([]string, error) {
+	var rest []string
+next:
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			return append(rest, args[i:]...), nil
+		}
+		if len(a) < 2 || a[0] != '-' || isNumber(a) {
+			rest = append(rest, a)
+			continue
+		}
+		name, value, hasValue := strings.TrimLeft(a, "-"), "", false
+		if k := strings.IndexByte(name, '='); k >= 0 {
+			name, value, hasValue = name[:k], name[k+1:], true
+		}
+		for _, g := range globalFlags {
+			if !slices.Contains(g.names, name) {
+				continue
+			}
+			switch {
+			case g.boolean && hasValue:
+				return nil, usageErrorf("flag %s takes no value", a)
+			case !g.boolean && !hasValue:
+				if i+1 >= len(args) {
+					return nil, usageErrorf("flag %s needs a value", a)
+				}
+				i++
+				value = args[i]
+			}
+			if err := g.apply(value); err != nil {
+				return nil, usageErrorf("%w", err)
+			}
+			continue next
+		}
+		rest = append(rest, a)
 	}
-	cmd := os.Args[1]
-	args := os.Args[2:]
-	var err error
-	switch cmd {
-	case "add":
-		err = cmdAdd(args)
-	case "list":
-		err = cmdList(args)
-	case "do", "complete":
-		err = cmdDo(args)
-	case "rm", "remove":
-		err = cmdRemove(args)
-	case "edit":
-		err = cmdEdit(args)
-	case "clear":
-		err = cmdClear(args)
-	case "help":
-		usage()
-		return
-	default:
-		fmt.Println("Unknown command:", cmd)
-		usage()
-		return
+	return rest, nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run executes a command line and returns the exit code, which follows the
+// exit* constants.
+func run(argv []string) int {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: ignoring config file:", err)
+	} else {
+		config = cfg
 	}
+	argv, err = extractGlobalFlags(argv)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
-		os.Exit(1)
+		return exitCode(err)
+	}
+	if err := validateBackend(os.Getenv("TODO_BACKEND")); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: TODO_BACKEND:", err)
+		return exitCode(err)
+	}
+	if passphrase, err = loadPassphrase(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitCode(err)
+	}
+	if len(argv) < 1 {
+		usage(os.Stderr)
+		return exitUsage
+	}
+	cmd := argv[0]
+	args := argv[1:]
+	c, ok := findCommand(cmd)
+	if !ok {
+		if path, found := findPlugin(cmd); found {
+			err := runPlugin(path, args)
+			var es exitStatus
+			if err != nil && !errors.As(err, &es) {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+			return exitCode(err)
+		}
+		// autocorrect only when the meaning is clear: one candidate, and no
+		// "--" saying the arguments are to be taken literally
+		names := suggestCommands(cmd)
+		if !config.Autocorrect || len(names) != 1 || slices.Contains(args, "--") {
+			unknownCommand(cmd)
+			return exitUsage
+		}
+		fmt.Fprintf(os.Stderr, "Unknown command %q; running %q instead.\n", cmd, names[0])
+		cmd, argv[0] = names[0], names[0]
+		c, _ = findCommand(cmd)
+	}
+	if wantsHelp(args) && !c.hidden {
+		printHelp(os.Stdout, c)
+		return exitOK
+	}
+	unlock := func() {}
+	if mutatingCommands[cmd] && !waitsForInput(cmd, args) {
+		if unlock, err = lockTasks(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitCode(err)
+		}
+	}
+	defer unlock()
+	commandName = cmd
+	err = c.run(args)
+	var es exitStatus
+	if err != nil && !errors.As(err, &es) {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	if err == nil && config.GitSync && mutatingCommands[cmd] && cmd != "sync" {
+		autoCommit(argv)
+	}
+	// a failed command sends nothing, even if it saved along the way
+	if err == nil {
+		sendEvents(cmd)
 	}
+	pendingEvents = nil
+	return exitCode(err)
 }
@@ -0,0 +1,353 @@
+// main_test.go
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestMain makes the test binary act as todo when TODO_TEST_MAIN is set,
+// for tests that need separate processes.
+func TestMain(m *testing.M) {
+	if os.Getenv("TODO_TEST_MAIN") == "1" {
+		os.Exit(run(os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// todoCommand runs the test binary as todo with args, in the environment
+// testEnv set up.
+func todoCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "TODO_TEST_MAIN=1")
+	return cmd
+}
+
+// testEnv gives a test empty data and config directories, a home and a
+// working directory of its own, and resets the state that commands leave
+// in globals, before and after. It returns the home directory.
+func testEnv(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	for _, v := range []string{"TODO_FILE", "TODO_LIST", "TODO_BACKEND", "TODO_PASSPHRASE", "NO_COLOR"} {
+		t.Setenv(v, "")
+		os.Unsetenv(v)
+	}
+	work := filepath.Join(home, "work")
+	if err := os.Mkdir(work, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(work)
+	resetState()
+	t.Cleanup(resetState)
+	return home
+}
+
+func resetState() {
+	if store != nil {
+		closeStore(store)
+	}
+	store, openStore = nil, listStore
+	config, passphrase = Config{}, ""
+	listName, recoverFlag, globalOnly, noWebhook = "", false, false, false
+	quiet, verbose, colorize, relativeTimes, idWidth = false, false, false, false, 0
+	projectSearched, projectPath = false, ""
+	loaded, pendingEvents, listTemplate, commandName = nil, nil, nil, ""
+}
+
+// runTodo runs a command line as main does, after resetting what the last
+// one left behind, and returns its exit code and standard output.
+func runTodo(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	resetState()
+	var code int
+	out := captureStdout(t, func() { code = run(args) })
+	return code, out
+}
+
+// captureStdout returns what f writes to standard output.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	done := make(chan string, 1)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+	saved := os.Stdout
+	os.Stdout = w
+	func() {
+		// restore stdout even when f stops the test
+		defer func() {
+			os.Stdout = saved
+			w.Close()
+		}()
+		f()
+	}()
+	return <-done
+}
+
+func TestParseIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		ids    []int64
+		ranged []int64
+		err    bool
+	}{
+		{name: "single", args: []string{"3"}, ids: []int64{3}},
+		{name: "range", args: []string{"1-3"}, ids: []int64{1, 2, 3}, ranged: []int64{1, 2, 3}},
+		{name: "one-ID range", args: []string{"4-4"}, ids: []int64{4}, ranged: []int64{4}},
+		{name: "mixed", args: []string{"1", "3", "5-7"}, ids: []int64{1, 3, 5, 6, 7}, ranged: []int64{5, 6, 7}},
+		{name: "repeats dropped", args: []string{"1", "1"}, ids: []int64{1}},
+		{name: "range overlapping an ID", args: []string{"2", "1-3"}, ids: []int64{2, 1, 3}, ranged: []int64{1, 3}},
+		{name: "start after end", args: []string{"3-1"}, err: true},
+		{name: "comma", args: []string{"1,1"}, err: true},
+		{name: "open range", args: []string{"3-"}, err: true},
+		{name: "word range", args: []string{"a-b"}, err: true},
+		{name: "empty", args: []string{""}, err: true},
+		{name: "too large", args: []string{"1-20000"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := parseIDs(tt.args)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("parseIDs(%q) = %v, want an error", tt.args, l.ids)
+				}
+				if got := exitCode(err); got != exitUsage {
+					t.Errorf("parseIDs(%q) error exits %d, want %d", tt.args, got, exitUsage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseIDs(%q): %v", tt.args, err)
+			}
+			if !slices.Equal(l.ids, tt.ids) {
+				t.Errorf("parseIDs(%q) = %v, want %v", tt.args, l.ids, tt.ids)
+			}
+			for _, id := range l.ids {
+				if want := slices.Contains(tt.ranged, id); l.ranged[id] != want {
+					t.Errorf("parseIDs(%q): ID %d ranged = %v, want %v", tt.args, id, l.ranged[id], want)
+				}
+			}
+		})
+	}
+}
+
+// TestUndoneDropsCompletedAt checks that reopening a task removes its
+// completion time from the file, not just sets it to null.
+func TestUndoneDropsCompletedAt(t *testing.T) {
+	testEnv(t)
+	for _, args := range [][]string{{"add", "Pay rent"}, {"do", "1"}, {"undone", "1"}} {
+		if code, _ := runTodo(t, args...); code != exitOK {
+			t.Fatalf("todo %s exited %d", strings.Join(args, " "), code)
+		}
+	}
+	path, err := tasksFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "completed_at") {
+		t.Errorf("reopened task still has completed_at:\n%s", b)
+	}
+	if _, out := runTodo(t, "undone", "1"); out != "Task 1 is not completed.\n" {
+		t.Errorf("undone of an open task printed %q", out)
+	}
+}
+
+// TestTasksFilePath checks the order in which the tasks file is chosen:
+// a list, TODO_FILE, a project file, then the default list under
+// XDG_DATA_HOME or ~/.local/share, with an old ~/.todo moved there.
+func TestTasksFilePath(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T, home string)
+		want   string // relative to home
+		source string
+	}{
+		{
+			name:   "XDG_DATA_HOME",
+			want:   "data/todo/tasks.json",
+			source: "default list",
+		},
+		{
+			name:   "HOME without XDG_DATA_HOME",
+			setup:  func(t *testing.T, home string) { t.Setenv("XDG_DATA_HOME", "") },
+			want:   ".local/share/todo/tasks.json",
+			source: "default list",
+		},
+		{
+			name:   "TODO_FILE beats XDG_DATA_HOME",
+			setup:  func(t *testing.T, home string) { t.Setenv("TODO_FILE", filepath.Join(home, "mine.json")) },
+			want:   "mine.json",
+			source: "TODO_FILE",
+		},
+		{
+			name: "TODO_FILE beats a project file",
+			setup: func(t *testing.T, home string) {
+				t.Setenv("TODO_FILE", filepath.Join(home, "mine.json"))
+				writeFile(t, filepath.Join(home, "work", projectFileName), "[]")
+			},
+			want:   "mine.json",
+			source: "TODO_FILE",
+		},
+		{
+			name: "TODO_LIST beats TODO_FILE",
+			setup: func(t *testing.T, home string) {
+				t.Setenv("TODO_FILE", filepath.Join(home, "mine.json"))
+				t.Setenv("TODO_LIST", "work")
+			},
+			want:   "data/todo/work.json",
+			source: "TODO_LIST",
+		},
+		{
+			name:   "project file beats the default list",
+			setup:  func(t *testing.T, home string) { writeFile(t, filepath.Join(home, "work", projectFileName), "[]") },
+			want:   "work/" + projectFileName,
+			source: "project file",
+		},
+		{
+			name: "project file found above the working directory",
+			setup: func(t *testing.T, home string) {
+				writeFile(t, filepath.Join(home, "work", projectFileName), "[]")
+				sub := filepath.Join(home, "work", "src", "cmd")
+				if err := os.MkdirAll(sub, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				t.Chdir(sub)
+			},
+			want:   "work/" + projectFileName,
+			source: "project file",
+		},
+		{
+			name:   "no project file in the home directory",
+			setup:  func(t *testing.T, home string) { writeFile(t, filepath.Join(home, projectFileName), "[]") },
+			want:   "data/todo/tasks.json",
+			source: "default list",
+		},
+		{
+			name: "--global skips the project file",
+			setup: func(t *testing.T, home string) {
+				writeFile(t, filepath.Join(home, "work", projectFileName), "[]")
+				globalOnly = true
+			},
+			want:   "data/todo/tasks.json",
+			source: "default list",
+		},
+		{
+			name: "legacy ~/.todo is moved to XDG_DATA_HOME",
+			setup: func(t *testing.T, home string) {
+				writeFile(t, filepath.Join(home, ".todo", "tasks.json"), `[{"id":1,"title":"old","done":false,"created_at":"2024-01-01T00:00:00Z"}]`)
+			},
+			want:   "data/todo/tasks.json",
+			source: "default list",
+		},
+		{
+			name: "XDG_DATA_HOME beats a legacy ~/.todo",
+			setup: func(t *testing.T, home string) {
+				writeFile(t, filepath.Join(home, "data", "todo", "tasks.json"), "[]")
+				writeFile(t, filepath.Join(home, ".todo", "tasks.json"), "[]")
+			},
+			want:   "data/todo/tasks.json",
+			source: "default list",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := testEnv(t)
+			if tt.setup != nil {
+				tt.setup(t, home)
+			}
+			path, source, err := resolveTasksFile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(home, tt.want); path != want || source != tt.source {
+				t.Errorf("tasks file = %s (%s), want %s (%s)", path, source, want, tt.source)
+			}
+		})
+	}
+}
+
+func TestLegacyDataDirMoved(t *testing.T) {
+	home := testEnv(t)
+	const list = `[{"id":1,"title":"old","done":false,"created_at":"2024-01-01T00:00:00Z"}]`
+	writeFile(t, filepath.Join(home, ".todo", "tasks.json"), list)
+	path, err := tasksFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != list {
+		t.Errorf("%s holds %q (%v), want the old list", path, b, err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".todo")); !os.IsNotExist(err) {
+		t.Errorf("~/.todo is still there (%v)", err)
+	}
+}
+
+// TestInitOutsideHome checks that a project's state directory can't be
+// mistaken for the old ~/.todo data directory: init refuses to run in the
+// home directory, and elsewhere keeps its files in .todo.d.
+func TestInitOutsideHome(t *testing.T) {
+	home := testEnv(t)
+	t.Chdir(home)
+	if code, _ := runTodo(t, "init"); code != exitUsage {
+		t.Errorf("init in the home directory exited %d, want %d", code, exitUsage)
+	}
+	if _, err := os.Stat(filepath.Join(home, projectFileName)); !os.IsNotExist(err) {
+		t.Errorf("init in the home directory created %s (%v)", projectFileName, err)
+	}
+	t.Chdir(filepath.Join(home, "work"))
+	for _, args := range [][]string{{"init"}, {"add", "Fix the build"}} {
+		if code, _ := runTodo(t, args...); code != exitOK {
+			t.Fatalf("todo %s exited %d", strings.Join(args, " "), code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(home, "work", ".todo.d", "lock")); err != nil {
+		t.Errorf("project lock not in .todo.d: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(home, "work", ".gitignore")); err != nil || string(b) != "/.todo.d/\n" {
+		t.Errorf(".gitignore holds %q (%v)", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".todo")); !os.IsNotExist(err) {
+		t.Errorf("~/.todo exists (%v)", err)
+	}
+}
+
+func TestConfigFilePath(t *testing.T) {
+	home := testEnv(t)
+	path, err := configFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, "config", "todo", "config.toml"); path != want {
+		t.Errorf("config file = %s, want %s", path, want)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
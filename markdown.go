@@ -0,0 +1,107 @@
+// markdown.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// exportMarkdown writes GitHub-flavored task lists under Pending and
+// Completed headings; the Completed section is left out when empty.
+func exportMarkdown(w io.Writer, ts Tasks) error {
+	var b strings.Builder
+	b.WriteString("## Pending\n\n")
+	for _, t := range ts.Filter(func(t Task) bool { return !t.Done }) {
+		b.WriteString(markdownItem(t) + "\n")
+	}
+	if done := ts.Filter(func(t Task) bool { return t.Done }); len(done) > 0 {
+		b.WriteString("\n## Completed\n\n")
+		for _, t := range done {
+			b.WriteString(markdownItem(t) + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownItem(t Task) string {
+	check := " "
+	if t.Done {
+		check = "x"
+	}
+	line := fmt.Sprintf("- [%s] %s", check, t.Title)
+	if t.State() == todo.StatusDoing {
+		line += " (doing)"
+	}
+	if t.Pinned {
+		line += " (pinned)"
+	}
+	if t.DueDate != nil {
+		line += fmt.Sprintf(" (due %s)", t.DueDate.Format("2006-01-02"))
+	}
+	return line
+}
+
+// checklistItem matches a task list line such as "  - [x] title" or
+// "* [ ] title"; the doing, pinned and due suffixes written by
+// exportMarkdown are picked up too.
+var (
+	checklistItem  = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	markdownDue    = regexp.MustCompile(`\s*\(due (\d{4}-\d{2}-\d{2})\)$`)
+	markdownPinned = regexp.MustCompile(`\s*\(pinned\)$`)
+	markdownDoing  = regexp.MustCompile(`\s*\(doing\)$`)
+)
+
+// importMarkdown creates a task from every checklist line, at any
+// indentation; nested items are flattened. Other lines are ignored.
+func importMarkdown(r io.Reader) (Tasks, int, error) {
+	var ts Tasks
+	lines, skipped := 0, 0
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lines++
+		m := checklistItem.FindStringSubmatch(sc.Text())
+		if m == nil || strings.TrimSpace(m[2]) == "" {
+			continue
+		}
+		t := Task{Title: strings.TrimSpace(m[2]), Done: m[1] != " ", CreatedAt: time.Now()}
+		if d := markdownDue.FindStringSubmatch(t.Title); d != nil {
+			if due, err := time.ParseInLocation("2006-01-02", d[1], time.Local); err == nil {
+				t.DueDate = &due
+				t.Title = strings.TrimSpace(strings.TrimSuffix(t.Title, d[0]))
+			}
+		}
+		if p := markdownPinned.FindString(t.Title); p != "" {
+			t.Pinned = true
+			t.Title = strings.TrimSuffix(t.Title, p)
+		}
+		if p := markdownDoing.FindString(t.Title); p != "" && !t.Done {
+			t.Status = todo.StatusDoing
+			t.Title = strings.TrimSuffix(t.Title, p)
+		}
+		title, err := normalizeTitle(t.Title)
+		if err != nil {
+			skipRecord(lines, "%v", err)
+			skipped++
+			continue
+		}
+		t.Title = title
+		if t.Done {
+			// the checkbox carries no date; completion is the import
+			completed := t.CreatedAt
+			t.CompletedAt = &completed
+		}
+		ts = append(ts, t)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, 0, err
+	}
+	say("Found %d checklist items in %d lines.\n", len(ts)+skipped, lines)
+	return ts, skipped, nil
+}
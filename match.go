@@ -0,0 +1,154 @@
+// match.go
+package main
+
+import (
+	"strings"
+)
+
+// idLike reports whether an argument is meant as an ID or a range of them
+// rather than a title.
+func idLike(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789-") == ""
+}
+
+// uidLike reports whether an argument could be the start of a UID: at
+// least four hex digits and dashes, with a letter among them so that it
+// isn't read as an ID.
+func uidLike(s string) bool {
+	s = strings.ToLower(s)
+	return len(s) >= 4 && strings.Trim(s, "0123456789abcdef-") == "" && strings.ContainsAny(s, "abcdef")
+}
+
+// uidTasks returns the tasks whose UID starts with prefix, ignoring case.
+func uidTasks(ts Tasks, prefix string) Tasks {
+	prefix = strings.ToLower(prefix)
+	return ts.Filter(func(t Task) bool { return strings.HasPrefix(t.UID, prefix) })
+}
+
+// isUID reports whether arg starts some task's UID. Besides uidLike
+// arguments, that can be six or more digits, which would be an unlikely
+// ID; one that starts no UID is still read as an ID.
+func isUID(arg string) bool {
+	if !uidLike(arg) && (len(arg) < 6 || strings.Trim(arg, "0123456789") != "") {
+		return false
+	}
+	ts, err := loadTasks()
+	return err == nil && len(uidTasks(ts, arg)) > 0
+}
+
+// findByUID returns the one task whose UID starts with prefix.
+func findByUID(prefix string) (int64, error) {
+	ts, err := loadTasks()
+	if err != nil {
+		return 0, err
+	}
+	found := uidTasks(ts, prefix)
+	switch len(found) {
+	case 0:
+		return 0, notFoundErrorf("no task has a UID starting with %q", prefix)
+	case 1:
+		return found[0].ID, nil
+	}
+	lines := make([]string, len(found))
+	for i, t := range found {
+		lines[i] = "  " + t.UID + "  " + taskLine(t)
+	}
+	return 0, notFoundErrorf("UID prefix %q matches %d tasks; give more of it:\n%s", prefix, len(found), strings.Join(lines, "\n"))
+}
+
+// namedOnce reports whether a command's tasks were named in exactly one
+// way: as arguments, with --title or with --pick.
+func namedOnce(ca cmdArgs) bool {
+	n := 0
+	for _, given := range []bool{len(ca.pos) > 0, ca.has("title"), ca.has("pick")} {
+		if given {
+			n++
+		}
+	}
+	return n == 1
+}
+
+// targetIDs returns the tasks named by a command's arguments: IDs, ranges
+// and UID prefixes, or the one task whose title matches when --title is
+// given or an argument is neither. IDs and UIDs win, so a title that looks
+// like one needs --title.
+func targetIDs(ca cmdArgs) (idList, error) {
+	query := ca.value("title")
+	if !ca.has("title") {
+		for _, a := range ca.pos {
+			if !idLike(a) && !isUID(a) {
+				query = strings.Join(ca.pos, " ")
+				break
+			}
+		}
+		if query == "" {
+			return parseIDs(ca.pos)
+		}
+	}
+	id, err := findByTitle(query)
+	if err != nil {
+		return idList{}, err
+	}
+	return idList{ids: []int64{id}, ranged: map[int64]bool{}}, nil
+}
+
+// targetID is targetIDs for commands that take one task, named by arg
+// unless --title is given.
+func targetID(ca cmdArgs, arg string) (int64, error) {
+	switch {
+	case ca.has("title"):
+		return findByTitle(ca.value("title"))
+	case idLike(arg):
+		return parseID(arg)
+	case isUID(arg):
+		return findByUID(arg)
+	}
+	return findByTitle(arg)
+}
+
+// findByTitle returns the task whose title starts with or contains query,
+// ignoring case and spacing. Prefix matches beat other ones, and pending
+// tasks beat completed ones; anything but one best match is an error.
+func findByTitle(query string) (int64, error) {
+	ts, err := loadTasks()
+	if err != nil {
+		return 0, err
+	}
+	q := dupKey(query)
+	if q == "" {
+		return 0, usageErrorf("empty task title")
+	}
+	var best Tasks
+	bestRank := 4
+	for _, t := range ts {
+		title := dupKey(t.Title)
+		rank := 0
+		switch {
+		case strings.HasPrefix(title, q):
+		case strings.Contains(title, q):
+			rank = 1
+		default:
+			continue
+		}
+		if t.Done {
+			rank += 2
+		}
+		if rank < bestRank {
+			best, bestRank = nil, rank
+		}
+		if rank == bestRank {
+			best = append(best, t)
+		}
+	}
+	switch len(best) {
+	case 0:
+		return 0, notFoundErrorf("no task matches %q", query)
+	case 1:
+		return best[0].ID, nil
+	}
+	lines := make([]string, len(best))
+	for i, t := range best {
+		lines[i] = "  " + taskLine(t)
+	}
+	return 0, notFoundErrorf("%q matches %d tasks; give an ID:\n%s", query, len(best), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,239 @@
+// notify.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// defaultNotifyWindow is how far ahead notify looks when neither --within
+// nor notify_window says.
+const defaultNotifyWindow = time.Hour
+
+var notifyFlags = []flagDef{valueFlag("within", "w"), boolFlag("stdout")}
+
+// cmdNotify sends a desktop notification for each pending task that is
+// overdue or due within the window, once per task and due date, so that it
+// can run every few minutes from cron or a systemd timer. A notification
+// that can't be sent is reported on stderr and tried again on the next run
+// without failing this one.
+func cmdNotify(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, notifyFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("notify")
+	}
+	window, err := notifyWindow(ca)
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	notices, _, err := notifyDue(ts, window, time.Now(), notifySender(ca))
+	for _, n := range notices {
+		if n.err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not notify about task %d: %v\n", n.t.ID, n.err)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+	}
+	return nil
+}
+
+// notifyWindow is how far ahead of a due date to notify: --within, else
+// notify_window, else an hour.
+func notifyWindow(ca cmdArgs) (time.Duration, error) {
+	window := defaultNotifyWindow
+	if config.NotifyWindow != "" {
+		// validated when the config was read
+		window, _ = time.ParseDuration(config.NotifyWindow)
+	}
+	if ca.has("within") {
+		w, err := time.ParseDuration(ca.value("within"))
+		if err != nil || w < 0 {
+			return 0, usageErrorf("invalid --within %q: expected a duration such as 30m or 2h", ca.value("within"))
+		}
+		window = w
+	}
+	return window, nil
+}
+
+// notifySender shows a notification on the desktop, or with --stdout
+// prints it.
+func notifySender(ca cmdArgs) func(Task, string) error {
+	if ca.has("stdout") {
+		return func(t Task, body string) error {
+			fmt.Printf("%s: %s\n", taskLine(t), body)
+			return nil
+		}
+	}
+	return func(t Task, body string) error { return desktopNotify(t.Title, body) }
+}
+
+// notice is a notification notifyDue sent, or failed to send if err is
+// set.
+type notice struct {
+	t    Task
+	body string
+	err  error
+}
+
+// notifyDue sends the notifications that are due at now and not yet sent,
+// records them and returns them, with the time the next one falls due or
+// the zero time. The error is about reading or writing the record, which
+// doesn't stop the notifications.
+func notifyDue(ts Tasks, window time.Duration, now time.Time, send func(Task, string) error) ([]notice, time.Time, error) {
+	sent, stateErr := readNotified()
+	if stateErr != nil {
+		stateErr = fmt.Errorf("could not read past notifications: %v", stateErr)
+	}
+	// forgetting tasks that are done or due at another time keeps the file
+	// small, and notifies again about a postponed task
+	var kept []notified
+	for _, n := range sent {
+		if i := slices.IndexFunc(ts, func(t Task) bool { return t.UID == n.UID }); i != -1 &&
+			!ts[i].Done && ts[i].DueDate != nil && ts[i].DueDate.Equal(n.Due) {
+			kept = append(kept, n)
+		}
+	}
+	pending := ts.Filter(func(t Task) bool { return !t.Done && t.DueDate != nil })
+	slices.SortStableFunc(pending, func(a, b Task) int { return a.DueDate.Compare(*b.DueDate) })
+	var notices []notice
+	var next time.Time
+	for _, t := range pending {
+		n := notified{UID: t.UID, Due: *t.DueDate}
+		if indexNotified(kept, n) != -1 {
+			continue
+		}
+		// a deferred task is notified about once it starts
+		at := t.DueDate.Add(-window)
+		if t.StartDate != nil && t.StartDate.After(at) {
+			at = *t.StartDate
+		}
+		if at.After(now) {
+			if next.IsZero() || at.Before(next) {
+				next = at
+			}
+			continue
+		}
+		body := "due " + humanizeTime(*t.DueDate, now)
+		if t.IsOverdue(now) {
+			body = "overdue, due " + humanizeTime(*t.DueDate, now)
+		}
+		err := send(t, body)
+		notices = append(notices, notice{t, body, err})
+		if err == nil {
+			n.At = now
+			kept = append(kept, n)
+		}
+	}
+	if err := writeNotified(kept); err != nil && stateErr == nil {
+		stateErr = fmt.Errorf("could not record notifications: %v", err)
+	}
+	return notices, next, stateErr
+}
+
+// notified records that a task was notified about for a due date, so a
+// postponed task is notified about again.
+type notified struct {
+	UID string    `json:"uid"`
+	Due time.Time `json:"due"`
+	At  time.Time `json:"at"`
+}
+
+func indexNotified(ns []notified, n notified) int {
+	for i, o := range ns {
+		if o.UID == n.UID && o.Due.Equal(n.Due) {
+			return i
+		}
+	}
+	return -1
+}
+
+func readNotified() ([]notified, error) {
+	path, err := companionPath("notified")
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, dataError(err)
+	}
+	var ns []notified
+	if err := json.Unmarshal(b, &ns); err != nil {
+		return nil, dataError(fmt.Errorf("%s: %v", path, err))
+	}
+	return ns, nil
+}
+
+func writeNotified(ns []notified) error {
+	path, err := companionPath("notified")
+	if err != nil {
+		return err
+	}
+	if len(ns) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.MarshalIndent(ns, "", "  ")
+	if err != nil {
+		return err
+	}
+	return todo.WriteFileAtomic(path, b)
+}
+
+// windowsToast shows a toast through the Windows Runtime, reading the
+// title and body from the environment so they need no quoting.
+const windowsToast = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:TODO_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:TODO_NOTIFY_BODY)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('todo').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+
+// desktopNotify shows a notification with notify-send, osascript on macOS
+// or a PowerShell toast on Windows.
+func desktopNotify(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", "on run argv", "-e",
+			"display notification (item 2 of argv) with title (item 1 of argv)", "-e", "end run", title, body)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToast)
+		cmd.Env = append(os.Environ(), "TODO_NOTIFY_TITLE="+title, "TODO_NOTIFY_BODY="+body)
+	default:
+		cmd = exec.Command("notify-send", "--app-name=todo", title, body)
+	}
+	out, err := cmd.CombinedOutput()
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%s is not installed", cmd.Args[0])
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %v: %s", cmd.Args[0], err, msg)
+		}
+		return fmt.Errorf("%s: %v", cmd.Args[0], err)
+	}
+	return nil
+}
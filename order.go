@@ -0,0 +1,121 @@
+// order.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// orderStep is the gap left between neighbouring tasks' Order values so a
+// task can usually be moved by changing only its own value.
+const orderStep = 1024
+
+// orderRank is the Order value used for sorting. Tasks that were never
+// placed (Order 0) go after placed ones, so imported or old tasks land at
+// the bottom.
+func orderRank(t Task) int64 {
+	if t.Order == 0 {
+		return 1<<63 - 1
+	}
+	return t.Order
+}
+
+// nextOrder is the Order for a task appended at the bottom of the list.
+// While nothing has been placed by hand it stays 0 so ID order holds.
+func nextOrder(ts Tasks) int64 {
+	var last int64
+	for _, t := range ts {
+		last = max(last, t.Order)
+	}
+	if last == 0 {
+		return 0
+	}
+	return last + orderStep
+}
+
+// renumberOrder gives every task an Order matching how list shows it now,
+// spaced orderStep apart.
+func renumberOrder(ts Tasks) {
+	sorted := slices.Clone(ts)
+	sortForDisplay(sorted)
+	for k, t := range sorted {
+		ts[ts.Index(t.ID)].Order = int64(k+1) * orderStep
+	}
+}
+
+// reorderTask handles move --up, --down, --top, --bottom and --before. A
+// task moves among the tasks list would show next to it: those with the
+// same priority and done state, since priority still comes first.
+func reorderTask(ca cmdArgs, id int64) error {
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	if slices.ContainsFunc(ts, func(t Task) bool { return t.Order == 0 }) {
+		renumberOrder(ts)
+	}
+	t := ts[i]
+	group := ts.Filter(func(o Task) bool {
+		return o.Done == t.Done && priorityRank(o.Priority) == priorityRank(t.Priority)
+	})
+	sortForDisplay(group)
+	pos := slices.IndexFunc(group, func(o Task) bool { return o.ID == id })
+	group = slices.Delete(group, pos, pos+1)
+	switch {
+	case ca.has("up"):
+		pos = max(pos-1, 0)
+	case ca.has("down"):
+		pos = min(pos+1, len(group))
+	case ca.has("top"):
+		pos = 0
+	case ca.has("bottom"):
+		pos = len(group)
+	case ca.has("before"):
+		other, err := parseID(ca.value("before"))
+		if err != nil {
+			return err
+		}
+		if other == id {
+			return errors.New("can't move a task before itself")
+		}
+		if pos = slices.IndexFunc(group, func(o Task) bool { return o.ID == other }); pos == -1 {
+			if ts.Index(other) == -1 {
+				return notFoundErrorf("task %d not found", other)
+			}
+			return fmt.Errorf("can't move before task %d: it has a different priority or status", other)
+		}
+	}
+	// place the task halfway between its new neighbours; when there is no
+	// room left between them, spread everything out again and retry
+	place := func() bool {
+		order := func(k int) int64 { return ts[ts.Index(group[k].ID)].Order }
+		var lo, hi int64
+		if pos > 0 {
+			lo = order(pos - 1)
+		}
+		if pos < len(group) {
+			hi = order(pos)
+		} else {
+			hi = lo + 2*orderStep
+		}
+		if hi-lo < 2 {
+			return false
+		}
+		ts[i].Order = lo + (hi-lo)/2
+		return true
+	}
+	if !place() {
+		renumberOrder(ts)
+		place()
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	say("Moved %d to position %d\n", id, pos+1)
+	return nil
+}
@@ -0,0 +1,235 @@
+// output.go
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Flags shared by every command that prints tasks.
+var (
+	jsonFlag  = boolFlag("json")
+	jsonlFlag = boolFlag("jsonl")
+	colorFlag = valueFlag("color")
+)
+
+// ANSI SGR codes used by paint.
+const (
+	ansiBold   = "1"
+	ansiDim    = "2"
+	ansiRed    = "31"
+	ansiGreen  = "32"
+	ansiYellow = "33"
+
+	ansiReverse = "7"
+)
+
+// verbose is set by the global -v flag. It makes printTask include a task's
+// creation time and notes, and list pad IDs into a column.
+var verbose bool
+
+// quiet is set by the global -q flag and silences say.
+var quiet bool
+
+// idWidth pads the ID in taskLine to line up a listing.
+var idWidth int
+
+// relativeTimes makes printTask show due and completion times relative to
+// now, as list does unless --absolute is given.
+var relativeTimes bool
+
+// colorize is set by setupColor and decides whether paint emits escapes.
+var colorize bool
+
+// setupColor applies a --color value, falling back to the color setting of
+// the config file: "always", "never", or "auto" (the default), which colors
+// only when stdout is a terminal and NO_COLOR is unset.
+// listDate formats a time for printTask, using format unless relativeTimes
+// is set.
+func listDate(t time.Time, format func(time.Time) string) string {
+	if relativeTimes {
+		return humanizeTime(t, time.Now())
+	}
+	return format(t)
+}
+
+func setupColor(mode string) error {
+	if mode == "" {
+		mode = config.Color
+	}
+	switch mode {
+	case "", "auto":
+		colorize = os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	case "always":
+		colorize = true
+	case "never":
+		colorize = false
+	default:
+		return fmt.Errorf("invalid --color value %q: use auto, always or never", mode)
+	}
+	return nil
+}
+
+// isTerminal reports whether f is a terminal. Being a character device
+// isn't enough: /dev/null is one too.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// paint wraps s in the given SGR codes when color is enabled.
+func paint(s string, codes ...string) string {
+	if !colorize || len(codes) == 0 {
+		return s
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + s + "\x1b[0m"
+}
+
+// say prints the confirmation a command gives on success, like "Added 5",
+// unless -q was given. Errors and requested output don't go through it.
+func say(format string, a ...any) {
+	if !quiet {
+		fmt.Printf(format, a...)
+	}
+}
+
+func wantsJSON(ca cmdArgs) bool {
+	return ca.has("json") || ca.has("jsonl")
+}
+
+// printJSON writes tasks for scripts: an indented array with --json, or one
+// compact object per line with --jsonl.
+func printJSON(ca cmdArgs, ts Tasks) error {
+	if ca.has("jsonl") {
+		enc := json.NewEncoder(os.Stdout)
+		for _, t := range ts {
+			if err := enc.Encode(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if ts == nil {
+		ts = Tasks{}
+	}
+	b, err := json.MarshalIndent(ts, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// printTasks writes tasks in display order, or as JSON when asked. The empty
+// message replaces an empty list; in JSON mode it goes to stderr so that
+// stdout stays machine readable.
+func printTasks(ca cmdArgs, ts Tasks, empty string) error {
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	if err := ts.SortBy(ca.value("sort"), ca.has("reverse")); err != nil {
+		sortForDisplay(ts)
+	}
+	pinnedFirst(ts)
+	if wantsJSON(ca) || ca.has("porcelain") {
+		if len(ts) == 0 {
+			fmt.Fprintln(os.Stderr, empty)
+		}
+		if ca.has("porcelain") {
+			return printPorcelain(ts, ca.has("z"))
+		}
+		return printJSON(ca, ts)
+	}
+	if len(ts) == 0 {
+		fmt.Println(empty)
+		return nil
+	}
+	if verbose {
+		idWidth = len(strconv.FormatInt(slices.MaxFunc(ts, func(a, b Task) int { return cmp.Compare(a.ID, b.ID) }).ID, 10))
+	}
+	if by := ca.value("group-by"); by != "" {
+		return printGroups(ts, by, time.Now())
+	}
+	return printPlain(ts)
+}
+
+// printPlain writes tasks through the --format template if there is one,
+// or as a tree.
+func printPlain(ts Tasks) error {
+	if listTemplate != nil {
+		for _, t := range ts {
+			if err := listTemplate.Execute(os.Stdout, t); err != nil {
+				return err
+			}
+			fmt.Println()
+		}
+		return nil
+	}
+	printTree(ts)
+	return nil
+}
+
+// pinnedFirst moves pinned tasks to the front, whatever the order asked
+// for, keeping the order within each group.
+func pinnedFirst(ts Tasks) {
+	slices.SortStableFunc(ts, func(a, b Task) int {
+		switch {
+		case a.Pinned == b.Pinned:
+			return 0
+		case a.Pinned:
+			return -1
+		}
+		return 1
+	})
+}
+
+// confirm asks a yes/no question on stdin; only "y" or "yes" agree.
+// printPorcelain writes one tab-separated record per task: id, done (0/1),
+// created, completed (or -) and title, with times in RFC 3339. The column
+// order is frozen; new columns may only be added before the title, which
+// stays last. Records end in a newline, with backslashes and newlines in
+// the title escaped as \\ and \n, or in a NUL with the title as is when
+// nul is set.
+func printPorcelain(ts Tasks, nul bool) error {
+	w := bufio.NewWriter(os.Stdout)
+	escape := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+	for _, t := range ts {
+		done, completed := "0", "-"
+		if t.Done {
+			done = "1"
+		}
+		if t.CompletedAt != nil {
+			completed = t.CompletedAt.Format(time.RFC3339)
+		}
+		title, end := escape.Replace(t.Title), "\n"
+		if nul {
+			title, end = t.Title, "\x00"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s%s", t.ID, done, t.CreatedAt.Format(time.RFC3339), completed, title, end)
+	}
+	return w.Flush()
+}
+
+func confirm(question string) (bool, error) {
+	fmt.Printf("%s [y/N] ", question)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err == io.EOF {
+		fmt.Println()
+	} else if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
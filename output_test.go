@@ -0,0 +1,112 @@
+// output_test.go
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestListColor checks that list prints exactly the plain text when color
+// is off, through NO_COLOR, --color never or stdout not being a terminal,
+// and only paints it when forced.
+func TestListColor(t *testing.T) {
+	testEnv(t)
+	for _, args := range [][]string{
+		{"add", "Overdue", "--due", "2020-01-02"},
+		{"add", "Urgent", "-p", "1"},
+		{"add", "Finished"},
+		{"do", "3"},
+	} {
+		if code, _ := runTodo(t, args...); code != exitOK {
+			t.Fatalf("todo %s exited %d", strings.Join(args, " "), code)
+		}
+	}
+	list := []string{"list", "--all", "--absolute"}
+	_, plain := runTodo(t, append(list, "--color", "never")...)
+	if plain == "" || strings.Contains(plain, "\x1b[") {
+		t.Fatalf("--color never printed %q", plain)
+	}
+	tests := []struct {
+		name    string
+		noColor string
+		args    []string
+		painted bool
+	}{
+		// the captured stdout is a pipe, not a terminal
+		{name: "not a terminal", args: list},
+		{name: "NO_COLOR", noColor: "1", args: list},
+		{name: "NO_COLOR with --color auto", noColor: "1", args: append(list, "--color", "auto")},
+		{name: "--color always", args: append(list, "--color", "always"), painted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			code, out := runTodo(t, tt.args...)
+			if code != exitOK {
+				t.Fatalf("todo %s exited %d", strings.Join(tt.args, " "), code)
+			}
+			if tt.painted {
+				if !strings.Contains(out, "\x1b[") {
+					t.Fatalf("no color in %q", out)
+				}
+				return
+			}
+			if out != plain {
+				t.Errorf("output changed with color off:\n%s\nwant:\n%s", out, plain)
+			}
+		})
+	}
+}
+
+func TestPaint(t *testing.T) {
+	defer func() { colorize = false }()
+	colorize = false
+	if got := paint("x", ansiRed); got != "x" {
+		t.Errorf("paint without color = %q, want %q", got, "x")
+	}
+	colorize = true
+	if got, want := paint("x", ansiRed, ansiBold), "\x1b[31;1mx\x1b[0m"; got != want {
+		t.Errorf("paint = %q, want %q", got, want)
+	}
+	if got := paint("x"); got != "x" {
+		t.Errorf("paint without codes = %q, want %q", got, "x")
+	}
+}
+
+// TestDevNullIsNotATerminal runs the commands that ask before acting with
+// stdin on /dev/null, a character device but no terminal, and checks that
+// they refuse instead of prompting.
+func TestDevNullIsNotATerminal(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	if isTerminal(devNull) {
+		t.Fatalf("%s is reported as a terminal", os.DevNull)
+	}
+	testEnv(t)
+	if code, _ := runTodo(t, "add", "Pay rent", "--tag", "home"); code != exitOK {
+		t.Fatalf("add exited %d", code)
+	}
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"add", "Pay rent"}, "not adding a duplicate; give --dup"},
+		{[]string{"clear"}, "refusing to clear without --force"},
+		{[]string{"tags", "rm", "home"}, "refusing to remove a tag without --force"},
+	} {
+		cmd := todoCommand(tt.args...)
+		cmd.Stdin = devNull
+		out, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(out), tt.want) {
+			t.Errorf("todo %s with stdin on %s: %v\n%s", strings.Join(tt.args, " "), os.DevNull, err, out)
+		}
+	}
+	resetState()
+	if ts, err := loadTasks(); err != nil || len(ts) != 1 {
+		t.Errorf("list is %+v, %v; want the one task", ts, err)
+	}
+}
@@ -0,0 +1,134 @@
+// pick.go
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// pickIDs lets the user choose pending tasks for do --pick and rm --pick,
+// through fzf when it is installed and stdout is a terminal, or from a
+// numbered list otherwise. Cancelling returns no IDs and changes nothing.
+func pickIDs(verb string) (idList, error) {
+	l := idList{ranged: map[int64]bool{}}
+	ts, err := loadTasks()
+	if err != nil {
+		return l, err
+	}
+	pending := ts.Filter(func(t Task) bool { return !t.Done })
+	if len(pending) == 0 {
+		say("No pending tasks.\n")
+		return l, nil
+	}
+	sortForDisplay(pending)
+	if fzf, err := exec.LookPath("fzf"); err == nil && isTerminal(os.Stdout) {
+		l.ids, err = pickFzf(fzf, pending, verb)
+	} else {
+		l.ids, err = pickPrompt(pending, verb)
+	}
+	if err == nil && len(l.ids) == 0 {
+		say("Nothing picked.\n")
+	}
+	return l, err
+}
+
+// pickFzf pipes the tasks through fzf with multi-select and reads the IDs
+// back from the chosen lines. fzf exits with 1 when nothing matched and 130
+// when cancelled; both pick nothing.
+func pickFzf(fzf string, ts Tasks, verb string) ([]int64, error) {
+	var in strings.Builder
+	for _, t := range ts {
+		fmt.Fprintln(&in, taskLine(t))
+	}
+	cmd := exec.Command(fzf, "--multi", "--prompt", verb+"> ")
+	cmd.Stdin, cmd.Stderr = strings.NewReader(in.String()), os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && (ee.ExitCode() == 1 || ee.ExitCode() == 130) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("running fzf: %v", err)
+	}
+	var ids []int64
+	for _, line := range strings.Split(string(out), "\n") {
+		idText, _, ok := strings.Cut(strings.TrimSpace(line), ")")
+		if !ok {
+			continue
+		}
+		if id, err := strconv.ParseInt(idText, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// pickPrompt numbers the tasks and reads selections such as "1 3 5-7". A
+// selection that doesn't parse is asked for again; an empty line or end of
+// input picks nothing.
+func pickPrompt(ts Tasks, verb string) ([]int64, error) {
+	w := len(strconv.Itoa(len(ts)))
+	for i, t := range ts {
+		fmt.Printf("%*d. %s%s\n", w, i+1, priorityMarker(t.Priority), t.Title)
+	}
+	in := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Tasks to %s (numbers or ranges, empty to cancel): ", verb)
+		line, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if err == io.EOF {
+			fmt.Println()
+		}
+		if strings.TrimSpace(line) == "" {
+			return nil, nil
+		}
+		picked, perr := parseSelection(line, len(ts))
+		if perr == nil {
+			ids := make([]int64, len(picked))
+			for i, n := range picked {
+				ids[i] = ts[n-1].ID
+			}
+			return ids, nil
+		}
+		fmt.Fprintln(os.Stderr, perr)
+		if err == io.EOF {
+			return nil, nil
+		}
+	}
+}
+
+// parseSelection parses list numbers and ranges between 1 and n, dropping
+// repeats.
+func parseSelection(line string, n int) ([]int, error) {
+	var picked []int
+	seen := map[int]bool{}
+	for _, f := range strings.Fields(line) {
+		lo, hi, ranged := strings.Cut(f, "-")
+		a, err := strconv.Atoi(lo)
+		b := a
+		if err == nil && ranged {
+			b, err = strconv.Atoi(hi)
+		}
+		if err != nil || a > b {
+			return nil, fmt.Errorf("invalid selection %q", f)
+		}
+		if a < 1 || b > n {
+			return nil, fmt.Errorf("no task numbered %s; pick from 1 to %d", f, n)
+		}
+		for k := a; k <= b; k++ {
+			if !seen[k] {
+				seen[k] = true
+				picked = append(picked, k)
+			}
+		}
+	}
+	return picked, nil
+}
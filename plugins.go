@@ -0,0 +1,81 @@
+// plugins.go
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// pluginPrefix starts the name of an executable on PATH that todo runs as
+// a command, as git does: todo review runs todo-review.
+const pluginPrefix = "todo-"
+
+// findPlugin looks on PATH for the plugin that provides the command name.
+// Built-in commands are looked up first, so a plugin can't replace one.
+func findPlugin(name string) (string, bool) {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	return path, err == nil
+}
+
+// runPlugin runs a plugin with the rest of the command line and the
+// terminal, telling it in TODO_FILE and TODO_BACKEND where the current list
+// is, so that it reads the same tasks and todo commands it runs do too.
+// Its exit status becomes todo's.
+func runPlugin(path string, args []string) error {
+	file, err := tasksFilePath()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	// TODO_LIST would win over TODO_FILE; the list it names is in file
+	cmd.Env = append(os.Environ(), "TODO_FILE="+file, "TODO_LIST=", "TODO_BACKEND="+backend())
+	err = cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		// killed by a signal
+		if exit.ExitCode() < 0 {
+			return exitStatus(exitError)
+		}
+		return exitStatus(exit.ExitCode())
+	}
+	return err
+}
+
+// pluginCommands lists the commands that plugins on PATH provide, leaving
+// out the ones a built-in command shadows or an earlier directory on PATH
+// already provides.
+func pluginCommands() []string {
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), pluginPrefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if !ok || name == "" || slices.Contains(names, name) {
+				continue
+			}
+			if _, builtin := findCommand(name); builtin {
+				continue
+			}
+			if _, found := findPlugin(name); found {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
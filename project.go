@@ -0,0 +1,210 @@
+// project.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// projectFileName is the list a directory keeps of its own. Commands run
+// in the directory, or below it, use it instead of the list in the data
+// directory, as git finds .git.
+const projectFileName = ".todo.json"
+
+// projectStateName is the directory beside a project file that holds what
+// todo keeps for it: the lock, the undo snapshot, the archive, trash,
+// tombstones and backups. init keeps it out of git. It isn't .todo, which in
+// the home directory is the old data directory that dataDir moves away.
+const projectStateName = ".todo.d"
+
+// globalOnly, set by --global, ignores project files.
+var globalOnly bool
+
+var (
+	projectSearched bool
+	projectPath     string
+)
+
+// projectFile returns the project file in the working directory or the
+// nearest one above it, or "" when there is none or --global is given.
+// The search stops below the home directory, where the user's own list
+// lives, or at the root, and is made once.
+func projectFile() string {
+	if globalOnly {
+		return ""
+	}
+	if projectSearched {
+		return projectPath
+	}
+	projectSearched = true
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	home, _ := os.UserHomeDir()
+	for dir != home {
+		path := filepath.Join(dir, projectFileName)
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			projectPath = path
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return ""
+}
+
+// projectStateDir returns the state directory of the list in path, or ""
+// when it isn't a project file. It is created when missing, as it is in a
+// fresh clone of the project.
+func projectStateDir(path string) string {
+	if filepath.Base(path) != projectFileName {
+		return ""
+	}
+	dir := filepath.Join(filepath.Dir(path), projectStateName)
+	_ = os.MkdirAll(dir, 0o755) // opening a file in it reports a failure
+	return dir
+}
+
+// sideFile is the path of a file kept for the list in path, like its lock:
+// tasks.json.lock beside tasks.json, or .todo.d/lock for a project.
+func sideFile(path, name string) string {
+	if dir := projectStateDir(path); dir != "" {
+		return filepath.Join(dir, name)
+	}
+	return path + "." + name
+}
+
+// usingProjectFile reports whether the current list is a project file.
+func usingProjectFile() bool {
+	_, source, err := resolveTasksFile()
+	return err == nil && source == "project file"
+}
+
+// cmdInit starts a project list in the working directory, which can't be
+// the home directory: projectFile doesn't look there.
+func cmdInit(args []string) error {
+	_ = args
+	if len(args) > 0 {
+		return usageError("init")
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if home, err := os.UserHomeDir(); err == nil && dir == home {
+		return usageErrorf("not starting a project list in your home directory; your own list is used there")
+	}
+	path := filepath.Join(dir, projectFileName)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if err := writeTasksFile(path, Tasks{}); err != nil {
+		return err
+	}
+	say("Created %s; todo uses it in %s and below (--global for your own list)\n", path, dir)
+	added, err := ignoreStateDir(dir)
+	if err != nil {
+		return err
+	}
+	if added {
+		say("Added %s/ to %s\n", projectStateName, filepath.Join(dir, ".gitignore"))
+	}
+	return nil
+}
+
+// ignoreStateDir adds the state directory to the .gitignore in dir,
+// creating the file if need be, and reports whether it had to.
+func ignoreStateDir(dir string) (bool, error) {
+	path := filepath.Join(dir, ".gitignore")
+	line := "/" + projectStateName + "/"
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	for l := range strings.Lines(string(b)) {
+		switch strings.TrimSpace(l) {
+		case line, projectStateName, projectStateName + "/", "/" + projectStateName:
+			return false, nil
+		}
+	}
+	if len(b) > 0 && !strings.HasSuffix(string(b), "\n") {
+		line = "\n" + line
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return false, err
+	}
+	_, err = fmt.Fprintln(f, line)
+	return true, errors.Join(err, f.Close())
+}
+
+// cmdEnv prints where todo keeps things, or with a name just that value,
+// for scripts. list is empty when TODO_FILE or a project file, rather than
+// a list name, chose the file.
+func cmdEnv(args []string) error {
+	_ = args
+	if len(args) > 1 {
+		return usageError("env")
+	}
+	file, source, err := resolveTasksFile()
+	if err != nil {
+		return err
+	}
+	store, err := dataFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := dataDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := configFilePath()
+	if err != nil {
+		return err
+	}
+	hooks, err := hooksDir()
+	if err != nil {
+		return err
+	}
+	list := ""
+	switch source {
+	case "--list", "TODO_LIST":
+		list = activeList()
+	case "default list":
+		list = currentDefaultList()
+	}
+	// dataDir falls back to ~/.todo when it couldn't be moved
+	if legacy, err := legacyDataDir(); err == nil && data == legacy && list != "" {
+		source += ", in the old ~/.todo"
+	}
+	vars := []struct{ name, value string }{
+		{"file", file},
+		{"source", source},
+		{"list", list},
+		{"backend", backend()},
+		{"store", store},
+		{"data", data},
+		{"config", cfg},
+		{"hooks", hooks},
+	}
+	for _, v := range vars {
+		switch {
+		case len(args) == 0:
+			fmt.Printf("%-8s %s\n", v.name, v.value)
+		case args[0] == v.name:
+			fmt.Println(v.value)
+			return nil
+		}
+	}
+	if len(args) > 0 {
+		return usageErrorf("unknown name %q: use file, source, list, backend, store, data, config or hooks", args[0])
+	}
+	return nil
+}
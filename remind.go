@@ -0,0 +1,128 @@
+// remind.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// remindRetry is how long the daemon waits before trying a notification
+// that failed again.
+const remindRetry = time.Minute
+
+// remindJump is how far the wall clock may drift from the monotonic one
+// between two looks before the daemon logs that the clock changed or the
+// machine slept.
+const remindJump = time.Minute
+
+var remindFlags = []flagDef{boolFlag("daemon"), valueFlag("within", "w"), valueFlag("log"), boolFlag("stdout")}
+
+// cmdRemind stays running and sends each notification notify would when
+// its time comes, until SIGTERM or Ctrl-C. Rather than sleeping until the
+// next reminder, which a suspended machine or a changed clock would make
+// late, it looks at the wall clock and the list's files every second, and
+// works the schedule out again whenever the files change.
+func cmdRemind(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, remindFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 || !ca.has("daemon") {
+		return usageError("remind")
+	}
+	window, err := notifyWindow(ca)
+	if err != nil {
+		return err
+	}
+	paths, err := watchedPaths()
+	if err != nil {
+		return err
+	}
+	logf := func(format string, args ...any) {}
+	if ca.has("log") {
+		f, err := os.OpenFile(ca.value("log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		logf = func(format string, args ...any) {
+			fmt.Fprintf(f, "%s "+format+"\n", append([]any{time.Now().Format("2006-01-02 15:04:05")}, args...)...)
+		}
+	}
+	warnf := func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+		logf(format, args...)
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	tick := time.NewTicker(watchInterval)
+	defer tick.Stop()
+
+	logf("started for %s, notifying %s ahead", paths[0], window)
+	send := notifySender(ca)
+	var (
+		last        = ""
+		ts          Tasks
+		next, retry time.Time
+		wall, mono  = time.Now().Round(0), time.Now()
+	)
+	for {
+		now := time.Now()
+		due := !next.IsZero() && !now.Before(next) || !retry.IsZero() && !now.Before(retry)
+		// Round(0) drops the monotonic reading, leaving the wall clock
+		if jump := now.Round(0).Sub(wall) - now.Sub(mono); jump.Abs() > remindJump {
+			logf("clock moved by %s, or the machine slept; checking again", jump.Round(time.Second))
+			due = true
+		}
+		wall, mono = now.Round(0), now
+		if sig := filesSignature(paths); sig != last {
+			last = sig
+			loaded, err := loadTasks()
+			if err != nil {
+				// a file caught halfway through a save is read again on
+				// the next change
+				warnf("could not read tasks: %v", err)
+			} else {
+				ts, due = loaded, true
+				if ts == nil {
+					ts = Tasks{}
+				}
+				logf("read %d tasks", len(ts))
+			}
+		}
+		// without a list read yet, nothing is known to be due
+		if due && ts != nil {
+			notices, upcoming, err := notifyDue(ts, window, now, send)
+			if err != nil {
+				warnf("%v", err)
+			}
+			retry = time.Time{}
+			for _, n := range notices {
+				if n.err != nil {
+					warnf("could not notify about task %d: %v", n.t.ID, n.err)
+					retry = now.Add(remindRetry)
+				} else {
+					logf("notified about task %d (%s): %s", n.t.ID, n.t.Title, n.body)
+				}
+			}
+			if !upcoming.Equal(next) {
+				if next = upcoming; next.IsZero() {
+					logf("no reminders to come")
+				} else {
+					logf("next reminder at %s", next.Format("2006-01-02 15:04:05"))
+				}
+			}
+		}
+		select {
+		case sig := <-stop:
+			logf("stopping on %v", sig)
+			return nil
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,305 @@
+// remotesync.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// remote talks to another instance's `todo serve`.
+type remote struct {
+	url, token string
+	client     *http.Client
+}
+
+// call sends a request with body encoded as JSON, if there is one, and
+// decodes the reply onto out.
+func (r *remote) call(method, path string, body, out any) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(r.url, "/")+path, &buf)
+	if err != nil {
+		return usageErrorf("invalid remote %q: %v", r.url, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var e struct{ Error string }
+		if json.NewDecoder(resp.Body).Decode(&e) != nil || e.Error == "" {
+			e.Error = resp.Status
+		}
+		return fmt.Errorf("remote %s %s: %s", method, path, e.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// syncPlan is what a remote sync changes on each side.
+type syncPlan struct {
+	// local is the list after the merge; changed reports whether it
+	// differs from the one loaded
+	local   Tasks
+	changed bool
+	// trash holds local tasks deleted on the remote
+	trash Tasks
+	// puts are stored on the remote under their IDs there, in an order
+	// that sends parents and dependencies first
+	puts []Task
+	// deletes are remote IDs deleted here
+	deletes []int64
+	lines   []syncLine
+}
+
+// syncLine describes one change, as "<verb> <text>".
+type syncLine struct{ verb, text string }
+
+// pastTense is how each verb reads once the change is made.
+var pastTense = map[string]string{"add": "Added", "update": "Updated", "remove": "Removed", "send": "Sent"}
+
+func (p *syncPlan) note(verb, text string) {
+	p.lines = append(p.lines, syncLine{verb, text})
+}
+
+// syncRemote merges the current list with a remote one both ways. A task on
+// one side only is copied over, unless the other side deleted it after its
+// last change; a task on both sides takes the version changed last.
+func syncRemote(r *remote, dryRun bool) error {
+	local, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	localGone, err := readTombstones()
+	if err != nil {
+		return err
+	}
+	var theirs Tasks
+	var theirGone []tombstone
+	if err := r.call("GET", "/tasks", nil, &theirs); err != nil {
+		return err
+	}
+	if err := r.call("GET", "/tombstones", nil, &theirGone); err != nil {
+		return err
+	}
+	// a remote from before UIDs existed sends tasks without them
+	theirs.AssignUIDs()
+	p := planSync(local, theirs, localGone, theirGone)
+	if len(p.lines) == 0 {
+		say("Nothing to sync.\n")
+		return nil
+	}
+	for _, l := range p.lines {
+		if dryRun {
+			fmt.Printf("Would %s %s\n", l.verb, l.text)
+		} else {
+			say("%s %s\n", pastTense[l.verb], l.text)
+		}
+	}
+	if dryRun {
+		return nil
+	}
+	// the remote first: if it fails part way, running sync again finishes
+	// the job since nothing here has changed yet
+	for _, t := range p.puts {
+		if err := r.call("PUT", fmt.Sprintf("/tasks/%d", t.ID), t, nil); err != nil {
+			return err
+		}
+	}
+	for _, id := range p.deletes {
+		if err := r.call("DELETE", fmt.Sprintf("/tasks/%d", id), nil, nil); err != nil {
+			return err
+		}
+	}
+	if !p.changed {
+		return nil
+	}
+	if len(p.trash) > 0 {
+		if err := moveToTrash(p.trash); err != nil {
+			return err
+		}
+	}
+	return saveTasks(p.local)
+}
+
+func planSync(local, theirs Tasks, localGone, theirGone []tombstone) syncPlan {
+	var p syncPlan
+	goneHere, goneThere := tombstoneTimes(localGone), tombstoneTimes(theirGone)
+	localByKey, theirByKey := indexByKey(local), indexByKey(theirs)
+
+	// IDs every task will have on each side, for translating links
+	localID, theirID := map[string]int64{}, map[string]int64{}
+	for _, t := range local {
+		localID[taskKey(t)] = t.ID
+	}
+	for _, t := range theirs {
+		theirID[taskKey(t)] = t.ID
+	}
+	nextLocal, nextTheirs := local.NextID(), theirs.NextID()
+
+	var pull, push []Task
+	deleteHere := map[string]bool{}
+	for _, t := range theirs {
+		key := taskKey(t)
+		if i, ok := localByKey[key]; ok {
+			if modifiedAt(t).After(modifiedAt(local[i])) {
+				pull = append(pull, t)
+				p.note("update", fmt.Sprintf("%d from the remote: %s", local[i].ID, t.Title))
+			}
+			continue
+		}
+		if gone, ok := goneHere[key]; ok && !modifiedAt(t).After(gone) {
+			p.deletes = append(p.deletes, t.ID)
+			p.note("remove", fmt.Sprintf("%d on the remote, deleted here: %s", t.ID, t.Title))
+			continue
+		}
+		localID[key] = nextLocal
+		nextLocal++
+		pull = append(pull, t)
+		p.note("add", fmt.Sprintf("%d from the remote as %d: %s", t.ID, localID[key], t.Title))
+	}
+	for _, t := range local {
+		key := taskKey(t)
+		if j, ok := theirByKey[key]; ok {
+			if modifiedAt(t).After(modifiedAt(theirs[j])) {
+				push = append(push, t)
+				p.note("update", fmt.Sprintf("%d on the remote from %d: %s", theirs[j].ID, t.ID, t.Title))
+			}
+			continue
+		}
+		if gone, ok := goneThere[key]; ok && !modifiedAt(t).After(gone) {
+			deleteHere[key] = true
+			p.note("remove", fmt.Sprintf("%d, deleted on the remote: %s", t.ID, t.Title))
+			continue
+		}
+		theirID[key] = nextTheirs
+		nextTheirs++
+		push = append(push, t)
+		p.note("send", fmt.Sprintf("%d to the remote as %d: %s", t.ID, theirID[key], t.Title))
+	}
+
+	localKeys, theirKeys := keysByID(local), keysByID(theirs)
+	p.local = make(Tasks, 0, len(local)+len(pull))
+	for _, t := range local {
+		if deleteHere[taskKey(t)] {
+			p.trash = append(p.trash, t)
+			continue
+		}
+		p.local = append(p.local, t)
+	}
+	for _, t := range pull {
+		t = relink(t, theirKeys, localID)
+		if i := p.local.Index(t.ID); i != -1 {
+			p.local[i] = t
+		} else {
+			p.local = append(p.local, t)
+		}
+	}
+	detachRemoved(p.local, p.trash)
+	p.changed = len(pull) > 0 || len(p.trash) > 0
+	for _, t := range sendOrder(push) {
+		p.puts = append(p.puts, relink(t, localKeys, theirID))
+	}
+	return p
+}
+
+func indexByKey(ts Tasks) map[string]int {
+	m := make(map[string]int, len(ts))
+	for i, t := range ts {
+		m[taskKey(t)] = i
+	}
+	return m
+}
+
+func keysByID(ts Tasks) map[int64]string {
+	m := make(map[int64]string, len(ts))
+	for _, t := range ts {
+		m[t.ID] = taskKey(t)
+	}
+	return m
+}
+
+func tombstoneTimes(tbs []tombstone) map[string]time.Time {
+	m := make(map[string]time.Time, len(tbs))
+	for _, tb := range tbs {
+		m[tb.key()] = tb.DeletedAt
+	}
+	return m
+}
+
+// relink gives t its ID on the other side and translates its parent and
+// dependencies there, dropping links to tasks the other side won't have.
+func relink(t Task, keys map[int64]string, ids map[string]int64) Task {
+	t.ID = ids[taskKey(t)]
+	if t.ParentID != nil {
+		if id, ok := ids[keys[*t.ParentID]]; ok {
+			t.ParentID = &id
+		} else {
+			t.ParentID = nil
+		}
+	}
+	var deps []int64
+	for _, d := range t.DependsOn {
+		if id, ok := ids[keys[d]]; ok {
+			deps = append(deps, id)
+		}
+	}
+	t.DependsOn = deps
+	t.Blocked = false
+	return t
+}
+
+// sendOrder sorts tasks so that each comes after the ones it links to.
+// Each PUT is saved on its own, and a save drops links to tasks that don't
+// exist yet.
+func sendOrder(ts Tasks) Tasks {
+	pending := map[int64]bool{}
+	for _, t := range ts {
+		pending[t.ID] = true
+	}
+	var out Tasks
+	for len(out) < len(ts) {
+		progress := false
+		for _, t := range ts {
+			if !pending[t.ID] {
+				continue
+			}
+			ready := t.ParentID == nil || !pending[*t.ParentID]
+			for _, d := range t.DependsOn {
+				ready = ready && !pending[d]
+			}
+			if ready {
+				out = append(out, t)
+				delete(pending, t.ID)
+				progress = true
+			}
+		}
+		if !progress {
+			// links that go round in circles: send the rest as they are
+			for _, t := range ts {
+				if pending[t.ID] {
+					out = append(out, t)
+				}
+			}
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+// renumber.go
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var renumberFlags = []flagDef{boolFlag("force", "f")}
+
+// cmdRenumber gives the tasks IDs 1 to N in the order list --all shows
+// them, in one save, and prints the IDs that changed.
+func cmdRenumber(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, renumberFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("renumber")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	shown := slices.Clone(ts)
+	sortForDisplay(shown)
+	pinnedFirst(shown)
+	newID := map[int64]int64{}
+	walkTree(shown, func(t Task, _ string) {
+		newID[t.ID] = int64(len(newID) + 1)
+	})
+	var moved []int64
+	for _, t := range shown {
+		if newID[t.ID] != t.ID {
+			moved = append(moved, t.ID)
+		}
+	}
+	if len(moved) == 0 {
+		say("IDs are already 1 to %d.\n", len(ts))
+		return nil
+	}
+	if !ca.has("force") {
+		if err := checkRenumber(len(ts)); err != nil {
+			return err
+		}
+	}
+	for i := range ts {
+		t := &ts[i]
+		t.ID = newID[t.ID]
+		// links to tasks no longer in the list map to 0, which save drops
+		if t.ParentID != nil {
+			p := newID[*t.ParentID]
+			t.ParentID = &p
+		}
+		for k, d := range t.DependsOn {
+			t.DependsOn[k] = newID[d]
+		}
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	slices.SortFunc(moved, func(a, b int64) int { return int(newID[a] - newID[b]) })
+	w := len(strconv.FormatInt(slices.Max(moved), 10))
+	for _, id := range moved {
+		fmt.Printf("%*d → %d\n", w, id, newID[id])
+	}
+	return nil
+}
+
+// checkRenumber refuses to hand out IDs 1 to n while the archive or the
+// trash holds tasks with some of them, as restore and list --archived
+// would then show two tasks under one ID.
+func checkRenumber(n int) error {
+	for _, name := range []string{"archive", "trash"} {
+		path, err := companionPath(name)
+		if err != nil {
+			return err
+		}
+		ts, err := readTasksFile(path)
+		if err != nil {
+			return err
+		}
+		var clash []int64
+		for _, t := range ts {
+			if t.ID >= 1 && t.ID <= int64(n) {
+				clash = append(clash, t.ID)
+			}
+		}
+		if len(clash) > 0 {
+			slices.Sort(clash)
+			ids := make([]string, 0, len(clash))
+			for _, id := range slices.Compact(clash) {
+				ids = append(ids, strconv.FormatInt(id, 10))
+			}
+			return fmt.Errorf("the %s holds tasks numbered %s, which renumber would give to other tasks; "+
+				"run again with --force to renumber anyway", name, strings.Join(ids, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+// serve.go
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// api serves the current list over HTTP. Every request loads and saves
+// through loadTasks and saveTasks, under the same file lock as the CLI, so
+// both see each other's changes.
+type api struct {
+	token string
+	// mu serializes requests, since the file lock only guards against
+	// other processes
+	mu sync.Mutex
+}
+
+var serveFlags = []flagDef{valueFlag("addr"), valueFlag("token")}
+
+func cmdServe(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, serveFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("serve")
+	}
+	addr := ca.value("addr")
+	if addr == "" {
+		addr = ":8080"
+	}
+	a := &api{token: ca.value("token")}
+	say("Serving tasks on %s\n", addr)
+	return http.ListenAndServe(addr, a.handler())
+}
+
+func (a *api) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", a.read(a.list))
+	mux.HandleFunc("POST /tasks", a.write(a.create))
+	mux.HandleFunc("GET /tasks/{id}", a.read(a.get))
+	mux.HandleFunc("PATCH /tasks/{id}", a.write(a.update))
+	mux.HandleFunc("DELETE /tasks/{id}", a.write(a.remove))
+	mux.HandleFunc("POST /tasks/{id}/complete", a.write(a.complete))
+	mux.HandleFunc("PUT /tasks/{id}", a.write(a.put))
+	mux.HandleFunc("GET /tombstones", a.read(a.tombstones))
+	return a.authorize(mux)
+}
+
+// authorize requires "Authorization: Bearer <token>" when a token is set.
+func (a *api) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiFunc handles a request. It returns the status and the value to send
+// as JSON, or an error, whose status follows its exit code.
+type apiFunc func(r *http.Request) (int, any, error)
+
+func (a *api) read(f apiFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		status, v, err := f(r)
+		respond(w, status, v, err)
+	}
+}
+
+func (a *api) write(f apiFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		unlock, err := lockTasks()
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		defer unlock()
+		status, v, err := f(r)
+		respond(w, status, v, err)
+		if err == nil && config.GitSync {
+			autoCommit([]string{"serve", r.Method, r.URL.Path})
+		}
+		if err == nil {
+			sendEvents("serve")
+		}
+		pendingEvents = nil
+	}
+}
+
+func respond(w http.ResponseWriter, status int, v any, err error) {
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	if v == nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// errorStatus maps an error to an HTTP status the way exitCode maps it to
+// an exit code. Anything else is a request the list's rules refuse.
+func errorStatus(err error) int {
+	switch exitCode(err) {
+	case exitUsage:
+		return http.StatusBadRequest
+	case exitNotFound:
+		return http.StatusNotFound
+	case exitData:
+		return http.StatusInternalServerError
+	}
+	return http.StatusConflict
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// decodeBody reads a JSON object from the request onto v, so fields left
+// out keep their current values.
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return usageErrorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// findTask loads the list and locates the task named in the URL.
+func findTask(r *http.Request) (Tasks, int, error) {
+	id, err := parseID(r.PathValue("id"))
+	if err != nil {
+		return nil, 0, err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return nil, 0, err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return nil, 0, notFoundErrorf("task %d not found", id)
+	}
+	return ts, i, nil
+}
+
+func (a *api) list(r *http.Request) (int, any, error) {
+	ts, err := loadTasks()
+	return http.StatusOK, ts, err
+}
+
+func (a *api) get(r *http.Request) (int, any, error) {
+	ts, i, err := findTask(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, ts[i], nil
+}
+
+func (a *api) create(r *http.Request) (int, any, error) {
+	var t Task
+	if err := decodeBody(r, &t); err != nil {
+		return 0, nil, err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return 0, nil, err
+	}
+	if t.ParentID != nil && ts.Index(*t.ParentID) == -1 {
+		return 0, nil, usageErrorf("parent task %d not found", *t.ParentID)
+	}
+	t.ID, t.UID, t.CreatedAt, t.Order = ts.NextID(), todo.NewUID(), time.Now(), nextOrder(ts)
+	if err := checkTask(&t); err != nil {
+		return 0, nil, err
+	}
+	if err := saveTasks(append(ts, t)); err != nil {
+		return 0, nil, err
+	}
+	return savedTask(http.StatusCreated, t.ID)
+}
+
+func (a *api) update(r *http.Request) (int, any, error) {
+	ts, i, err := findTask(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	t := ts[i]
+	if err := decodeBody(r, &t); err != nil {
+		return 0, nil, err
+	}
+	if t.ID != ts[i].ID {
+		return 0, nil, usageErrorf("the id of a task can't be changed")
+	}
+	if t.UID != ts[i].UID {
+		return 0, nil, usageErrorf("the uid of a task can't be changed")
+	}
+	if !t.Done {
+		t.CompletedAt = nil
+	}
+	if err := checkTask(&t); err != nil {
+		return 0, nil, err
+	}
+	ts[i] = t
+	if err := saveTasks(ts); err != nil {
+		return 0, nil, err
+	}
+	return savedTask(http.StatusOK, t.ID)
+}
+
+func (a *api) remove(r *http.Request) (int, any, error) {
+	ts, i, err := findTask(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	removed := Tasks{ts[i]}
+	ts = append(ts[:i], ts[i+1:]...)
+	detachRemoved(ts, removed)
+	if err := moveToTrash(removed); err != nil {
+		return 0, nil, err
+	}
+	if err := saveTasks(ts); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// put stores a task exactly as given under the ID in the URL, replacing the
+// task with that ID if there is one. Unlike the other calls it keeps the
+// timestamps sent, which sync relies on.
+func (a *api) put(r *http.Request) (int, any, error) {
+	id, err := parseID(r.PathValue("id"))
+	if err != nil {
+		return 0, nil, err
+	}
+	var t Task
+	if err := decodeBody(r, &t); err != nil {
+		return 0, nil, err
+	}
+	if t.CreatedAt.IsZero() {
+		return 0, nil, usageErrorf("a stored task needs created_at")
+	}
+	if t.UID == "" {
+		t.UID = todo.LegacyUID(t.CreatedAt)
+	}
+	if err := checkTask(&t); err != nil {
+		return 0, nil, err
+	}
+	t.ID = id
+	ts, err := loadTasks()
+	if err != nil {
+		return 0, nil, err
+	}
+	status := http.StatusOK
+	if i := ts.Index(id); i != -1 {
+		ts[i] = t
+	} else {
+		ts = append(ts, t)
+		status = http.StatusCreated
+	}
+	if err := saveTasks(ts); err != nil {
+		return 0, nil, err
+	}
+	return savedTask(status, id)
+}
+
+func (a *api) tombstones(r *http.Request) (int, any, error) {
+	tbs, err := readTombstones()
+	if tbs == nil {
+		tbs = []tombstone{}
+	}
+	return http.StatusOK, tbs, err
+}
+
+// complete marks a task done like `todo do` without --force, adding the
+// next occurrence of a recurring task.
+func (a *api) complete(r *http.Request) (int, any, error) {
+	ts, i, err := findTask(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if ts[i].Done {
+		return http.StatusOK, ts[i], nil
+	}
+	if err := checkCompletable(ts, ts[i].ID, nil); err != nil {
+		return 0, nil, err
+	}
+	now := time.Now()
+	ts[i].MarkDone(now)
+	id := ts[i].ID
+	if ts[i].Repeat != "" {
+		n, err := nextOccurrence(ts[i], ts.NextID(), now)
+		if err != nil {
+			return 0, nil, err
+		}
+		ts = append(ts, n)
+	}
+	if err := saveTasks(ts); err != nil {
+		return 0, nil, err
+	}
+	return savedTask(http.StatusOK, id)
+}
+
+// savedTask answers a call that changed a task with the task read back
+// from the list, so that it has the updated_at and status the save gave it.
+func savedTask(status int, id int64) (int, any, error) {
+	ts, err := loadTasks()
+	if err != nil {
+		return 0, nil, err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return 0, nil, notFoundErrorf("task %d not found", id)
+	}
+	return status, ts[i], nil
+}
+
+// checkTask validates a task sent to the API and normalizes it the way the
+// CLI does its flags.
+func checkTask(t *Task) error {
+	title, err := normalizeTitle(t.Title)
+	if err != nil {
+		return err
+	}
+	t.Title = title
+	if _, err := parsePriority(fmt.Sprint(t.Priority)); err != nil {
+		return err
+	}
+	if t.Repeat != "" {
+		if _, err := parseRepeat(t.Repeat); err != nil {
+			return err
+		}
+	}
+	t.Tags = normalizeTags(t.Tags)
+	if t.Done && t.CompletedAt == nil {
+		now := time.Now()
+		t.CompletedAt = &now
+	}
+	return nil
+}
@@ -0,0 +1,429 @@
+// shell.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// shellHistoryLines is how many lines of todo shell history are kept.
+const shellHistoryLines = 500
+
+// shellUncached are the commands that reach a list's files other than
+// through its store: they replace, re-encrypt or merge them, or hand them
+// to other processes. The shell writes out what it holds before running
+// one and reads the list again afterwards.
+var shellUncached = map[string]bool{
+	"undo": true, "restore-backup": true, "encrypt": true, "decrypt": true,
+	"migrate": true, "compact": true, "sync": true, "serve": true,
+}
+
+// cachedStore keeps the list it last loaded or saved in memory. With
+// deferred set, saves only change that copy until the shell flushes it.
+// Otherwise the copy is read again when another process has changed the
+// file since, so that its changes aren't saved over.
+type cachedStore struct {
+	taskStore
+	path     string
+	mem      todo.MemStore
+	loaded   bool
+	deferred bool
+	dirty    bool
+	// stamp is the backend's file as of the last load or save
+	stamp fileStamp
+}
+
+// fileStamp is what tells a change to a file: its size and modification
+// time.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func stampOf(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{fi.Size(), fi.ModTime()}
+}
+
+func (a fileStamp) same(b fileStamp) bool {
+	return a.size == b.size && a.modTime.Equal(b.modTime)
+}
+
+func (s *cachedStore) Load() (Tasks, error) {
+	if s.loaded && !s.deferred && !stampOf(backendFile(s.path)).same(s.stamp) {
+		s.loaded = false
+	}
+	if !s.loaded {
+		stamp := stampOf(backendFile(s.path))
+		ts, err := s.taskStore.Load()
+		if err != nil {
+			return nil, err
+		}
+		s.mem.Tasks, s.loaded, s.stamp = ts, true, stamp
+	}
+	return s.mem.Load()
+}
+
+func (s *cachedStore) Save(ts Tasks) error {
+	if !s.deferred {
+		if err := s.taskStore.Save(ts); err != nil {
+			return err
+		}
+		s.stamp = stampOf(backendFile(s.path))
+	}
+	s.dirty = s.deferred
+	s.loaded = true
+	return s.mem.Save(ts)
+}
+
+// shell is the state of a todo shell session: the store of the list last
+// used, and whether the next command must go around it.
+type shell struct {
+	saveOnExit bool
+	cache      *cachedStore
+	bypass     bool
+}
+
+// open is openStore for the shell. Moving to another list writes out and
+// forgets the one held so far.
+func (sh *shell) open(path string) taskStore {
+	if sh.bypass {
+		return listStore(path)
+	}
+	if sh.cache != nil && sh.cache.path == path {
+		return sh.cache
+	}
+	if err := sh.flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	sh.drop()
+	sh.cache = &cachedStore{taskStore: listStore(path), path: path, deferred: sh.saveOnExit}
+	return sh.cache
+}
+
+// flush saves the changes --save-on-exit has held back.
+func (sh *shell) flush() error {
+	c := sh.cache
+	if c == nil || !c.dirty {
+		return nil
+	}
+	unlock, err := lockFile(sideFile(c.path, "lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := c.taskStore.Save(c.mem.Tasks); err != nil {
+		return dataError(err)
+	}
+	c.dirty = false
+	return nil
+}
+
+func (sh *shell) drop() {
+	if sh.cache != nil {
+		closeStore(sh.cache.taskStore)
+		sh.cache = nil
+	}
+}
+
+var shellFlags = []flagDef{boolFlag("save-on-exit")}
+
+func cmdShell(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, shellFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("shell")
+	}
+	sh := &shell{saveOnExit: ca.has("save-on-exit")}
+	openStore, store = sh.open, nil
+	defer func() { openStore = listStore }()
+
+	var next func() (string, error)
+	var ed *lineEditor
+	if isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		ed = &lineEditor{history: readShellHistory()}
+		next = func() (string, error) { return ed.readLine("todo> ") }
+	} else {
+		sc := bufio.NewScanner(os.Stdin)
+		next = func() (string, error) {
+			if sc.Scan() {
+				return sc.Text(), nil
+			}
+			if err := sc.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+	}
+
+	// every line starts from the global flags the shell was started with
+	list, q, v, rec := listName, quiet, verbose, recoverFlag
+	reset := func() { listName, quiet, verbose, recoverFlag, store = list, q, v, rec, nil }
+	for {
+		line, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		words, err := splitWords(line)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			continue
+		}
+		if len(words) > 0 && words[0] == "todo" {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if words[0] == "quit" || words[0] == "exit" {
+			break
+		}
+		rest, _ := extractGlobalFlags(words)
+		reset()
+		c, _ := findCommand(append(rest, "")[0])
+		if c.name == "shell" {
+			fmt.Fprintln(os.Stderr, "Error: already in todo shell")
+			continue
+		}
+		if shellUncached[c.name] {
+			if err := sh.flush(); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				continue
+			}
+			sh.drop()
+			sh.bypass = true
+		}
+		run(words)
+		if sh.bypass {
+			if store != nil {
+				closeStore(store)
+			}
+			sh.bypass = false
+		}
+		reset()
+	}
+	if ed != nil {
+		writeShellHistory(ed.history)
+	}
+	dirty := sh.cache != nil && sh.cache.dirty
+	if err := sh.flush(); err != nil {
+		return err
+	}
+	sh.drop()
+	if dirty {
+		say("Saved changes.\n")
+	}
+	return nil
+}
+
+// splitWords splits a line into arguments the way a shell would in simple
+// cases: blanks separate words, single and double quotes group them, and a
+// backslash outside single quotes takes the next character literally.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, usageErrorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// lineEditor reads lines from the terminal with readline's basic keys:
+// arrows, Home and End, Ctrl-A/E/B/F/K/U/W, and up and down for history.
+type lineEditor struct {
+	history []string
+	keys    []string // read ahead of the current line, as when pasting
+}
+
+// readLine reads one line. Ctrl-C drops what was typed and returns an
+// empty line; Ctrl-D on an empty line returns io.EOF.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	restore, err := rawTerminal()
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	var text []rune
+	pos, hist, draft := 0, len(e.history), ""
+	fmt.Print(prompt)
+	buf := make([]byte, 256)
+	for {
+		if len(e.keys) == 0 {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return "", err
+			}
+			e.keys = parseKeys(buf[:n])
+			continue
+		}
+		k := e.keys[0]
+		e.keys = e.keys[1:]
+		switch k {
+		case "enter":
+			fmt.Print("\r\n")
+			line := string(text)
+			if strings.TrimSpace(line) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+				e.history = append(e.history, line)
+			}
+			return line, nil
+		case "ctrl-c":
+			fmt.Print("^C\r\n")
+			return "", nil
+		case "ctrl-d":
+			if len(text) == 0 {
+				fmt.Print("\r\n")
+				return "", io.EOF
+			}
+			fallthrough
+		case "delete":
+			if pos < len(text) {
+				text = slices.Delete(text, pos, pos+1)
+			}
+		case "backspace":
+			if pos > 0 {
+				text = slices.Delete(text, pos-1, pos)
+				pos--
+			}
+		case "left", "ctrl-b":
+			pos = max(0, pos-1)
+		case "right", "ctrl-f":
+			pos = min(len(text), pos+1)
+		case "home", "ctrl-a":
+			pos = 0
+		case "end", "ctrl-e":
+			pos = len(text)
+		case "ctrl-k":
+			text = text[:pos]
+		case "ctrl-u":
+			text, pos = slices.Clone(text[pos:]), 0
+		case "ctrl-w":
+			start := pos
+			for start > 0 && unicode.IsSpace(text[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(text[start-1]) {
+				start--
+			}
+			text, pos = slices.Delete(text, start, pos), start
+		case "up":
+			if hist > 0 {
+				if hist == len(e.history) {
+					draft = string(text)
+				}
+				hist--
+				text = []rune(e.history[hist])
+				pos = len(text)
+			}
+		case "down":
+			if hist < len(e.history) {
+				hist++
+				if hist == len(e.history) {
+					text = []rune(draft)
+				} else {
+					text = []rune(e.history[hist])
+				}
+				pos = len(text)
+			}
+		default:
+			if utf8.RuneCountInString(k) == 1 {
+				text = slices.Insert(text, pos, []rune(k)...)
+				pos++
+			}
+		}
+		fmt.Printf("\r%s%s\x1b[K", prompt, string(text))
+		if back := len(text) - pos; back > 0 {
+			fmt.Printf("\x1b[%dD", back)
+		}
+	}
+}
+
+func shellHistoryPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "shell_history"), nil
+}
+
+// readShellHistory loads the lines typed in earlier sessions. History is a
+// convenience, so a missing or unreadable file is just an empty one.
+func readShellHistory() []string {
+	path, err := shellHistoryPath()
+	if err != nil {
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+}
+
+func writeShellHistory(lines []string) {
+	path, err := shellHistoryPath()
+	if err != nil || len(lines) == 0 {
+		return
+	}
+	if len(lines) > shellHistoryLines {
+		lines = lines[len(lines)-shellHistoryLines:]
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: could not save shell history:", err)
+	}
+}
@@ -0,0 +1,402 @@
+// stats.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type tagStats struct {
+	Pending   int `json:"pending"`
+	Completed int `json:"completed"`
+}
+
+type taskStats struct {
+	Total             int     `json:"total"`
+	Pending           int     `json:"pending"`
+	Completed         int     `json:"completed"`
+	CompletedThisWeek int     `json:"completed_this_week"`
+	CompletedToday    int     `json:"completed_today"`
+	CompletionRate    float64 `json:"completion_rate"`
+	// AvgCompletionSeconds is nil when no task has been completed.
+	AvgCompletionSeconds *float64            `json:"avg_completion_seconds"`
+	Tags                 map[string]tagStats `json:"tags,omitempty"`
+}
+
+func computeStats(ts Tasks, now time.Time) taskStats {
+	s := taskStats{Total: len(ts), Tags: map[string]tagStats{}}
+	today, week := startOfDay(now), startOfWeek(now)
+	var elapsed time.Duration
+	timed := 0
+	for _, t := range ts {
+		for _, tag := range t.Tags {
+			c := s.Tags[tag]
+			if t.Done {
+				c.Completed++
+			} else {
+				c.Pending++
+			}
+			s.Tags[tag] = c
+		}
+		if !t.Done {
+			s.Pending++
+			continue
+		}
+		s.Completed++
+		if t.CompletedAt == nil {
+			continue
+		}
+		if !t.CompletedAt.Before(week) {
+			s.CompletedThisWeek++
+		}
+		if !t.CompletedAt.Before(today) {
+			s.CompletedToday++
+		}
+		elapsed += t.CompletedAt.Sub(t.CreatedAt)
+		timed++
+	}
+	if s.Total > 0 {
+		s.CompletionRate = float64(s.Completed) / float64(s.Total)
+	}
+	if timed > 0 {
+		avg := elapsed.Seconds() / float64(timed)
+		s.AvgCompletionSeconds = &avg
+	}
+	return s
+}
+
+var statsFlags = []flagDef{jsonFlag}
+
+func cmdStats(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, statsFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("stats")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	s := computeStats(ts, time.Now())
+	if ca.has("json") {
+		b, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	fmt.Printf("Total:               %d\n", s.Total)
+	fmt.Printf("Pending:             %d\n", s.Pending)
+	fmt.Printf("Completed:           %d\n", s.Completed)
+	fmt.Printf("Completed this week: %d\n", s.CompletedThisWeek)
+	fmt.Printf("Completed today:     %d\n", s.CompletedToday)
+	if s.Total > 0 {
+		fmt.Printf("Completion rate:     %.0f%%\n", s.CompletionRate*100)
+	} else {
+		fmt.Println("Completion rate:     n/a")
+	}
+	if s.AvgCompletionSeconds != nil {
+		fmt.Printf("Avg time to finish:  %s\n", formatDuration(time.Duration(*s.AvgCompletionSeconds*float64(time.Second))))
+	} else {
+		fmt.Println("Avg time to finish:  n/a")
+	}
+	if len(s.Tags) > 0 {
+		tags := make([]string, 0, len(s.Tags))
+		for tag := range s.Tags {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		fmt.Println("By tag:")
+		for _, tag := range tags {
+			fmt.Printf("  #%-16s %d pending, %d completed\n", tag, s.Tags[tag].Pending, s.Tags[tag].Completed)
+		}
+	}
+	return nil
+}
+
+// formatDuration renders a duration as days, hours and minutes, e.g. "2d 3h".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	minutes := (d - hours*time.Hour) / time.Minute
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+var reportFlags = []flagDef{valueFlag("from"), valueFlag("to"), boolFlag("today"), boolFlag("week")}
+
+func cmdReport(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, reportFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 || (ca.has("today") && ca.has("week")) {
+		return usageError("report")
+	}
+	now := time.Now()
+	from, to := now.AddDate(0, 0, -7), now
+	switch {
+	case ca.has("today"):
+		from = startOfDay(now)
+	case ca.has("week"):
+		from = startOfWeek(now)
+	}
+	if ca.has("from") {
+		if from, err = parseDate(ca.value("from")); err != nil {
+			return usageErrorf("invalid --from date %q: %v", ca.value("from"), err)
+		}
+	}
+	if ca.has("to") {
+		if to, err = parseDate(ca.value("to")); err != nil {
+			return usageErrorf("invalid --to date %q: %v", ca.value("to"), err)
+		}
+		if to.Equal(startOfDay(to)) {
+			// a bare date includes the whole day
+			to = to.AddDate(0, 0, 1)
+		}
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	done := ts.Filter(func(t Task) bool {
+		return t.Done && t.CompletedAt != nil && !t.CompletedAt.Before(from) && t.CompletedAt.Before(to)
+	})
+	if len(done) == 0 {
+		fmt.Println("No tasks completed in this period.")
+		return nil
+	}
+	sort.SliceStable(done, func(i, j int) bool { return done[i].CompletedAt.Before(*done[j].CompletedAt) })
+	for i := 0; i < len(done); {
+		day := startOfDay(*done[i].CompletedAt)
+		j := i
+		for j < len(done) && startOfDay(*done[j].CompletedAt).Equal(day) {
+			j++
+		}
+		fmt.Printf("%s (%d)\n", day.Format("Mon 2006-01-02"), j-i)
+		for _, t := range done[i:j] {
+			fmt.Printf("  %s  (%s)\n", taskLine(t), t.CompletedAt.Format("15:04"))
+		}
+		i = j
+	}
+	return nil
+}
+
+// completedHistory returns the completed tasks of the list and of its
+// archive, which together hold everything ever finished in it.
+func completedHistory() (Tasks, error) {
+	ts, err := loadTasks()
+	if err != nil {
+		return nil, err
+	}
+	path, err := companionPath("archive")
+	if err != nil {
+		return nil, err
+	}
+	archived, err := readTasksFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return append(ts, archived...).Filter(func(t Task) bool { return t.Done && t.CompletedAt != nil }), nil
+}
+
+// dayKey names the calendar day of t in loc.
+func dayKey(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
+}
+
+// completionDays counts completions per calendar day in loc.
+func completionDays(ts Tasks, loc *time.Location) map[string]int {
+	days := map[string]int{}
+	for _, t := range ts {
+		if t.CompletedAt != nil {
+			days[dayKey(*t.CompletedAt, loc)]++
+		}
+	}
+	return days
+}
+
+// currentStreak counts the days in a row, up to today in now's zone, with
+// at least one completion. Today only ends the streak once it is over, so
+// a streak that reached yesterday still counts this morning.
+func currentStreak(days map[string]int, now time.Time) int {
+	day := startOfDay(now)
+	if days[dayKey(day, now.Location())] == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	n := 0
+	for days[dayKey(day, now.Location())] > 0 {
+		n++
+		day = day.AddDate(0, 0, -1)
+	}
+	return n
+}
+
+var chartFlags = []flagDef{valueFlag("days", "d"), jsonFlag}
+
+// defaultChartDays is the window chart shows without --days.
+const defaultChartDays = 30
+
+func cmdChart(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, chartFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("chart")
+	}
+	n := defaultChartDays
+	if ca.has("days") {
+		if n, err = strconv.Atoi(ca.value("days")); err != nil || n < 1 {
+			return usageErrorf("invalid --days value %q: use a number of days, 1 or more", ca.value("days"))
+		}
+	}
+	ts, err := completedHistory()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	counts := completionDays(ts, now.Location())
+	type dayCount struct {
+		Date  string `json:"date"`
+		Count int    `json:"count"`
+	}
+	days := make([]dayCount, n)
+	total, most := 0, 0
+	start := startOfDay(now).AddDate(0, 0, 1-n)
+	for i := range days {
+		key := dayKey(start.AddDate(0, 0, i), now.Location())
+		days[i] = dayCount{key, counts[key]}
+		total += counts[key]
+		most = max(most, counts[key])
+	}
+	if ca.has("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(days)
+	}
+	cols := 80
+	if isTerminal(os.Stdout) {
+		_, cols = terminalSize()
+	}
+	// "Mon 2006-01-02 " and the count before the bar
+	width := len(strconv.Itoa(most))
+	room := max(1, cols-16-width-1)
+	for i, d := range days {
+		bar := d.Count
+		if most > room {
+			bar = (d.Count*room + most - 1) / most
+		}
+		line := fmt.Sprintf("%s %*d %s", start.AddDate(0, 0, i).Format("Mon 2006-01-02"), width, d.Count, strings.Repeat("#", bar))
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+	fmt.Printf("\n%d completed in %s. Current streak: %s.\n", total, streakDays(n), streakDays(currentStreak(counts, now)))
+	return nil
+}
+
+// longestStreak finds the most days in a row with a completion and the
+// last day of that run, the latest run winning a tie. end is zero when
+// nothing was ever completed.
+func longestStreak(days map[string]int, loc *time.Location) (n int, end time.Time) {
+	keys := make([]string, 0, len(days))
+	for k, c := range days {
+		if c > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	run := 0
+	var prev time.Time
+	for _, k := range keys {
+		day, err := time.ParseInLocation("2006-01-02", k, loc)
+		if err != nil {
+			continue
+		}
+		if run > 0 && prev.AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		if run >= n {
+			n, end = run, day
+		}
+		prev = day
+	}
+	return n, end
+}
+
+var streakFlags = []flagDef{valueFlag("per-tag", "t"), jsonFlag}
+
+func cmdStreak(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, streakFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("streak")
+	}
+	ts, err := completedHistory()
+	if err != nil {
+		return err
+	}
+	label := ""
+	if ca.has("per-tag") {
+		tag := normalizeTag(ca.value("per-tag"))
+		ts = ts.Filter(func(t Task) bool { return t.HasTag(tag) })
+		label = " for #" + tag
+	}
+	now := time.Now()
+	days := completionDays(ts, now.Location())
+	current := currentStreak(days, now)
+	longest, end := longestStreak(days, now.Location())
+	if ca.has("json") {
+		out := struct {
+			Current      int    `json:"current"`
+			Longest      int    `json:"longest"`
+			LongestEnded string `json:"longest_ended,omitempty"`
+		}{Current: current, Longest: longest}
+		if longest > 0 {
+			out.LongestEnded = end.Format("2006-01-02")
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	if longest == 0 {
+		fmt.Printf("No completed tasks%s yet.\n", label)
+		return nil
+	}
+	fmt.Printf("Current streak%s: %s\n", label, streakDays(current))
+	if current == longest && calendarDays(end, now) <= 1 {
+		fmt.Printf("Longest streak%s: %s, the current one\n", label, streakDays(longest))
+	} else {
+		fmt.Printf("Longest streak%s: %s, ended %s\n", label, streakDays(longest), end.Format("2006-01-02"))
+	}
+	return nil
+}
+
+func streakDays(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
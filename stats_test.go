@@ -0,0 +1,93 @@
+// stats_test.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// doneAt returns tasks completed at each of the times.
+func doneAt(times ...time.Time) Tasks {
+	var ts Tasks
+	for i, at := range times {
+		t := Task{ID: int64(i + 1), Title: "Task", CreatedAt: at}
+		t.MarkDone(at)
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// TestStreakTimeZone pins the zone, ten hours behind UTC, so completions
+// stored in UTC land on the local day they happened on whatever zone the
+// tests run in.
+func TestStreakTimeZone(t *testing.T) {
+	zone := time.FixedZone("UTC-10", -10*60*60)
+	utc := func(d, h int) time.Time { return time.Date(2024, time.July, d, h, 0, 0, 0, time.UTC) }
+	ts := doneAt(
+		utc(2, 12),  // 2 July, 02:00 local
+		utc(3, 20),  // 3 July
+		utc(5, 8),   // 4 July, 22:00 local, though 5 July in UTC
+		utc(6, 1),   // 5 July, 15:00 local
+		utc(6, 9),   // 5 July, 23:00 local
+		utc(7, 11),  // 7 July, 01:00 local
+		utc(9, 5),   // 8 July, 19:00 local
+		utc(10, 10), // 10 July, 00:00 local
+	)
+	days := completionDays(ts, zone)
+	for day, want := range map[string]int{"2024-07-02": 1, "2024-07-03": 1, "2024-07-04": 1, "2024-07-05": 2, "2024-07-06": 0, "2024-07-07": 1, "2024-07-08": 1, "2024-07-09": 0, "2024-07-10": 1} {
+		if days[day] != want {
+			t.Errorf("%s has %d completions, want %d", day, days[day], want)
+		}
+	}
+	if n, end := longestStreak(days, zone); n != 4 || dayKey(end, zone) != "2024-07-05" {
+		t.Errorf("longest streak = %d ending %s, want 4 ending 2024-07-05", n, dayKey(end, zone))
+	}
+	// in UTC the same completions make different days
+	if n, end := longestStreak(completionDays(ts, time.UTC), time.UTC); n != 3 || dayKey(end, time.UTC) != "2024-07-07" {
+		t.Errorf("longest streak in UTC = %d ending %s, want 3 ending 2024-07-07", n, dayKey(end, time.UTC))
+	}
+
+	local := func(d, h int) time.Time { return time.Date(2024, time.July, d, h, 0, 0, 0, zone) }
+	for _, tt := range []struct {
+		now  time.Time
+		want int
+	}{
+		{local(10, 9), 1},  // a completion today
+		{local(8, 21), 2},  // 7 and 8 July
+		{local(9, 8), 2},   // today not over, yesterday counts
+		{local(9, 23), 2},  // not until midnight
+		{local(6, 12), 4},  // 2 to 5 July
+		{local(11, 12), 1}, // yesterday only
+		{local(12, 0), 0},  // a day missed
+		{local(1, 12), 0},  // before anything was done
+		{local(3, 0), 2},   // just past midnight on 3 July, which has one
+	} {
+		if got := currentStreak(days, tt.now); got != tt.want {
+			t.Errorf("current streak at %s = %d, want %d", tt.now.Format("Jan 2 15:04 MST"), got, tt.want)
+		}
+	}
+}
+
+// TestStreakDST checks that the day a clock change makes shorter or longer
+// still counts as one day.
+func TestStreakDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	var times []time.Time
+	// clocks go forward on 31 March and back on 27 October
+	for _, d := range []struct {
+		m   time.Month
+		day int
+	}{{time.March, 30}, {time.March, 31}, {time.April, 1}, {time.October, 26}, {time.October, 27}, {time.October, 28}, {time.October, 29}} {
+		times = append(times, time.Date(2024, d.m, d.day, 23, 30, 0, 0, loc))
+	}
+	days := completionDays(doneAt(times...), loc)
+	if n, end := longestStreak(days, loc); n != 4 || dayKey(end, loc) != "2024-10-29" {
+		t.Errorf("longest streak = %d ending %s, want 4 ending 2024-10-29", n, dayKey(end, loc))
+	}
+	if got := currentStreak(days, time.Date(2024, time.April, 2, 12, 0, 0, 0, loc)); got != 3 {
+		t.Errorf("current streak after the spring change = %d, want 3", got)
+	}
+}
@@ -0,0 +1,172 @@
+// subtasks.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// printTree prints tasks with subtasks indented beneath their parent. A
+// task whose parent isn't among ts is printed at the top level, so filtered
+// listings still show every match. The order of ts is kept among siblings.
+func printTree(ts Tasks) {
+	walkTree(ts, printTaskIndent)
+}
+
+// walkTree calls visit on each task in the order printTree prints them,
+// with the indent for its depth.
+func walkTree(ts Tasks, visit func(t Task, indent string)) {
+	present := map[int64]bool{}
+	for _, t := range ts {
+		present[t.ID] = true
+	}
+	// track by index: the trash can hold the same ID more than once
+	printed := make([]bool, len(ts))
+	var walk func(i int, indent string)
+	walk = func(i int, indent string) {
+		printed[i] = true
+		visit(ts[i], indent)
+		for j, c := range ts {
+			if !printed[j] && c.ParentID != nil && *c.ParentID == ts[i].ID {
+				walk(j, indent+"    ")
+			}
+		}
+	}
+	for i, t := range ts {
+		if !printed[i] && (t.ParentID == nil || !present[*t.ParentID]) {
+			walk(i, "")
+		}
+	}
+	// anything left is part of a parent cycle, which the CLI never creates
+	// but a hand-edited file might
+	for i, t := range ts {
+		if !printed[i] {
+			fmt.Fprintf(os.Stderr, "Warning: task %d is part of a subtask cycle.\n", t.ID)
+			walk(i, "")
+		}
+	}
+}
+
+// withOpenSubtasks adds to ids the open subtasks of each task in it, at any
+// depth, for do --force to complete with their parent.
+func withOpenSubtasks(ts Tasks, ids []int64) []int64 {
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for k := 0; k < len(ids); k++ {
+		for _, c := range ts.OpenChildren(ids[k]) {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	return ids
+}
+
+var splitFlags = []flagDef{boolFlag("close-original")}
+
+// cmdSplit breaks a pending task into new ones with its tags, priority and
+// due date. They become its subtasks, or with --close-original its
+// siblings, as the original is marked done. Without titles the editor
+// asks for them, one per line.
+func cmdSplit(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, splitFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) == 0 {
+		return usageError("split")
+	}
+	id, err := parseID(ca.pos[0])
+	if err != nil {
+		return err
+	}
+	titles := ca.pos[1:]
+	if len(titles) == 0 {
+		ts, err := loadTasks()
+		if err != nil {
+			return err
+		}
+		i := ts.Index(id)
+		if i == -1 {
+			return notFoundErrorf("task %d not found", id)
+		}
+		header := fmt.Sprintf("# Split %d) %s\n# One line per new task; lines starting with # are ignored.\n", id, ts[i].Title)
+		edited, err := editText(header)
+		if err != nil {
+			return err
+		}
+		lines, err := readTitles(strings.NewReader(edited))
+		if err != nil {
+			return err
+		}
+		titles = slices.DeleteFunc(lines, func(l string) bool { return strings.HasPrefix(l, "#") })
+		if len(titles) == 0 {
+			say("No new tasks; nothing changed.\n")
+			return nil
+		}
+		unlock, err := lockTasks()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	for i := range titles {
+		if titles[i], err = normalizeTitle(titles[i]); err != nil {
+			return err
+		}
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	orig := ts[i]
+	if orig.Done {
+		return fmt.Errorf("task %d is completed; reopen it to split it", id)
+	}
+	closing := ca.has("close-original")
+	parent := &orig.ID
+	if closing {
+		if err := checkCompletable(ts, id, nil); err != nil {
+			return err
+		}
+		parent = orig.ParentID
+	}
+	first := ts.NextID()
+	now := time.Now()
+	for k, title := range titles {
+		ts = append(ts, Task{
+			ID:        first + int64(k),
+			Title:     title,
+			CreatedAt: now.Add(time.Duration(k)),
+			DueDate:   orig.DueDate,
+			Priority:  orig.Priority,
+			Tags:      slices.Clone(orig.Tags),
+			ParentID:  parent,
+			Order:     nextOrder(ts),
+		})
+	}
+	if closing {
+		ts[i].Done, ts[i].CompletedAt = true, &now
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	for k, title := range titles {
+		say("Added %d: %s\n", first+int64(k), title)
+	}
+	if closing {
+		say("Marked %d done\n", id)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// subtasks_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// addTree adds a parent (1) with a subtask (2) that has its own (3).
+func addTree(t *testing.T) {
+	t.Helper()
+	for _, args := range [][]string{{"add", "Move house"}, {"add", "--under", "1", "Pack"}, {"add", "--under", "2", "Buy boxes"}} {
+		if code, _ := runTodo(t, args...); code != exitOK {
+			t.Fatalf("todo %s exited %d", strings.Join(args, " "), code)
+		}
+	}
+}
+
+// doneIDs returns whether each of the tasks is completed.
+func doneIDs(t *testing.T, ids ...int64) []bool {
+	t.Helper()
+	resetState()
+	ts, err := loadTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var done []bool
+	for _, id := range ids {
+		i := ts.Index(id)
+		if i < 0 {
+			t.Fatalf("task %d is gone", id)
+		}
+		done = append(done, ts[i].Done)
+	}
+	return done
+}
+
+func TestDoRefusesOpenSubtasks(t *testing.T) {
+	testEnv(t)
+	addTree(t)
+	if code, _ := runTodo(t, "do", "1"); code == exitOK {
+		t.Errorf("do of a parent with open subtasks succeeded")
+	}
+	if code, _ := runTodo(t, "do", "1", "2"); code == exitOK {
+		t.Errorf("do of a parent and a subtask with its own open subtask succeeded")
+	}
+	if got := doneIDs(t, 1, 2, 3); got[0] || got[1] || got[2] {
+		t.Errorf("refused do completed tasks: %v", got)
+	}
+	// completing the whole tree in one call is fine
+	if code, _ := runTodo(t, "do", "1-3"); code != exitOK {
+		t.Errorf("do 1-3 exited %d", code)
+	}
+	if got := doneIDs(t, 1, 2, 3); !got[0] || !got[1] || !got[2] {
+		t.Errorf("after do 1-3, done = %v", got)
+	}
+}
+
+func TestDoForceCompletesSubtasks(t *testing.T) {
+	testEnv(t)
+	addTree(t)
+	if code, _ := runTodo(t, "add", "Unrelated"); code != exitOK {
+		t.Fatalf("add exited %d", code)
+	}
+	if code, _ := runTodo(t, "do", "--force", "1"); code != exitOK {
+		t.Fatalf("do --force 1 exited %d", code)
+	}
+	if got := doneIDs(t, 1, 2, 3, 4); !got[0] || !got[1] || !got[2] || got[3] {
+		t.Errorf("after do --force 1, done = %v, want the tree done and 4 open", got)
+	}
+}
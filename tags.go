@@ -0,0 +1,158 @@
+// tags.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+var tagsFlags = []flagDef{boolFlag("force", "f"), jsonFlag}
+
+// cmdTags lists the tags in use with how many pending and completed tasks
+// carry each, or renames or removes one on every task in a single save.
+func cmdTags(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, tagsFlags...)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(ca.pos) == 0 && !ca.has("force"):
+		return listTags(ca)
+	case len(ca.pos) == 3 && ca.pos[0] == "rename" && !ca.has("force") && !ca.has("json"):
+		return renameTag(ca.pos[1], ca.pos[2])
+	case len(ca.pos) == 2 && (ca.pos[0] == "rm" || ca.pos[0] == "remove") && !ca.has("json"):
+		return removeTag(ca.pos[1], ca.has("force"))
+	}
+	return usageError("tags")
+}
+
+// tagCount is a line of todo tags.
+type tagCount struct {
+	Tag     string `json:"tag"`
+	Pending int    `json:"pending"`
+	Done    int    `json:"done"`
+}
+
+func listTags(ca cmdArgs) error {
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	counts := map[string]*tagCount{}
+	for _, t := range ts {
+		for _, tag := range t.Tags {
+			c := counts[tag]
+			if c == nil {
+				c = &tagCount{Tag: tag}
+				counts[tag] = c
+			}
+			if t.Done {
+				c.Done++
+			} else {
+				c.Pending++
+			}
+		}
+	}
+	rows := make([]tagCount, 0, len(counts))
+	for _, c := range counts {
+		rows = append(rows, *c)
+	}
+	slices.SortFunc(rows, func(a, b tagCount) int { return strings.Compare(a.Tag, b.Tag) })
+	if ca.has("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	if len(rows) == 0 {
+		fmt.Println("No tags.")
+		return nil
+	}
+	w := 0
+	for _, r := range rows {
+		w = max(w, len(r.Tag)+1)
+	}
+	for _, r := range rows {
+		fmt.Printf("%-*s  %d pending, %d done\n", w, "#"+r.Tag, r.Pending, r.Done)
+	}
+	return nil
+}
+
+// renameTag replaces a tag on every task that has it. A task that already
+// has the new tag keeps a single copy.
+func renameTag(from, to string) error {
+	from, to = normalizeTag(from), normalizeTag(to)
+	if from == "" || to == "" {
+		return usageErrorf("empty tag")
+	}
+	if from == to {
+		return usageErrorf("#%s is already called that", from)
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	n := 0
+	for i := range ts {
+		if j := slices.Index(ts[i].Tags, from); j != -1 {
+			tags := slices.Clone(ts[i].Tags)
+			tags[j] = to
+			ts[i].Tags = normalizeTags(tags)
+			n++
+		}
+	}
+	if n == 0 {
+		return notFoundErrorf("no task has #%s", from)
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	say("Renamed #%s to #%s on %d tasks\n", from, to, n)
+	return nil
+}
+
+// removeTag drops a tag from every task after asking, unless force is set.
+func removeTag(tag string, force bool) error {
+	tag = normalizeTag(tag)
+	if tag == "" {
+		return usageErrorf("empty tag")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	n := len(ts.Filter(func(t Task) bool { return t.HasTag(tag) }))
+	if n == 0 {
+		return notFoundErrorf("no task has #%s", tag)
+	}
+	if !force {
+		if !isTerminal(os.Stdin) {
+			return errors.New("refusing to remove a tag without --force when stdin is not a terminal")
+		}
+		ok, err := confirm(fmt.Sprintf("Remove #%s from %d tasks?", tag, n))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+	for i := range ts {
+		if ts[i].HasTag(tag) {
+			ts[i].Tags = slices.DeleteFunc(slices.Clone(ts[i].Tags), func(t string) bool { return t == tag })
+			if len(ts[i].Tags) == 0 {
+				ts[i].Tags = nil
+			}
+		}
+	}
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	say("Removed #%s from %d tasks\n", tag, n)
+	return nil
+}
@@ -0,0 +1,149 @@
+// tags_test.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// addTagged adds tasks with tags and returns the saved file.
+func addTagged(t *testing.T) string {
+	t.Helper()
+	testEnv(t)
+	for _, args := range [][]string{
+		{"add", "Buy milk", "--tag", "shoping", "--due", "2030-01-02", "-p", "2"},
+		{"add", "Pay rent", "--tag", "home"},
+		{"add", "Buy bread", "--tag", "shoping", "--tag", "someday"},
+		{"add", "Call mum"},
+		{"add", "Read a book", "--tag", "someday", "--tag", "shopping"},
+	} {
+		if code, _ := runTodo(t, args...); code != exitOK {
+			t.Fatalf("todo %s exited %d", strings.Join(args, " "), code)
+		}
+	}
+	path, err := tasksFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// rawTasks returns the JSON of each task in the file as it is written.
+func rawTasks(t *testing.T, path string) map[int64]json.RawMessage {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(b, &raws); err != nil {
+		t.Fatal(err)
+	}
+	tasks := map[int64]json.RawMessage{}
+	for _, raw := range raws {
+		var id struct{ ID int64 }
+		if err := json.Unmarshal(raw, &id); err != nil {
+			t.Fatal(err)
+		}
+		tasks[id.ID] = raw
+	}
+	return tasks
+}
+
+// sameExcept checks that the tasks other than changed are written exactly
+// as before, and that the changed ones differ.
+func sameExcept(t *testing.T, before, after map[int64]json.RawMessage, changed ...int64) {
+	t.Helper()
+	if len(after) != len(before) {
+		t.Fatalf("%d tasks became %d", len(before), len(after))
+	}
+	for id, raw := range before {
+		switch same := bytes.Equal(raw, after[id]); {
+		case !same && !slices.Contains(changed, id):
+			t.Errorf("untouched task %d changed:\n%s\nto\n%s", id, raw, after[id])
+		case same && slices.Contains(changed, id):
+			t.Errorf("task %d didn't change", id)
+		}
+	}
+}
+
+func tagsOf(t *testing.T, id int64) []string {
+	t.Helper()
+	resetState()
+	ts, err := loadTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts[ts.Index(id)].Tags
+}
+
+func TestTagsRenameKeepsOthers(t *testing.T) {
+	path := addTagged(t)
+	before := rawTasks(t, path)
+	if code, _ := runTodo(t, "tags", "rename", "shoping", "shopping"); code != exitOK {
+		t.Fatalf("tags rename exited %d", code)
+	}
+	sameExcept(t, before, rawTasks(t, path), 1, 3)
+	if got := tagsOf(t, 3); strings.Join(got, ",") != "shopping,someday" {
+		t.Errorf("task 3 has tags %v", got)
+	}
+}
+
+// TestTagsRenameMerges renames onto a tag a task already has, which leaves
+// it with one copy.
+func TestTagsRenameMerges(t *testing.T) {
+	path := addTagged(t)
+	before := rawTasks(t, path)
+	if code, _ := runTodo(t, "tags", "rename", "someday", "shopping"); code != exitOK {
+		t.Fatalf("tags rename exited %d", code)
+	}
+	sameExcept(t, before, rawTasks(t, path), 3, 5)
+	if got := tagsOf(t, 5); strings.Join(got, ",") != "shopping" {
+		t.Errorf("task 5 has tags %v, want one shopping", got)
+	}
+}
+
+func TestTagsRemoveKeepsOthers(t *testing.T) {
+	path := addTagged(t)
+	before := rawTasks(t, path)
+	if code, _ := runTodo(t, "tags", "rm", "--force", "someday"); code != exitOK {
+		t.Fatalf("tags rm exited %d", code)
+	}
+	after := rawTasks(t, path)
+	sameExcept(t, before, after, 3, 5)
+	if bytes.Contains(after[3], []byte("someday")) || bytes.Contains(after[5], []byte("someday")) {
+		t.Errorf("someday still there:\n%s\n%s", after[3], after[5])
+	}
+	if code, _ := runTodo(t, "tags", "rm", "--force", "shopping"); code != exitOK {
+		t.Fatalf("tags rm exited %d", code)
+	}
+	// a task left without tags has none in the file, not an empty list
+	if after = rawTasks(t, path); bytes.Contains(after[5], []byte(`"tags"`)) {
+		t.Errorf("task 5 keeps its tags field: %s", after[5])
+	}
+}
+
+// TestTagsNoMatchLeavesFile checks that an operation that matches no task
+// doesn't write the file at all.
+func TestTagsNoMatchLeavesFile(t *testing.T) {
+	path := addTagged(t)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"tags", "rename", "work", "office"}, {"tags", "rm", "--force", "work"}} {
+		if code, _ := runTodo(t, args...); code != exitNotFound {
+			t.Errorf("todo %s exited %d, want %d", strings.Join(args, " "), code, exitNotFound)
+		}
+	}
+	if code, _ := runTodo(t, "tags"); code != exitOK {
+		t.Errorf("tags exited %d", code)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Errorf("file changed:\n%s\nto\n%s", before, after)
+	}
+}
@@ -0,0 +1,219 @@
+// taskwarrior.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// twTimeLayout is how Taskwarrior writes dates: 20240601T120000Z.
+const twTimeLayout = "20060102T150405Z"
+
+// twTask is the part of a task in `task export` that a todo task can hold.
+type twTask struct {
+	ID          int64          `json:"id"`
+	UUID        string         `json:"uuid"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	Entry       twTime         `json:"entry"`
+	Modified    twTime         `json:"modified"`
+	End         twTime         `json:"end"`
+	Due         twTime         `json:"due"`
+	Wait        twTime         `json:"wait"`
+	Start       twTime         `json:"start"`
+	Tags        []string       `json:"tags"`
+	Priority    string         `json:"priority"`
+	Annotations []twAnnotation `json:"annotations"`
+}
+
+type twAnnotation struct {
+	Entry       twTime `json:"entry"`
+	Description string `json:"description"`
+}
+
+// twKnownFields are the fields of twTask, with urgency, which Taskwarrior
+// computes from the others, so nothing is lost by leaving it out.
+var twKnownFields = map[string]bool{
+	"id": true, "uuid": true, "description": true, "status": true, "entry": true, "modified": true, "end": true,
+	"due": true, "wait": true, "start": true, "tags": true, "priority": true, "annotations": true, "urgency": true,
+}
+
+// twTime is a Taskwarrior date, in local time; unset is nil.
+type twTime struct{ t *time.Time }
+
+func (tt *twTime) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse(twTimeLayout, s)
+	if err != nil {
+		// exports from before 2.4 and some hooks use ISO 8601
+		if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Errorf("invalid date %q", s)
+		}
+	}
+	t = t.Local()
+	tt.t = &t
+	return nil
+}
+
+// importTaskwarrior reads the output of `task export`: a JSON array, or one
+// object per line as older versions wrote. Deleted tasks and the templates
+// of recurring ones are skipped, and the fields todo has no place for, such
+// as project, are counted and reported.
+func importTaskwarrior(r io.Reader) (Tasks, int, error) {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	first, err := peekNonSpace(br)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading Taskwarrior export: %v", err)
+	}
+	var raws []json.RawMessage
+	if first == '[' {
+		if err := dec.Decode(&raws); err != nil {
+			return nil, 0, fmt.Errorf("reading Taskwarrior export: %v", err)
+		}
+	} else {
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, 0, fmt.Errorf("reading Taskwarrior export: %v", err)
+			}
+			raws = append(raws, raw)
+		}
+	}
+	var ts Tasks
+	skipped := 0
+	dropped := map[string]int{}
+	for i, raw := range raws {
+		skip := func(format string, args ...any) {
+			fmt.Fprintf(os.Stderr, "task %d: %s, skipped\n", i+1, fmt.Sprintf(format, args...))
+			skipped++
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			skip("not a task object")
+			continue
+		}
+		var tw twTask
+		if err := json.Unmarshal(raw, &tw); err != nil {
+			skip("%v", err)
+			continue
+		}
+		t, err := twTodoTask(tw)
+		if err != nil {
+			skip("%v", err)
+			continue
+		}
+		for name := range fields {
+			if !twKnownFields[name] {
+				dropped[name]++
+			}
+		}
+		ts = append(ts, t)
+	}
+	if len(dropped) > 0 {
+		n := 0
+		var counts []string
+		for _, name := range slices.Sorted(maps.Keys(dropped)) {
+			n += dropped[name]
+			counts = append(counts, fmt.Sprintf("%s %d", name, dropped[name]))
+		}
+		fmt.Fprintf(os.Stderr, "Dropped %d fields todo has no place for: %s\n", n, strings.Join(counts, ", "))
+	}
+	return ts, skipped, nil
+}
+
+// peekNonSpace returns the first byte of r that isn't white space, leaving
+// it unread.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b)) {
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+func twTodoTask(tw twTask) (Task, error) {
+	var t Task
+	switch tw.Status {
+	case "pending", "waiting", "":
+	case "completed":
+		t.Done = true
+	case "deleted":
+		return t, fmt.Errorf("%q is deleted", tw.Description)
+	case "recurring":
+		// its pending instances are exported as tasks of their own
+		return t, fmt.Errorf("%q is the template of a recurring task", tw.Description)
+	default:
+		return t, fmt.Errorf("unknown status %q", tw.Status)
+	}
+	title, err := normalizeTitle(tw.Description)
+	if err != nil {
+		return t, err
+	}
+	t.Title = title
+	if !t.Done {
+		t.ID = tw.ID
+	}
+	if uidLike(tw.UUID) {
+		t.UID = strings.ToLower(tw.UUID)
+	}
+	t.CreatedAt = time.Now()
+	if tw.Entry.t != nil {
+		t.CreatedAt = *tw.Entry.t
+	}
+	if tw.Modified.t != nil {
+		t.UpdatedAt = *tw.Modified.t
+	}
+	if t.Done {
+		t.CompletedAt = tw.End.t
+		if t.CompletedAt == nil {
+			t.CompletedAt = &t.CreatedAt
+		}
+	}
+	t.DueDate = tw.Due.t
+	// a waiting task is hidden until its wait date, as a deferred one is
+	t.StartDate = tw.Wait.t
+	if tw.Start.t != nil && !t.Done {
+		t.Status = todo.StatusDoing
+	}
+	t.Tags = normalizeTags(tw.Tags)
+	switch tw.Priority {
+	case "":
+	case "H":
+		t.Priority = priorityHigh
+	case "M":
+		t.Priority = 2
+	case "L":
+		t.Priority = priorityLow
+	default:
+		return t, fmt.Errorf("invalid priority %q", tw.Priority)
+	}
+	var notes []string
+	for _, a := range tw.Annotations {
+		if a.Entry.t != nil {
+			notes = append(notes, a.Entry.t.Format("2006-01-02")+" "+a.Description)
+		} else {
+			notes = append(notes, a.Description)
+		}
+	}
+	t.Notes = strings.Join(notes, "\n")
+	return t, nil
+}
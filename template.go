@@ -0,0 +1,66 @@
+// template.go
+package main
+
+import (
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// listTemplate, when set, replaces the usual list line. It is set by list
+// from --format or the list_format config key.
+var listTemplate *template.Template
+
+// templateFuncs are the helpers available to --format templates on top of
+// the Task fields.
+var templateFuncs = template.FuncMap{
+	// age is how long ago the task was created, like 3d 4h
+	"age": func(t Task) string { return formatDuration(time.Since(t.CreatedAt)) },
+	// due_in is the due date relative to now, or "" without one
+	"due_in": func(t Task) string {
+		if t.DueDate == nil {
+			return ""
+		}
+		return humanizeTime(*t.DueDate, time.Now())
+	},
+	// checkbox is the [ ], [x] or [~] shown by list
+	"checkbox": func(t Task) string {
+		switch {
+		case t.Done:
+			return "[x]"
+		case t.Blocked:
+			return "[~]"
+		}
+		return "[ ]"
+	},
+	// date formats a time the way list does; a nil time is ""
+	"date": func(v any) string {
+		switch t := v.(type) {
+		case time.Time:
+			return formatDate(t)
+		case *time.Time:
+			if t != nil {
+				return formatDate(*t)
+			}
+		}
+		return ""
+	},
+	"join": strings.Join,
+}
+
+// parseListFormat compiles a --format template. \t and \n may be written
+// literally, as shells pass them. The template is tried on an empty task so
+// that a reference to a field that doesn't exist is caught before anything
+// is printed.
+func parseListFormat(s string) (*template.Template, error) {
+	s = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(s)
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(s)
+	if err != nil {
+		return nil, usageErrorf("invalid format: %v", err)
+	}
+	if err := tmpl.Execute(io.Discard, Task{}); err != nil {
+		return nil, usageErrorf("invalid format: %v", err)
+	}
+	return tmpl, nil
+}
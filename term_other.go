@@ -0,0 +1,24 @@
+// term_other.go
+
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// rawTerminal is only implemented with stty on Unix systems.
+func rawTerminal() (func(), error) {
+	return nil, errors.New("todo ui is not supported on this platform")
+}
+
+func terminalSize() (rows, cols int) {
+	return 24, 80
+}
+
+// resizeSignal never fires; a nil channel blocks forever in a select.
+func resizeSignal() <-chan os.Signal {
+	return nil
+}
@@ -0,0 +1,52 @@
+// term_unix.go
+
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// stty runs stty on the terminal and returns what it printed.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// rawTerminal puts the terminal into raw mode, so keys arrive as they are
+// typed and without echo. The returned function restores the old settings.
+func rawTerminal() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("reading terminal settings: %v", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("setting terminal to raw mode: %v", err)
+	}
+	return func() { _, _ = stty(saved) }, nil
+}
+
+// terminalSize returns the rows and columns of the terminal, or 24x80 when
+// they can't be read.
+func terminalSize() (rows, cols int) {
+	out, err := stty("size")
+	if _, serr := fmt.Sscan(out, &rows, &cols); err != nil || serr != nil || rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+// resizeSignal delivers a value whenever the terminal window changes size.
+func resizeSignal() <-chan os.Signal {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGWINCH)
+	return ch
+}
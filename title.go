@@ -0,0 +1,40 @@
+// title.go
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTitleRunes is the longest title a task may have, counted in runes so
+// that accented and combining characters count as what they are.
+const maxTitleRunes = 500
+
+// normalizeTitle cleans up a title from the command line, an editor or an
+// import: whitespace such as pasted newlines becomes spaces, other control
+// characters are dropped, and the result is trimmed and, unless the
+// keep_whitespace setting is on, has runs of spaces collapsed. An empty or
+// overlong result is a usage error.
+func normalizeTitle(s string) (string, error) {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		case !unicode.IsControl(r):
+			b.WriteRune(r)
+		}
+	}
+	title := strings.TrimSpace(b.String())
+	if !config.KeepWhitespace {
+		title = strings.Join(strings.Fields(title), " ")
+	}
+	if title == "" {
+		return "", usageErrorf("a task needs a title")
+	}
+	if n := utf8.RuneCountInString(title); n > maxTitleRunes {
+		return "", usageErrorf("title is %d characters long; the limit is %d", n, maxTitleRunes)
+	}
+	return title, nil
+}
@@ -0,0 +1,115 @@
+// title_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+		keep           bool
+	}{
+		{name: "plain", in: "Pay rent", want: "Pay rent"},
+		{name: "trimmed", in: "  Pay rent\t", want: "Pay rent"},
+		{name: "pasted newlines", in: "Pay\nrent\r\nnow", want: "Pay rent now"},
+		{name: "runs of spaces", in: "Pay    rent", want: "Pay rent"},
+		{name: "runs of spaces kept", in: " Pay    rent ", want: "Pay    rent", keep: true},
+		{name: "control characters", in: "Pay\x00 rent\x1b[31m", want: "Pay rent[31m"},
+		{name: "accents", in: "Café crème brûlée", want: "Café crème brûlée"},
+		{name: "combining marks", in: "Cafe\u0301  cre\u0300me", want: "Cafe\u0301 cre\u0300me"},
+		{name: "wide characters", in: "買い物\u3000リスト", want: "買い物 リスト"},
+		{name: "emoji", in: "Ship it 🚀👩‍💻", want: "Ship it 🚀👩‍💻"},
+		{name: "no-break space", in: "10\u00a0km run", want: "10 km run"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetState()
+			config.KeepWhitespace = tt.keep
+			t.Cleanup(resetState)
+			got, err := normalizeTitle(tt.in)
+			if err != nil {
+				t.Fatalf("normalizeTitle(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeTitle(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNormalizeTitleLimit checks that the length limit counts runes, so a
+// title of multi-byte or combining characters gets as many as ASCII would.
+func TestNormalizeTitleLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		ok   bool
+	}{
+		{name: "ASCII at the limit", in: strings.Repeat("a", maxTitleRunes), ok: true},
+		{name: "ASCII over the limit", in: strings.Repeat("a", maxTitleRunes+1)},
+		{name: "accented at the limit", in: strings.Repeat("é", maxTitleRunes), ok: true},
+		{name: "wide at the limit", in: strings.Repeat("買", maxTitleRunes), ok: true},
+		{name: "wide over the limit", in: strings.Repeat("買", maxTitleRunes+1)},
+		{name: "emoji at the limit", in: strings.Repeat("🚀", maxTitleRunes), ok: true},
+		// a combining mark is a rune of its own
+		{name: "combining over the limit", in: strings.Repeat("e\u0301", maxTitleRunes/2+1)},
+		{name: "combining at the limit", in: strings.Repeat("e\u0301", maxTitleRunes/2), ok: true},
+		{name: "spaces collapsed before counting", in: strings.Repeat("a  ", maxTitleRunes/2-1) + "ab", ok: true},
+		{name: "empty", in: ""},
+		{name: "only whitespace", in: " \t\n "},
+		{name: "only control characters", in: "\x00\x07"},
+	}
+	resetState()
+	for _, tt := range tests {
+		got, err := normalizeTitle(tt.in)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			} else if got != strings.Join(strings.Fields(tt.in), " ") {
+				t.Errorf("%s: title changed to %q", tt.name, got)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: accepted a %d-byte title", tt.name, len(tt.in))
+		} else if exitCode(err) != exitUsage {
+			t.Errorf("%s: error %v exits %d, want %d", tt.name, err, exitCode(err), exitUsage)
+		}
+	}
+}
+
+// TestTitleNormalizedEverywhere checks that add, edit and import all clean
+// titles the same way.
+func TestTitleNormalizedEverywhere(t *testing.T) {
+	home := testEnv(t)
+	csv := home + "/import.csv"
+	writeFile(t, csv, "title\n\"  Café\n  menu \"\n")
+	for _, args := range [][]string{
+		{"add", "  買い物\n\nリスト "},
+		{"add", "Draft"},
+		{"edit", "2", "Ship   it 🚀"},
+		{"import", "--format", "csv", csv},
+	} {
+		if code, _ := runTodo(t, args...); code != exitOK {
+			t.Fatalf("todo %s exited %d", strings.Join(args, " "), code)
+		}
+	}
+	resetState()
+	ts, err := loadTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, t := range ts {
+		titles = append(titles, t.Title)
+	}
+	want := []string{"買い物 リスト", "Ship it 🚀", "Café menu"}
+	if strings.Join(titles, "|") != strings.Join(want, "|") {
+		t.Errorf("titles = %q, want %q", titles, want)
+	}
+	if code, _ := runTodo(t, "edit", "1", " \n "); code != exitUsage {
+		t.Errorf("edit to a blank title exited %d, want %d", code, exitUsage)
+	}
+}
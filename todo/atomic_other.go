@@ -0,0 +1,29 @@
+// atomic_other.go
+
+//go:build !unix
+
+package todo
+
+import "os"
+
+// replaceFile renames src over dst. Renaming over an existing file can fail
+// on Windows, for instance while another program has it open, so the
+// destination is removed and the rename retried.
+func replaceFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if _, serr := os.Stat(dst); serr != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// syncDir is a no-op: directories can't be opened for syncing on Windows.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,21 @@
+// atomic_unix.go
+
+//go:build unix
+
+package todo
+
+import "os"
+
+func replaceFile(src, dst string) error {
+	return os.Rename(src, dst)
+}
+
+// syncDir flushes a directory so a rename inside it survives power loss.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,128 @@
+// encrypt.go
+package todo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// An encrypted tasks file is the magic header, the scrypt salt, the GCM
+// nonce and the sealed JSON, in that order.
+var magic = []byte("TODOENC1")
+
+const (
+	saltSize = 16
+	// scrypt parameters recommended for interactive use
+	scryptN, scryptR, scryptP = 1 << 15, 8, 1
+)
+
+var (
+	// ErrPassphrase is returned when an encrypted file can't be opened
+	// with the given passphrase, or was damaged.
+	ErrPassphrase = errors.New("wrong passphrase or damaged encrypted file")
+	// ErrNoPassphrase is returned when an encrypted file is read without
+	// a passphrase.
+	ErrNoPassphrase = errors.New("file is encrypted and no passphrase is set")
+)
+
+// derived caches the last key, since deriving one is deliberately slow and
+// a command reads and writes the same file with the same passphrase.
+var derived struct {
+	passphrase string
+	salt, key  []byte
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if derived.key != nil && derived.passphrase == passphrase && bytes.Equal(derived.salt, salt) {
+		return derived.key, nil
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	derived.passphrase, derived.salt, derived.key = passphrase, salt, key
+	return key, nil
+}
+
+// IsEncrypted reports whether data starts with the encrypted file header.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Encrypt seals data with AES-256-GCM under a key derived from passphrase.
+func Encrypt(data []byte, passphrase string) ([]byte, error) {
+	salt := derived.salt
+	if derived.key == nil || derived.passphrase != passphrase {
+		salt = make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(append(append([]byte{}, magic...), salt...), nonce...)
+	return gcm.Seal(out, nonce, data, magic), nil
+}
+
+// Decrypt opens data sealed by Encrypt.
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+	rest := data[len(magic):]
+	if len(rest) < saltSize {
+		return nil, ErrPassphrase
+	}
+	gcm, err := newGCM(passphrase, rest[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	rest = rest[saltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrPassphrase
+	}
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], magic)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plain, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decode returns the JSON held in data, decrypting it if needed.
+func decode(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	return Decrypt(data, passphrase)
+}
+
+// encode encrypts JSON when a passphrase is given.
+func encode(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return data, nil
+	}
+	return Encrypt(data, passphrase)
+}
@@ -0,0 +1,208 @@
+// file.go
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrCorrupt is returned, wrapped, by FileStore.Save when the file on disk
+// was unreadable and nothing could be salvaged from it.
+var ErrCorrupt = errors.New("tasks file is corrupted")
+
+// FileStore keeps tasks in a JSON file, the format used by the todo command.
+// A missing file is an empty list. Each save keeps the previous contents for
+// Undo and replaces the file atomically.
+type FileStore struct {
+	Path string
+	// UndoPath is where Save keeps the previous contents for Undo, Path
+	// with .undo added when empty.
+	UndoPath string
+	// Recover lets Save replace a file Load could salvage nothing from.
+	Recover bool
+	// Warnings receives notes about a damaged file; nil discards them.
+	Warnings io.Writer
+	// Passphrase encrypts the file when set. An encrypted file is read
+	// whether it is set or not, but only opens with the right one.
+	Passphrase string
+
+	// unsalvaged holds the backup path of a file that was unreadable and
+	// yielded no tasks
+	unsalvaged string
+}
+
+// NewFileStore returns a FileStore for the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) warn(format string, a ...any) {
+	if s.Warnings != nil {
+		fmt.Fprintf(s.Warnings, format, a...)
+	}
+}
+
+// Load reads the file. A corrupted file is backed up next to it and every
+// task that can still be decoded is returned. An encrypted file that
+// doesn't open is an error, never treated as corrupted.
+func (s *FileStore) Load() (Tasks, error) {
+	b, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return Tasks{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b, err = decode(b, s.Passphrase); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Path, err)
+	}
+	var ts Tasks
+	if err := json.Unmarshal(b, &ts); err != nil {
+		// backup the corrupted file so user can inspect
+		backup := backupBroken(s.Path, b)
+		ts, skipped := salvageTasks(b)
+		if len(ts) > 0 {
+			s.warn("Warning: tasks file corrupted. Recovered %d tasks, skipped %d; original backed up to %s.\n",
+				len(ts), skipped, backup)
+			ts.MarkBlocked()
+			return ts, nil
+		}
+		// Nothing to keep: start fresh, but don't let a save replace the
+		// broken file unless the caller asks for it
+		if !s.Recover {
+			s.unsalvaged = backup
+		}
+		s.warn("Warning: tasks file corrupted and no tasks could be recovered. Backed up to %s and starting with empty list.\n", backup)
+		return Tasks{}, nil
+	}
+	ts.MarkBlocked()
+	return ts, nil
+}
+
+// Save writes ts to the file, first dropping links to tasks that are no
+// longer in the list.
+func (s *FileStore) Save(ts Tasks) error {
+	if s.unsalvaged != "" {
+		return fmt.Errorf("refusing to overwrite %s (backup at %s): %w", s.Path, s.unsalvaged, ErrCorrupt)
+	}
+	ts.DetachOrphans()
+	ts.PruneDependencies()
+	if err := s.saveUndo(); err != nil {
+		return err
+	}
+	return WriteEncrypted(s.Path, ts, s.Passphrase)
+}
+
+func (s *FileStore) Add(t Task) (Task, error)                        { return add(s, t) }
+func (s *FileStore) Complete(id int64) (Task, error)                 { return complete(s, id) }
+func (s *FileStore) Remove(id int64) (Task, error)                   { return remove(s, id) }
+func (s *FileStore) Edit(id int64, change func(*Task)) (Task, error) { return edit(s, id, change) }
+
+func (s *FileStore) saveUndo() error {
+	b, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		b, err = []byte("[]"), nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.undoPath(), b, 0o644)
+}
+
+func (s *FileStore) undoPath() string {
+	if s.UndoPath != "" {
+		return s.UndoPath
+	}
+	return s.Path + ".undo"
+}
+
+// Undo puts back the contents from before the last Save. Only one level is
+// kept, so it reports false when there is nothing to undo.
+func (s *FileStore) Undo() (bool, error) {
+	undo := s.undoPath()
+	if _, err := os.Stat(undo); os.IsNotExist(err) {
+		return false, nil
+	}
+	// the rename consumes the snapshot, so a second undo has nothing to do
+	if err := os.Rename(undo, s.Path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReadFile loads a task file without the salvaging Load does: a missing
+// file is an empty list and a corrupted one is an error.
+func ReadFile(path string) (Tasks, error) {
+	return ReadEncrypted(path, "")
+}
+
+// ReadEncrypted is ReadFile for a file that may be encrypted with
+// passphrase.
+func ReadEncrypted(path, passphrase string) (Tasks, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Tasks{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b, err = decode(b, passphrase); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var ts Tasks
+	if err := json.Unmarshal(b, &ts); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return ts, nil
+}
+
+// WriteFile writes ts to path atomically, without an undo snapshot.
+func WriteFile(path string, ts Tasks) error {
+	return WriteEncrypted(path, ts, "")
+}
+
+// WriteEncrypted is WriteFile encrypting with passphrase, or in plain JSON
+// when it is empty.
+func WriteEncrypted(path string, ts Tasks, passphrase string) error {
+	b, err := json.MarshalIndent(ts, "", "  ")
+	if err != nil {
+		return err
+	}
+	if b, err = encode(b, passphrase); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, b)
+}
+
+// WriteFileAtomic replaces path with data so that readers, and a crash at any
+// point, see either the old contents or the new ones. The temp file is
+// removed on failure.
+func WriteFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	// write temp file
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		// flush to disk before the rename makes the new contents visible
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// atomic move
+		err = replaceFile(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
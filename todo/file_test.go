@@ -0,0 +1,73 @@
+// file_test.go
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomicReplaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "new" {
+		t.Errorf("file holds %q, want %q", b, "new")
+	}
+	noTempFile(t, path)
+}
+
+// TestWriteFileAtomicCleansUp makes the rename over the destination fail
+// and checks that the temp file doesn't stay behind.
+func TestWriteFileAtomicCleansUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	// a directory with something in it can't be renamed over
+	if err := os.MkdirAll(filepath.Join(path, "keep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("new")); err == nil {
+		t.Fatal("rename over a directory succeeded")
+	}
+	noTempFile(t, path)
+	if _, err := os.Stat(filepath.Join(path, "keep")); err != nil {
+		t.Errorf("destination damaged: %v", err)
+	}
+}
+
+// TestWriteFileMarshalError checks that a list that can't be encoded
+// leaves the existing file as it was.
+func TestWriteFileMarshalError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// RFC 3339 has no room for a five-digit year
+	bad := Tasks{{ID: 1, Title: "x", CreatedAt: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	if err := WriteFile(path, bad); err == nil {
+		t.Fatal("writing an unencodable task succeeded")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "[]" {
+		t.Errorf("file holds %q after a failed write, want %q", b, "[]")
+	}
+	noTempFile(t, path)
+}
+
+func TestWriteFileAtomicMissingDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "tasks.json")
+	if err := WriteFileAtomic(path, []byte("new")); err == nil {
+		t.Fatal("write into a missing directory succeeded")
+	}
+	noTempFile(t, path)
+}
+
+func noTempFile(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Lstat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("%s.tmp left behind (%v)", path, err)
+	}
+}
@@ -0,0 +1,344 @@
+// journal.go
+package todo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// entry is one line of a journal. Op says what it does:
+//
+//	snapshot  replace the list with Tasks
+//	add       append Task
+//	edit      replace the task with Task's ID
+//	done      mark task ID done at Time
+//	remove    delete task ID
+//	undo      revert the save numbered Seq
+//
+// The lines written by one Save share a Seq, so undo can find them.
+type entry struct {
+	Op    string    `json:"op"`
+	Seq   int64     `json:"seq,omitempty"`
+	Time  time.Time `json:"time"`
+	ID    int64     `json:"id,omitempty"`
+	Task  *Task     `json:"task,omitempty"`
+	Tasks Tasks     `json:"tasks,omitempty"`
+	// Updated is the UpdatedAt a "done" leaves the task with
+	Updated time.Time `json:"updated,omitzero"`
+}
+
+// JournalStore keeps tasks as an append-only log of changes on top of a
+// JSON snapshot file. Save appends only what changed, and Load replays the
+// log. Compact squashes the log back into a snapshot.
+type JournalStore struct {
+	// Path is the journal, one JSON entry per line.
+	Path string
+	// Base is the JSON file the journal starts from until it is compacted,
+	// and receives a copy of the list on Compact. It may be empty.
+	Base string
+
+	entries []entry
+	// saved holds each stored task encoded, in list order, to tell what a
+	// Save changed
+	saved  []savedTask
+	loaded bool
+}
+
+type savedTask struct {
+	id   int64
+	data []byte
+}
+
+// NewJournalStore returns a JournalStore for the journal at path, starting
+// from the JSON file base.
+func NewJournalStore(path, base string) *JournalStore {
+	return &JournalStore{Path: path, Base: base}
+}
+
+func (s *JournalStore) Load() (Tasks, error) {
+	es, err := readJournal(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	base := Tasks{}
+	if s.Base != "" && (len(es) == 0 || es[0].Op != "snapshot") {
+		if base, err = ReadFile(s.Base); err != nil {
+			return nil, err
+		}
+	}
+	ts, err := replay(base, es)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", s.Path, err)
+	}
+	if ts == nil {
+		ts = Tasks{}
+	}
+	s.entries = es
+	if s.saved, err = encodeTasks(ts); err != nil {
+		return nil, err
+	}
+	s.loaded = true
+	ts.MarkBlocked()
+	return ts, nil
+}
+
+// Save appends the changes from the last loaded list to ts. Tasks added at
+// the end, edited, completed or removed get an entry each; any other
+// change, such as a new order, is written as a snapshot.
+func (s *JournalStore) Save(ts Tasks) error {
+	if !s.loaded {
+		if _, err := s.Load(); err != nil {
+			return err
+		}
+	}
+	ts.DetachOrphans()
+	ts.PruneDependencies()
+	next, err := encodeTasks(ts)
+	if err != nil {
+		return err
+	}
+	seq := int64(1)
+	for _, e := range s.entries {
+		seq = max(seq, e.Seq+1)
+	}
+	now := time.Now()
+	es := diffTasks(s.saved, next, ts)
+	if len(es) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for i := range es {
+		es[i].Seq, es[i].Time = seq, now
+		if es[i].Op == "done" {
+			es[i].Time = *ts[ts.Index(es[i].ID)].CompletedAt
+		}
+		b, err := json.Marshal(es[i])
+		if err != nil {
+			return err
+		}
+		buf.Write(append(b, '\n'))
+	}
+	if err := appendFile(s.Path, buf.Bytes()); err != nil {
+		return err
+	}
+	s.entries = append(s.entries, es...)
+	s.saved = next
+	return nil
+}
+
+func (s *JournalStore) Add(t Task) (Task, error)                        { return add(s, t) }
+func (s *JournalStore) Complete(id int64) (Task, error)                 { return complete(s, id) }
+func (s *JournalStore) Remove(id int64) (Task, error)                   { return remove(s, id) }
+func (s *JournalStore) Edit(id int64, change func(*Task)) (Task, error) { return edit(s, id, change) }
+
+// Undo reverts the last Save by appending an undo entry. Only one level is
+// kept, so it reports false when there is nothing to undo.
+func (s *JournalStore) Undo() (bool, error) {
+	es, err := readJournal(s.Path)
+	if err != nil {
+		return false, err
+	}
+	if len(es) == 0 {
+		return false, nil
+	}
+	last := es[len(es)-1]
+	if last.Op == "undo" || last.Seq == 0 {
+		return false, nil
+	}
+	b, err := json.Marshal(entry{Op: "undo", Seq: last.Seq, Time: time.Now()})
+	if err != nil {
+		return false, err
+	}
+	s.loaded = false
+	return true, appendFile(s.Path, append(b, '\n'))
+}
+
+// Compact replaces the journal with a single snapshot of the current list
+// and writes the list to Base as well. It returns the number of entries
+// that were squashed. The history, and with it undo, is gone afterwards.
+func (s *JournalStore) Compact() (int, error) {
+	ts, err := s.Load()
+	if err != nil {
+		return 0, err
+	}
+	n := len(s.entries)
+	b, err := json.Marshal(entry{Op: "snapshot", Time: time.Now(), Tasks: ts})
+	if err != nil {
+		return 0, err
+	}
+	// the journal is complete on its own from here, so a crash before Base
+	// is written loses nothing
+	if err := WriteFileAtomic(s.Path, append(b, '\n')); err != nil {
+		return 0, err
+	}
+	s.loaded = false
+	if s.Base != "" {
+		if err := WriteFile(s.Base, ts); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// readJournal parses a journal. A missing file has no entries. A last line
+// without its newline was cut short by a crash during a save and is
+// ignored, since that save never completed.
+func readJournal(path string) ([]entry, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if i := bytes.LastIndexByte(b, '\n'); i != len(b)-1 {
+		b = b[:i+1]
+	}
+	var es []entry
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, len(b)+1)
+	for n := 1; sc.Scan(); n++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var e entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		es = append(es, e)
+	}
+	return es, sc.Err()
+}
+
+// replay applies es to base. An undo entry rebuilds the list as it was
+// before the save it names.
+func replay(base Tasks, es []entry) (Tasks, error) {
+	ts := slices.Clone(base)
+	start := map[int64]int{}
+	for i, e := range es {
+		if _, ok := start[e.Seq]; !ok && e.Op != "undo" {
+			start[e.Seq] = i
+		}
+		switch e.Op {
+		case "snapshot":
+			ts = slices.Clone(e.Tasks)
+		case "add", "edit":
+			if e.Task == nil {
+				return nil, fmt.Errorf("%s entry without a task", e.Op)
+			}
+			if j := ts.Index(e.Task.ID); j != -1 {
+				ts[j] = *e.Task
+			} else {
+				ts = append(ts, *e.Task)
+			}
+		case "done":
+			if j := ts.Index(e.ID); j != -1 {
+				ts[j].MarkDone(e.Time)
+				if !e.Updated.IsZero() {
+					ts[j].UpdatedAt = e.Updated
+				}
+			}
+		case "remove":
+			if j := ts.Index(e.ID); j != -1 {
+				ts = slices.Delete(ts, j, j+1)
+			}
+		case "undo":
+			j, ok := start[e.Seq]
+			if !ok {
+				return nil, fmt.Errorf("undo of unknown save %d", e.Seq)
+			}
+			var err error
+			if ts, err = replay(base, es[:j]); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown journal op %q", e.Op)
+		}
+	}
+	return ts, nil
+}
+
+func encodeTasks(ts Tasks) ([]savedTask, error) {
+	out := make([]savedTask, len(ts))
+	for i, t := range ts {
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = savedTask{t.ID, b}
+	}
+	return out, nil
+}
+
+// diffTasks returns the entries turning old into next, whose tasks are ts.
+func diffTasks(old, next []savedTask, ts Tasks) []entry {
+	kept := map[int64]bool{}
+	for _, t := range next {
+		kept[t.id] = true
+	}
+	var es []entry
+	var remaining []savedTask
+	for _, t := range old {
+		if kept[t.id] {
+			remaining = append(remaining, t)
+		} else {
+			es = append(es, entry{Op: "remove", ID: t.id})
+		}
+	}
+	// what is left must be a prefix of next, in the same order, with any
+	// new tasks after it; otherwise the list was reordered
+	if len(remaining) > len(next) {
+		return []entry{{Op: "snapshot", Tasks: ts}}
+	}
+	for i, t := range remaining {
+		if next[i].id != t.id {
+			return []entry{{Op: "snapshot", Tasks: ts}}
+		}
+		if bytes.Equal(next[i].data, t.data) {
+			continue
+		}
+		if isCompletion(t.data, ts[i]) {
+			es = append(es, entry{Op: "done", ID: t.id, Updated: ts[i].UpdatedAt})
+		} else {
+			es = append(es, entry{Op: "edit", Task: &ts[i]})
+		}
+	}
+	for i := len(remaining); i < len(next); i++ {
+		es = append(es, entry{Op: "add", Task: &ts[i]})
+	}
+	return es
+}
+
+// isCompletion reports whether t is the task encoded in old changed only
+// by MarkDone, and by the new UpdatedAt and Status a save gives it.
+func isCompletion(old []byte, t Task) bool {
+	var o Task
+	if json.Unmarshal(old, &o) != nil || o.Done || !t.Done || t.CompletedAt == nil {
+		return false
+	}
+	o.MarkDone(*t.CompletedAt)
+	o.UpdatedAt, o.Status = t.UpdatedAt, t.Status
+	a, err1 := json.Marshal(o)
+	b, err2 := json.Marshal(t)
+	return err1 == nil && err2 == nil && bytes.Equal(a, b)
+}
+
+// appendFile adds data to the end of path in a single write and flushes it
+// to disk.
+func appendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	return errors.Join(err, f.Close())
+}
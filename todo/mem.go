@@ -0,0 +1,45 @@
+// mem.go
+package todo
+
+import "slices"
+
+// MemStore keeps tasks in memory, for tests and for programs that manage
+// persistence themselves.
+type MemStore struct {
+	Tasks Tasks
+}
+
+// Load returns a copy of the stored tasks, so changes to it have no effect
+// until they are saved.
+func (s *MemStore) Load() (Tasks, error) {
+	ts := copyTasks(s.Tasks)
+	ts.MarkBlocked()
+	return ts, nil
+}
+
+// Save stores a copy of ts, so later changes to it have no effect until
+// they are saved again.
+func (s *MemStore) Save(ts Tasks) error {
+	ts.DetachOrphans()
+	ts.PruneDependencies()
+	s.Tasks = copyTasks(ts)
+	return nil
+}
+
+// copyTasks copies ts along with the slices each task holds.
+func copyTasks(ts Tasks) Tasks {
+	out := make(Tasks, len(ts))
+	for i, t := range ts {
+		t.Tags = slices.Clone(t.Tags)
+		t.DependsOn = slices.Clone(t.DependsOn)
+		t.Sessions = slices.Clone(t.Sessions)
+		t.Attachments = slices.Clone(t.Attachments)
+		out[i] = t
+	}
+	return out
+}
+
+func (s *MemStore) Add(t Task) (Task, error)                        { return add(s, t) }
+func (s *MemStore) Complete(id int64) (Task, error)                 { return complete(s, id) }
+func (s *MemStore) Remove(id int64) (Task, error)                   { return remove(s, id) }
+func (s *MemStore) Edit(id int64, change func(*Task)) (Task, error) { return edit(s, id, change) }
@@ -0,0 +1,126 @@
+// mem_test.go
+package todo
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMemStoreShortcuts(t *testing.T) {
+	var s MemStore
+	a, err := s.Add(Task{Title: "Pay rent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Add(Task{Title: "Call mum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != 1 || b.ID != 2 || a.UID == "" || a.UID == b.UID || a.CreatedAt.IsZero() {
+		t.Errorf("added %+v and %+v, want IDs 1 and 2 with UIDs and creation times", a, b)
+	}
+
+	done, err := s.Complete(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !done.Done || done.CompletedAt == nil {
+		t.Errorf("completed task is %+v", done)
+	}
+	// completing it again keeps the first completion time
+	again, err := s.Complete(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.CompletedAt.Equal(*done.CompletedAt) {
+		t.Errorf("second Complete moved completed_at from %v to %v", done.CompletedAt, again.CompletedAt)
+	}
+
+	edited, err := s.Edit(2, func(t *Task) { t.Title = "Call dad" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Title != "Call dad" || s.Tasks[1].Title != "Call dad" {
+		t.Errorf("edit returned %q, stored %q", edited.Title, s.Tasks[1].Title)
+	}
+
+	removed, err := s.Remove(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed.ID != 1 || len(s.Tasks) != 1 || s.Tasks[0].ID != 2 {
+		t.Errorf("removed %d, left %+v", removed.ID, s.Tasks)
+	}
+	// IDs of removed tasks are not reused while higher ones exist
+	if c, _ := s.Add(Task{Title: "Water plants"}); c.ID != 3 {
+		t.Errorf("next ID = %d, want 3", c.ID)
+	}
+}
+
+func TestMemStoreNotFound(t *testing.T) {
+	s := MemStore{Tasks: Tasks{{ID: 1, Title: "Pay rent"}}}
+	if _, err := s.Complete(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Complete(9) = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Remove(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove(9) = %v, want ErrNotFound", err)
+	}
+	called := false
+	if _, err := s.Edit(9, func(*Task) { called = true }); !errors.Is(err, ErrNotFound) || called {
+		t.Errorf("Edit(9) = %v, called %v; want ErrNotFound without a call", err, called)
+	}
+	if len(s.Tasks) != 1 || s.Tasks[0].Title != "Pay rent" {
+		t.Errorf("failed calls changed the tasks: %+v", s.Tasks)
+	}
+}
+
+// TestMemStoreLoadCopies checks that what Load returns can be changed,
+// down to the tags and dependencies, without touching what is stored.
+func TestMemStoreLoadCopies(t *testing.T) {
+	s := MemStore{Tasks: Tasks{
+		{ID: 1, Title: "Pay rent", Tags: []string{"home"}},
+		{ID: 2, Title: "Post cheque", DependsOn: []int64{1}},
+	}}
+	ts, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts[0].Title = "changed"
+	ts[0].Tags[0] = "changed"
+	ts[1].DependsOn[0] = 9
+	if s.Tasks[0].Title != "Pay rent" || s.Tasks[0].Tags[0] != "home" || s.Tasks[1].DependsOn[0] != 1 {
+		t.Errorf("changing the loaded tasks changed the store: %+v", s.Tasks)
+	}
+	if !ts[1].Blocked || ts[0].Blocked {
+		t.Errorf("Load marked blocked = %v, %v; want false, true", ts[0].Blocked, ts[1].Blocked)
+	}
+
+	saved := Tasks{{ID: 1, Title: "Pay rent"}}
+	if err := s.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	saved[0].Title = "changed"
+	if s.Tasks[0].Title != "Pay rent" {
+		t.Errorf("changing the saved slice changed the store: %+v", s.Tasks)
+	}
+}
+
+// TestMemStoreSaveCleansReferences checks that Save drops parents and
+// dependencies that point at tasks no longer in the list.
+func TestMemStoreSaveCleansReferences(t *testing.T) {
+	gone := int64(7)
+	var s MemStore
+	if err := s.Save(Tasks{
+		{ID: 1, Title: "Pack", ParentID: &gone},
+		{ID: 2, Title: "Move", DependsOn: []int64{1, 7}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Tasks[0].ParentID != nil {
+		t.Errorf("task 1 still has missing parent %d", *s.Tasks[0].ParentID)
+	}
+	if !slices.Equal(s.Tasks[1].DependsOn, []int64{1}) {
+		t.Errorf("task 2 depends on %v, want [1]", s.Tasks[1].DependsOn)
+	}
+}
@@ -0,0 +1,52 @@
+// salvage.go
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// salvageTasks pulls every well-formed task object out of a damaged tasks
+// file. It tries to decode a task at each '{' and skips past the ones that
+// decode, so a mangled object only costs itself. skipped counts the objects
+// that looked like tasks but could not be decoded.
+func salvageTasks(b []byte) (ts Tasks, skipped int) {
+	for i := 0; i < len(b); {
+		k := bytes.IndexByte(b[i:], '{')
+		if k < 0 {
+			break
+		}
+		start := i + k
+		dec := json.NewDecoder(bytes.NewReader(b[start:]))
+		var t Task
+		if err := dec.Decode(&t); err == nil && t.ID != 0 {
+			ts = append(ts, t)
+			i = start + int(dec.InputOffset())
+			continue
+		}
+		// Task objects are written with the id first
+		if bytes.HasPrefix(bytes.TrimLeft(b[start+1:], " \t\r\n"), []byte(`"id"`)) {
+			skipped++
+		}
+		i = start + 1
+	}
+	return ts, skipped
+}
+
+// backupBroken copies a corrupted tasks file aside so the user can inspect
+// it, reusing an existing backup with the same contents.
+func backupBroken(path string, b []byte) string {
+	matches, _ := filepath.Glob(path + ".broken.*")
+	for _, m := range matches {
+		if old, err := os.ReadFile(m); err == nil && bytes.Equal(old, b) {
+			return m
+		}
+	}
+	backup := fmt.Sprintf("%s.broken.%d", path, time.Now().Unix())
+	_ = os.WriteFile(backup, b, 0o644) // best-effort
+	return backup
+}
@@ -0,0 +1,352 @@
+// sqlite.go
+package todo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// schema creates a tasks table with one column per Task field and an
+// identical undo_tasks table holding the list from before the last save.
+// pos keeps the order of the list, which IDs alone don't.
+const schema = `
+CREATE TABLE IF NOT EXISTS %s (
+	pos          INTEGER NOT NULL,
+	id           INTEGER NOT NULL,
+	title        TEXT NOT NULL,
+	done         INTEGER NOT NULL,
+	created_at   TEXT NOT NULL,
+	completed_at TEXT,
+	due_date     TEXT,
+	priority     INTEGER NOT NULL,
+	tags         TEXT,
+	deleted_at   TEXT,
+	notes        TEXT NOT NULL,
+	repeat       TEXT NOT NULL,
+	start_date   TEXT,
+	parent_id    INTEGER,
+	depends_on   TEXT,
+	sort_order   INTEGER NOT NULL,
+	updated_at   TEXT,
+	pinned       INTEGER NOT NULL DEFAULT 0,
+	uid          TEXT NOT NULL DEFAULT '',
+	status       TEXT NOT NULL DEFAULT '',
+	sessions     TEXT,
+	estimate     INTEGER NOT NULL DEFAULT 0,
+	waiting      TEXT NOT NULL DEFAULT '',
+	waiting_since TEXT,
+	url          TEXT NOT NULL DEFAULT '',
+	attachments  TEXT
+)`
+
+// addedColumns were added to the schema later, at the end of the table so
+// undo_tasks keeps matching tasks column for column. open adds them to an
+// older database.
+var addedColumns = []struct{ name, decl string }{
+	{"updated_at", "TEXT"},
+	{"pinned", "INTEGER NOT NULL DEFAULT 0"},
+	{"uid", "TEXT NOT NULL DEFAULT ''"},
+	{"status", "TEXT NOT NULL DEFAULT ''"},
+	{"sessions", "TEXT"},
+	{"estimate", "INTEGER NOT NULL DEFAULT 0"},
+	{"waiting", "TEXT NOT NULL DEFAULT ''"},
+	{"waiting_since", "TEXT"},
+	{"url", "TEXT NOT NULL DEFAULT ''"},
+	{"attachments", "TEXT"},
+}
+
+const columns = `pos, id, title, done, created_at, completed_at, due_date, priority, tags,
+	deleted_at, notes, repeat, start_date, parent_id, depends_on, sort_order, updated_at, pinned, uid, status, sessions, estimate,
+	waiting, waiting_since, url, attachments`
+
+// SQLiteStore keeps tasks in a SQLite database. Each save replaces the
+// list in one transaction and keeps the previous one for Undo, like
+// FileStore does.
+type SQLiteStore struct {
+	Path string
+
+	db *sql.DB
+}
+
+// NewSQLiteStore returns a SQLiteStore for the database at path, which is
+// created on first use.
+func NewSQLiteStore(path string) *SQLiteStore {
+	return &SQLiteStore{Path: path}
+}
+
+func (s *SQLiteStore) open() (*sql.DB, error) {
+	if s.db != nil {
+		return s.db, nil
+	}
+	// wait for other writers instead of failing with "database is locked"
+	db, err := sql.Open("sqlite", s.Path+"?_pragma=busy_timeout(10000)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	stmts := []string{
+		fmt.Sprintf(schema, "tasks"),
+		fmt.Sprintf(schema, "undo_tasks"),
+		`CREATE TABLE IF NOT EXISTS meta (key TEXT PRIMARY KEY, value TEXT NOT NULL)`,
+	}
+	for _, q := range stmts {
+		if _, err := db.Exec(q); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%s: %v", s.Path, err)
+		}
+	}
+	if err := addColumns(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %v", s.Path, err)
+	}
+	s.db = db
+	return db, nil
+}
+
+func addColumns(db *sql.DB) error {
+	for _, table := range []string{"tasks", "undo_tasks"} {
+		rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+		if err != nil {
+			return err
+		}
+		have := map[string]bool{}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return err
+			}
+			have[name] = true
+		}
+		rows.Close()
+		for _, c := range addedColumns {
+			if !have[c.name] {
+				if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, c.name, c.decl)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Close releases the database. The store opens it again when used.
+func (s *SQLiteStore) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+func (s *SQLiteStore) Load() (Tasks, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT ` + columns + ` FROM tasks ORDER BY pos`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ts := Tasks{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.Path, err)
+		}
+		ts = append(ts, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	ts.MarkBlocked()
+	return ts, nil
+}
+
+// Save replaces the stored list with ts, first dropping links to tasks that
+// are no longer in the list.
+func (s *SQLiteStore) Save(ts Tasks) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	ts.DetachOrphans()
+	ts.PruneDependencies()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmts := []string{
+		`DELETE FROM undo_tasks`,
+		`INSERT INTO undo_tasks SELECT * FROM tasks`,
+		`DELETE FROM tasks`,
+		`INSERT OR REPLACE INTO meta (key, value) VALUES ('undo', '1')`,
+	}
+	for _, q := range stmts {
+		if _, err := tx.Exec(q); err != nil {
+			return err
+		}
+	}
+	insert, err := tx.Prepare(`INSERT INTO tasks (` + columns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	for i, t := range ts {
+		tags, err := jsonColumn(t.Tags)
+		if err != nil {
+			return err
+		}
+		deps, err := jsonColumn(t.DependsOn)
+		if err != nil {
+			return err
+		}
+		sessions, err := jsonColumn(t.Sessions)
+		if err != nil {
+			return err
+		}
+		attachments, err := jsonColumn(t.Attachments)
+		if err != nil {
+			return err
+		}
+		if _, err := insert.Exec(i, t.ID, t.Title, t.Done, t.CreatedAt.Format(time.RFC3339Nano),
+			formatTime(t.CompletedAt), formatTime(t.DueDate), t.Priority, tags,
+			formatTime(t.DeletedAt), t.Notes, t.Repeat, formatTime(t.StartDate),
+			t.ParentID, deps, t.Order, formatTime(&t.UpdatedAt), t.Pinned, t.UID, t.Status, sessions, t.Estimate,
+			t.Waiting, formatTime(t.WaitingSince), t.URL, attachments); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Add(t Task) (Task, error)                        { return add(s, t) }
+func (s *SQLiteStore) Complete(id int64) (Task, error)                 { return complete(s, id) }
+func (s *SQLiteStore) Remove(id int64) (Task, error)                   { return remove(s, id) }
+func (s *SQLiteStore) Edit(id int64, change func(*Task)) (Task, error) { return edit(s, id, change) }
+
+// Undo puts back the list from before the last Save. Only one level is
+// kept, so it reports false when there is nothing to undo.
+func (s *SQLiteStore) Undo() (bool, error) {
+	db, err := s.open()
+	if err != nil {
+		return false, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`DELETE FROM meta WHERE key = 'undo'`)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	stmts := []string{
+		`DELETE FROM tasks`,
+		`INSERT INTO tasks SELECT * FROM undo_tasks`,
+		`DELETE FROM undo_tasks`,
+	}
+	for _, q := range stmts {
+		if _, err := tx.Exec(q); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+func scanTask(rows *sql.Rows) (Task, error) {
+	var (
+		t                              Task
+		pos                            int
+		created                        string
+		completed, due, deleted, start sql.NullString
+		waitingSince                   sql.NullString
+		updated                        sql.NullString
+		tags, deps, sessions           sql.NullString
+		attachments                    sql.NullString
+		parent                         sql.NullInt64
+	)
+	err := rows.Scan(&pos, &t.ID, &t.Title, &t.Done, &created, &completed, &due, &t.Priority,
+		&tags, &deleted, &t.Notes, &t.Repeat, &start, &parent, &deps, &t.Order, &updated, &t.Pinned, &t.UID, &t.Status, &sessions, &t.Estimate,
+		&t.Waiting, &waitingSince, &t.URL, &attachments)
+	if err != nil {
+		return t, err
+	}
+	if t.CreatedAt, err = time.Parse(time.RFC3339Nano, created); err != nil {
+		return t, err
+	}
+	for _, f := range []struct {
+		col sql.NullString
+		dst **time.Time
+	}{{completed, &t.CompletedAt}, {due, &t.DueDate}, {deleted, &t.DeletedAt}, {start, &t.StartDate}, {waitingSince, &t.WaitingSince}} {
+		if !f.col.Valid {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339Nano, f.col.String)
+		if err != nil {
+			return t, err
+		}
+		*f.dst = &v
+	}
+	if updated.Valid {
+		if t.UpdatedAt, err = time.Parse(time.RFC3339Nano, updated.String); err != nil {
+			return t, err
+		}
+	}
+	if parent.Valid {
+		t.ParentID = &parent.Int64
+	}
+	if tags.Valid {
+		if err := json.Unmarshal([]byte(tags.String), &t.Tags); err != nil {
+			return t, err
+		}
+	}
+	if deps.Valid {
+		if err := json.Unmarshal([]byte(deps.String), &t.DependsOn); err != nil {
+			return t, err
+		}
+	}
+	if sessions.Valid {
+		if err := json.Unmarshal([]byte(sessions.String), &t.Sessions); err != nil {
+			return t, err
+		}
+	}
+	if attachments.Valid {
+		if err := json.Unmarshal([]byte(attachments.String), &t.Attachments); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+// formatTime stores times in the layout encoding/json uses, so a task
+// reads back exactly as it would from a JSON file. A missing or zero time
+// is NULL.
+func formatTime(t *time.Time) any {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// jsonColumn encodes a list column as a JSON array, or NULL when empty.
+func jsonColumn[T any](v []T) (any, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,80 @@
+// store.go
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Store keeps a list of tasks. Load and Save work on the whole list; the
+// other methods are shortcuts that load, change one task and save.
+type Store interface {
+	Load() (Tasks, error)
+	Save(Tasks) error
+	// Add stores t under the next free ID, which the returned task has,
+	// giving it a UID if it has none.
+	Add(t Task) (Task, error)
+	// Complete marks a task done. Completing a done task changes nothing.
+	Complete(id int64) (Task, error)
+	// Remove deletes a task and returns it.
+	Remove(id int64) (Task, error)
+	// Edit calls change on the task with the given ID and saves the result.
+	Edit(id int64, change func(*Task)) (Task, error)
+}
+
+// ErrNotFound is returned, wrapped, when no task has the requested ID.
+var ErrNotFound = errors.New("task not found")
+
+// The helpers below implement the shortcut methods of Store in terms of
+// Load and Save, for any store.
+
+func add(s Store, t Task) (Task, error) {
+	ts, err := s.Load()
+	if err != nil {
+		return Task{}, err
+	}
+	t.ID = ts.NextID()
+	if t.UID == "" {
+		t.UID = NewUID()
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	return t, s.Save(append(ts, t))
+}
+
+func complete(s Store, id int64) (Task, error) {
+	return edit(s, id, func(t *Task) {
+		if !t.Done {
+			t.MarkDone(time.Now())
+		}
+	})
+}
+
+func remove(s Store, id int64) (Task, error) {
+	ts, err := s.Load()
+	if err != nil {
+		return Task{}, err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return Task{}, fmt.Errorf("task %d: %w", id, ErrNotFound)
+	}
+	t := ts[i]
+	return t, s.Save(slices.Delete(ts, i, i+1))
+}
+
+func edit(s Store, id int64, change func(*Task)) (Task, error) {
+	ts, err := s.Load()
+	if err != nil {
+		return Task{}, err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return Task{}, fmt.Errorf("task %d: %w", id, ErrNotFound)
+	}
+	change(&ts[i])
+	return ts[i], s.Save(ts)
+}
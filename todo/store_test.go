@@ -0,0 +1,254 @@
+// store_test.go
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	// mems holds the memory store of each directory, for every open to share
+	mems = map[string]*MemStore{}
+	// stores opens each kind of store on a directory. Opening twice on the
+	// same directory gives a second handle on the same list, to check what a
+	// Save leaves for the next process.
+	stores = []struct {
+		name string
+		open func(t *testing.T, dir string) Store
+	}{
+		{"file", func(t *testing.T, dir string) Store { return NewFileStore(filepath.Join(dir, "tasks.json")) }},
+		{"encrypted file", func(t *testing.T, dir string) Store {
+			return &FileStore{Path: filepath.Join(dir, "tasks.json"), Passphrase: "s3cret"}
+		}},
+		{"sqlite", func(t *testing.T, dir string) Store {
+			s := NewSQLiteStore(filepath.Join(dir, "tasks.db"))
+			t.Cleanup(func() { s.Close() })
+			return s
+		}},
+		{"journal", func(t *testing.T, dir string) Store {
+			return NewJournalStore(filepath.Join(dir, "tasks.journal"), filepath.Join(dir, "tasks.json"))
+		}},
+		{"memory", func(t *testing.T, dir string) Store {
+			if mems[dir] == nil {
+				mems[dir] = &MemStore{}
+			}
+			return mems[dir]
+		}},
+	}
+)
+
+// TestStores runs the same checks over every Store.
+func TestStores(t *testing.T) {
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			for _, c := range storeChecks {
+				t.Run(c.name, func(t *testing.T) {
+					dir := t.TempDir()
+					c.check(t, func() Store { return st.open(t, dir) })
+				})
+			}
+		})
+	}
+}
+
+var storeChecks = []struct {
+	name  string
+	check func(t *testing.T, open func() Store)
+}{
+	{"empty", checkEmpty},
+	{"round trip", checkRoundTrip},
+	{"shortcuts", checkShortcuts},
+	{"not found", checkNotFound},
+	{"references", checkReferences},
+	{"copies", checkCopies},
+}
+
+func checkEmpty(t *testing.T, open func() Store) {
+	ts, err := open().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts == nil || len(ts) != 0 {
+		t.Errorf("new store loads %#v, want an empty list", ts)
+	}
+}
+
+// checkRoundTrip saves tasks with every field set and reads them back
+// through a new handle.
+func checkRoundTrip(t *testing.T, open func() Store) {
+	at := func(d int) *time.Time {
+		v := time.Date(2024, time.July, d, 9, 30, 15, 500, time.UTC)
+		return &v
+	}
+	parent := int64(1)
+	want := Tasks{
+		{ID: 1, UID: "0190a1b2c3d4e5f6a7b8c9d0e1f2a3b4", Title: "Move house", CreatedAt: *at(1), Order: 2},
+		{
+			ID: 2, UID: "0190a1b2c3d4e5f6a7b8c9d0e1f2a3b5", Title: "Pack, then \"label\" boxes",
+			Done: true, CreatedAt: *at(2), CompletedAt: at(3), DueDate: at(4), Priority: 3,
+			Tags: []string{"home", "weekend"}, Notes: "line one\nline two", Repeat: "weekly",
+			StartDate: at(2), ParentID: &parent, DependsOn: []int64{1}, Order: 1,
+			UpdatedAt: *at(3), Pinned: true, Status: StatusDone, Waiting: "Sam", WaitingSince: at(2),
+			Estimate: 90 * time.Minute, Sessions: []Session{{Start: *at(2), End: *at(3)}},
+			URL: "https://example.com/boxes", Attachments: []string{"/tmp/list.txt"},
+		},
+		{ID: 5, Title: "Trashed", CreatedAt: *at(1), DeletedAt: at(5)},
+	}
+	if err := open().Save(want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := open().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameTasks(t, got, want)
+	// a done dependency doesn't block
+	if got[1].Blocked {
+		t.Errorf("task 2 is blocked by done task 1")
+	}
+	got[0].MarkDone(*at(6))
+	got[1].Done, got[1].CompletedAt, got[1].Status = false, nil, ""
+	if err := open().Save(got); err != nil {
+		t.Fatal(err)
+	}
+	again, err := open().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameTasks(t, again, got)
+}
+
+func checkShortcuts(t *testing.T, open func() Store) {
+	a, err := open().Add(Task{Title: "Pay rent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := open().Add(Task{Title: "Call mum", Tags: []string{"family"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != 1 || b.ID != 2 || a.UID == "" || a.CreatedAt.IsZero() {
+		t.Errorf("added %+v and %+v, want IDs 1 and 2 with a UID and creation time", a, b)
+	}
+	done, err := open().Complete(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !done.Done || done.CompletedAt == nil {
+		t.Errorf("Complete returned %+v", done)
+	}
+	if _, err := open().Edit(2, func(t *Task) { t.Title, t.Priority = "Call dad", 2 }); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := open().Remove(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed.Title != "Pay rent" || !removed.Done {
+		t.Errorf("Remove returned %+v", removed)
+	}
+	ts, err := open().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 || ts[0].ID != 2 || ts[0].Title != "Call dad" || ts[0].Priority != 2 || ts[0].UID != b.UID {
+		t.Errorf("store holds %+v, want only the edited task 2", ts)
+	}
+	if c, err := open().Add(Task{Title: "Water plants"}); err != nil || c.ID != 3 {
+		t.Errorf("next Add = %d, %v; want ID 3", c.ID, err)
+	}
+}
+
+func checkNotFound(t *testing.T, open func() Store) {
+	if _, err := open().Add(Task{Title: "Pay rent"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := open().Complete(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Complete(9) = %v, want ErrNotFound", err)
+	}
+	if _, err := open().Remove(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove(9) = %v, want ErrNotFound", err)
+	}
+	if _, err := open().Edit(9, func(*Task) {}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Edit(9) = %v, want ErrNotFound", err)
+	}
+	if ts, err := open().Load(); err != nil || len(ts) != 1 || ts[0].Done {
+		t.Errorf("failed calls left %+v, %v", ts, err)
+	}
+}
+
+// checkReferences checks that links to removed tasks are dropped on save
+// and that pending dependencies are marked on load.
+func checkReferences(t *testing.T, open func() Store) {
+	parent := int64(1)
+	if err := open().Save(Tasks{
+		{ID: 1, Title: "Move house"},
+		{ID: 2, Title: "Pack", ParentID: &parent},
+		{ID: 3, Title: "Drive", DependsOn: []int64{1, 2}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := open().Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	ts, err := open().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 2 || ts[0].ParentID != nil {
+		t.Errorf("task 2 keeps removed parent: %+v", ts)
+	}
+	if len(ts) == 2 && (len(ts[1].DependsOn) != 1 || ts[1].DependsOn[0] != 2 || !ts[1].Blocked) {
+		t.Errorf("task 3 depends on %v, blocked %v; want [2] and blocked", ts[1].DependsOn, ts[1].Blocked)
+	}
+}
+
+// checkCopies changes what Load returned and what was passed to Save, in
+// place, and checks that the store still holds what was saved.
+func checkCopies(t *testing.T, open func() Store) {
+	start := time.Date(2024, time.July, 1, 9, 0, 0, 0, time.UTC)
+	tasks := func() Tasks {
+		return Tasks{{
+			ID: 1, Title: "Write report", CreatedAt: start,
+			Sessions:    []Session{{Start: start, End: start.Add(time.Hour)}},
+			Attachments: []string{"/home/me/report.odt"},
+		}}
+	}
+	saved := tasks()
+	if err := open().Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	saved[0].Sessions[0].End = start.Add(5 * time.Hour)
+	saved[0].Attachments[0] = "/home/me/other.odt"
+	ts, err := open().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts[0].Sessions[0].Start = start.Add(-time.Hour)
+	ts[0].Attachments[0] = "/tmp/changed"
+	got, err := open().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameTasks(t, got, tasks())
+}
+
+// sameTasks compares tasks by their JSON, so times that are equal but in
+// different locations match.
+func sameTasks(t *testing.T, got, want Tasks) {
+	t.Helper()
+	g, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(g) != string(w) {
+		t.Errorf("loaded\n%s\nwant\n%s", g, w)
+	}
+}
@@ -0,0 +1,337 @@
+// task.go
+
+// Package todo holds the task model and storage behind the todo command, so
+// that other programs can read and change the same task lists.
+package todo
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Task struct {
+	ID int64 `json:"id"`
+	// UID identifies the task for good, across lists and machines, while
+	// the ID is a short handle that clear or another list can reuse.
+	UID         string     `json:"uid,omitempty"`
+	Title       string     `json:"title"`
+	Done        bool       `json:"done"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Priority    int        `json:"priority,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	Repeat      string     `json:"repeat,omitempty"`
+	StartDate   *time.Time `json:"start_date,omitempty"`
+	ParentID    *int64     `json:"parent_id,omitempty"`
+	DependsOn   []int64    `json:"depends_on,omitempty"`
+	Order       int64      `json:"order,omitempty"`
+	// UpdatedAt is the time of the last change, which sync uses to pick
+	// between two versions of a task.
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	// Pinned tasks are listed before all others.
+	Pinned bool `json:"pinned,omitempty"`
+	// Status is where the task stands on a board: empty for to do,
+	// StatusDoing, StatusWaiting or StatusDone. Use State to read it.
+	Status string `json:"status,omitempty"`
+	// Waiting names who or what the task is waiting on, since
+	// WaitingSince, after it was handed over.
+	Waiting      string     `json:"waiting,omitempty"`
+	WaitingSince *time.Time `json:"waiting_since,omitempty"`
+	// Estimate is how long the task was expected to take.
+	Estimate time.Duration `json:"estimate,omitempty"`
+	// Sessions are the stretches of time spent on the task, oldest first.
+	Sessions []Session `json:"sessions,omitempty"`
+	// URL is the link the task is about, which todo open opens.
+	URL string `json:"url,omitempty"`
+	// Attachments are absolute paths of files that go with the task.
+	Attachments []string `json:"attachments,omitempty"`
+
+	// Blocked is set by MarkBlocked when a dependency is still pending.
+	// It is not stored.
+	Blocked bool `json:"-"`
+}
+
+type Tasks []Task
+
+// The statuses a task moves through.
+const (
+	StatusTodo    = "todo"
+	StatusDoing   = "doing"
+	StatusWaiting = "waiting"
+	StatusDone    = "done"
+)
+
+// State returns the task's status. Done decides whether it is done and
+// Waiting whether it is waiting, so a task changed by a program that only
+// sets those counts too.
+func (t Task) State() string {
+	switch {
+	case t.Done:
+		return StatusDone
+	case t.Waiting != "":
+		return StatusWaiting
+	case t.Status == StatusDoing:
+		return StatusDoing
+	}
+	return StatusTodo
+}
+
+// SettleStatus stores each task's State as its Status, leaving it empty
+// for tasks to do. Completed tasks stop waiting.
+func (ts Tasks) SettleStatus() {
+	for i := range ts {
+		if ts[i].Done {
+			ts[i].Waiting, ts[i].WaitingSince = "", nil
+		}
+		ts[i].Status = ts[i].State()
+		if ts[i].Status == StatusTodo {
+			ts[i].Status = ""
+		}
+	}
+}
+
+// MarkDone completes the task at the given time. It stops waiting, and
+// its running session, if any, ends.
+func (t *Task) MarkDone(at time.Time) {
+	t.Done, t.CompletedAt, t.Status = true, &at, StatusDone
+	t.Waiting, t.WaitingSince = "", nil
+	t.Stop(at)
+}
+
+// Session is a stretch of time spent working on a task. End is zero while
+// the session is running.
+type Session struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitzero"`
+}
+
+// Running reports whether the task's last session is still open.
+func (t Task) Running() bool {
+	n := len(t.Sessions)
+	return n > 0 && t.Sessions[n-1].End.IsZero()
+}
+
+// Stop ends the task's running session at the given time, or when it
+// began if that is later, and returns how long it ran. It returns 0 when
+// no session is running.
+func (t *Task) Stop(at time.Time) time.Duration {
+	if !t.Running() {
+		return 0
+	}
+	s := &t.Sessions[len(t.Sessions)-1]
+	s.End = at
+	if s.End.Before(s.Start) {
+		s.End = s.Start
+	}
+	return s.End.Sub(s.Start)
+}
+
+// Tracked returns the time spent in the task's sessions, counting a
+// running one up to now.
+func (t Task) Tracked(now time.Time) time.Duration {
+	var d time.Duration
+	for _, s := range t.Sessions {
+		end := s.End
+		if end.IsZero() {
+			end = now
+		}
+		if end.After(s.Start) {
+			d += end.Sub(s.Start)
+		}
+	}
+	return d
+}
+
+// SettleSessions leaves at most one session running in the list: the last
+// session of a pending task, started most recently. Any other open session
+// is closed when the next one on the list began, or for a completed task
+// when it was completed, so no time is counted twice.
+func (ts Tasks) SettleSessions() {
+	var latest *Session
+	for i := range ts {
+		t := &ts[i]
+		for k := range t.Sessions {
+			s := &t.Sessions[k]
+			if !s.End.IsZero() {
+				continue
+			}
+			switch {
+			case k < len(t.Sessions)-1:
+				s.End = t.Sessions[k+1].Start
+			case t.Done && t.CompletedAt != nil:
+				s.End = *t.CompletedAt
+			case t.Done:
+				s.End = s.Start
+			case latest == nil || s.Start.After(latest.Start):
+				if latest != nil {
+					latest.End = s.Start
+				}
+				latest = s
+				continue
+			default:
+				s.End = latest.Start
+			}
+			if s.End.Before(s.Start) {
+				s.End = s.Start
+			}
+		}
+	}
+}
+
+// NextID returns the ID for a new task: one more than the highest in use.
+func (ts Tasks) NextID() int64 {
+	var max int64
+	for _, t := range ts {
+		if t.ID > max {
+			max = t.ID
+		}
+	}
+	return max + 1
+}
+
+// Index returns the position of the task with the given ID, or -1.
+func (ts Tasks) Index(id int64) int {
+	for i, t := range ts {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Filter returns the tasks for which keep returns true.
+func (ts Tasks) Filter(keep func(Task) bool) Tasks {
+	var out Tasks
+	for _, t := range ts {
+		if keep(t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// sortKey is an order SortBy knows. has reports whether a task has a value
+// for the key; cmp compares two tasks that both do.
+type sortKey struct {
+	name string
+	has  func(Task) bool
+	cmp  func(a, b Task) int
+}
+
+func always(Task) bool { return true }
+
+var sortKeys = []sortKey{
+	{"due", func(t Task) bool { return t.DueDate != nil }, func(a, b Task) int { return a.DueDate.Compare(*b.DueDate) }},
+	{"priority", func(t Task) bool { return t.Priority != 0 }, func(a, b Task) int { return cmp.Compare(a.Priority, b.Priority) }},
+	{"created", always, func(a, b Task) int { return a.CreatedAt.Compare(b.CreatedAt) }},
+	{"updated", always, func(a, b Task) int { return a.UpdatedAt.Compare(b.UpdatedAt) }},
+	{"title", always, func(a, b Task) int { return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)) }},
+	{"completed", func(t Task) bool { return t.CompletedAt != nil }, func(a, b Task) int { return a.CompletedAt.Compare(*b.CompletedAt) }},
+	{"estimate", func(t Task) bool { return t.Estimate > 0 }, func(a, b Task) int { return cmp.Compare(a.Estimate, b.Estimate) }},
+	{"waiting", func(t Task) bool { return t.WaitingSince != nil }, func(a, b Task) int { return a.WaitingSince.Compare(*b.WaitingSince) }},
+}
+
+// SortKeys returns the names of the orders SortBy knows.
+func SortKeys() []string {
+	names := make([]string, len(sortKeys))
+	for i, k := range sortKeys {
+		names[i] = k.name
+	}
+	return names
+}
+
+// SortBy orders the tasks by a key from SortKeys, reversed if asked. Tasks
+// without a value for the key go last either way, and ties are broken by
+// ID.
+func (ts Tasks) SortBy(key string, reverse bool) error {
+	i := slices.IndexFunc(sortKeys, func(k sortKey) bool { return k.name == key })
+	if i == -1 {
+		return fmt.Errorf("unknown sort key %q", key)
+	}
+	k := sortKeys[i]
+	slices.SortStableFunc(ts, func(a, b Task) int {
+		ha, hb := k.has(a), k.has(b)
+		switch {
+		case ha && hb:
+			c := k.cmp(a, b)
+			if reverse {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		case ha:
+			return -1
+		case hb:
+			return 1
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return nil
+}
+
+// HasTag reports whether t carries tag, which must already be normalized.
+func (t Task) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, tag)
+}
+
+// IsDeferred reports whether a task's start date is still in the future.
+func (t Task) IsDeferred(now time.Time) bool {
+	return t.StartDate != nil && t.StartDate.After(now)
+}
+
+// IsOverdue reports whether a pending task was due before today, in now's
+// time zone. A task due earlier today, or at midnight as a date-only due
+// date is stored, is due today rather than overdue until the day is over.
+func (t Task) IsOverdue(now time.Time) bool {
+	y, m, d := now.Date()
+	return !t.Done && t.DueDate != nil && t.DueDate.Before(time.Date(y, m, d, 0, 0, 0, 0, now.Location()))
+}
+
+// Children returns the direct subtasks of the task with the given ID.
+func (ts Tasks) Children(id int64) Tasks {
+	return ts.Filter(func(t Task) bool { return t.ParentID != nil && *t.ParentID == id })
+}
+
+// OpenChildren returns the pending direct subtasks of a task.
+func (ts Tasks) OpenChildren(id int64) Tasks {
+	return ts.Children(id).Filter(func(t Task) bool { return !t.Done })
+}
+
+// WaitingOn returns the dependencies of t that are still pending. A
+// dependency that is no longer in the list counts as satisfied.
+func (ts Tasks) WaitingOn(t Task) Tasks {
+	return ts.Filter(func(o Task) bool { return !o.Done && slices.Contains(t.DependsOn, o.ID) })
+}
+
+// MarkBlocked records on each pending task whether it still waits on
+// another, so a single task can be shown as blocked without the rest.
+func (ts Tasks) MarkBlocked() {
+	for i := range ts {
+		ts[i].Blocked = !ts[i].Done && len(ts.WaitingOn(ts[i])) > 0
+	}
+}
+
+// DetachOrphans makes a subtask top-level once its parent has left the
+// list (archived, moved, or removed by hand), so a later task that happens
+// to get the same ID doesn't adopt it.
+func (ts Tasks) DetachOrphans() {
+	for i := range ts {
+		if p := ts[i].ParentID; p != nil && ts.Index(*p) == -1 {
+			ts[i].ParentID = nil
+		}
+	}
+}
+
+// PruneDependencies drops dependencies on tasks that have left the list.
+func (ts Tasks) PruneDependencies() {
+	for i := range ts {
+		ts[i].DependsOn = slices.DeleteFunc(ts[i].DependsOn, func(id int64) bool { return ts.Index(id) == -1 })
+	}
+}
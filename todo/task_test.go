@@ -0,0 +1,77 @@
+// task_test.go
+package todo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskJSONCompletedAt(t *testing.T) {
+	created := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	open := Task{ID: 1, Title: "Pay rent", CreatedAt: created}
+	b, err := json.Marshal(open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "completed_at") {
+		t.Errorf("open task marshals with completed_at: %s", b)
+	}
+	var back Task
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.CompletedAt != nil || back.Done {
+		t.Errorf("open task reads back as done=%v completed_at=%v", back.Done, back.CompletedAt)
+	}
+
+	done := open
+	done.MarkDone(created.Add(time.Hour))
+	if b, err = json.Marshal(done); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"completed_at":"2024-07-01T10:00:00Z"`) {
+		t.Errorf("done task lacks completed_at: %s", b)
+	}
+	back = Task{}
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.CompletedAt == nil || !back.CompletedAt.Equal(*done.CompletedAt) {
+		t.Errorf("completed_at reads back as %v, want %v", back.CompletedAt, done.CompletedAt)
+	}
+}
+
+func TestTaskIsOverdue(t *testing.T) {
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	now := time.Date(2024, 7, 5, 15, 30, 0, 0, zone)
+	at := func(d, h, m int) *time.Time {
+		v := time.Date(2024, 7, d, h, m, 0, 0, zone)
+		return &v
+	}
+	tests := []struct {
+		name string
+		task Task
+		want bool
+	}{
+		{"no due date", Task{}, false},
+		{"date-only today", Task{DueDate: at(5, 0, 0)}, false},
+		{"earlier today", Task{DueDate: at(5, 9, 0)}, false},
+		{"later today", Task{DueDate: at(5, 23, 59)}, false},
+		{"tomorrow", Task{DueDate: at(6, 0, 0)}, false},
+		{"date-only yesterday", Task{DueDate: at(4, 0, 0)}, true},
+		{"late yesterday", Task{DueDate: at(4, 23, 59)}, true},
+		{"done yesterday", Task{DueDate: at(4, 0, 0), Done: true}, false},
+		// a due time in another zone counts on its day in now's
+		{"today in another zone", Task{DueDate: ptr(time.Date(2024, 7, 6, 0, 30, 0, 0, time.FixedZone("UTC+9", 9*60*60)))}, false},
+		{"yesterday in UTC", Task{DueDate: ptr(time.Date(2024, 7, 5, 4, 0, 0, 0, time.UTC))}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.task.IsOverdue(now); got != tt.want {
+			t.Errorf("%s: IsOverdue = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func ptr(t time.Time) *time.Time { return &t }
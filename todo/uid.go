@@ -0,0 +1,59 @@
+// uid.go
+package todo
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"time"
+)
+
+// NewUID returns a random version 4 UUID for a new task.
+func NewUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return formatUID(b, 4)
+}
+
+// LegacyUID is the UID a task saved before UIDs existed gets from its
+// creation time, a version 5 style UUID, so that every copy of the task on
+// every machine is given the same one.
+func LegacyUID(created time.Time) string {
+	return NamedUID("todo-cli task " + created.UTC().Format(time.RFC3339Nano))
+}
+
+// NamedUID returns the version 5 style UUID named by name, for tasks that
+// come from elsewhere, so that the same source always gives the same UID.
+func NamedUID(name string) string {
+	sum := sha1.Sum([]byte(name))
+	var b [16]byte
+	copy(b[:], sum[:])
+	return formatUID(b, 5)
+}
+
+func formatUID(b [16]byte, version byte) string {
+	b[6] = b[6]&0x0f | version<<4
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// AssignUIDs gives each task without a UID its LegacyUID, or a random one
+// if another task already has that, and reports whether any changed.
+func (ts Tasks) AssignUIDs() bool {
+	used := map[string]bool{}
+	for _, t := range ts {
+		used[t.UID] = true
+	}
+	changed := false
+	for i := range ts {
+		if ts[i].UID != "" {
+			continue
+		}
+		uid := LegacyUID(ts[i].CreatedAt)
+		if used[uid] {
+			uid = NewUID()
+		}
+		ts[i].UID, used[uid], changed = uid, true, true
+	}
+	return changed
+}
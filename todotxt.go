@@ -0,0 +1,144 @@
+// todotxt.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// todo.txt lines look like
+//
+//	x 2024-06-02 2024-06-01 title +tag due:2024-07-01 pri:A
+//	(A) 2024-06-01 title +tag @context pin:1 status:doing
+//
+// Completed lines keep their priority as a pri: key, as todo.txt drops
+// the (A) marker when a task is done. pin:1 marks a pinned task, and
+// status:doing one being worked on.
+const todotxtDate = "2006-01-02"
+
+func exportTodotxt(w io.Writer, ts Tasks) error {
+	for _, t := range ts {
+		var parts []string
+		if t.Done {
+			parts = append(parts, "x")
+			if t.CompletedAt != nil {
+				parts = append(parts, t.CompletedAt.Format(todotxtDate))
+			}
+		} else if t.Priority != priorityNone {
+			parts = append(parts, strings.TrimSpace(priorityMarker(t.Priority)))
+		}
+		parts = append(parts, t.CreatedAt.Format(todotxtDate), t.Title)
+		for _, tag := range t.Tags {
+			parts = append(parts, "+"+tag)
+		}
+		if t.DueDate != nil {
+			parts = append(parts, "due:"+t.DueDate.Format(todotxtDate))
+		}
+		if t.Done && t.Priority != priorityNone {
+			parts = append(parts, fmt.Sprintf("pri:%c", 'A'+t.Priority-1))
+		}
+		if t.Pinned {
+			parts = append(parts, "pin:1")
+		}
+		if t.State() == todo.StatusDoing {
+			parts = append(parts, "status:doing")
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(parts, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importTodotxt reverses exportTodotxt. Anything it doesn't recognize stays
+// in the title, so only lines too long for a title are rejected.
+func importTodotxt(r io.Reader) (Tasks, int, error) {
+	var ts Tasks
+	skipped := 0
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		t := Task{CreatedAt: time.Now()}
+		if fields[0] == "x" {
+			t.Done = true
+			fields = fields[1:]
+			if d, ok := todotxtDateField(fields); ok {
+				t.CompletedAt = &d
+				fields = fields[1:]
+			}
+		} else if len(fields[0]) == 3 && fields[0][0] == '(' && fields[0][2] == ')' {
+			if p := todotxtPriority(fields[0][1]); p != priorityNone {
+				t.Priority = p
+				fields = fields[1:]
+			}
+		}
+		if d, ok := todotxtDateField(fields); ok {
+			t.CreatedAt = d
+			fields = fields[1:]
+		}
+		var title, tags []string
+		for _, f := range fields {
+			switch {
+			case len(f) > 1 && (f[0] == '+' || f[0] == '@'):
+				tags = append(tags, f[1:])
+			case strings.HasPrefix(f, "due:"):
+				if d, err := time.ParseInLocation(todotxtDate, f[4:], time.Local); err == nil {
+					t.DueDate = &d
+					continue
+				}
+				title = append(title, f)
+			case strings.HasPrefix(f, "pri:") && len(f) == 5 && todotxtPriority(f[4]) != priorityNone:
+				t.Priority = todotxtPriority(f[4])
+			case f == "pin:1":
+				t.Pinned = true
+			case f == "status:doing" && !t.Done:
+				t.Status = todo.StatusDoing
+			default:
+				title = append(title, f)
+			}
+		}
+		t.Title = strings.Join(title, " ")
+		t.Tags = normalizeTags(tags)
+		if t.Title == "" {
+			t.Title = sc.Text()
+		}
+		var err error
+		if t.Title, err = normalizeTitle(t.Title); err != nil {
+			skipRecord(n, "%v", err)
+			skipped++
+			continue
+		}
+		ts = append(ts, t)
+	}
+	return ts, skipped, sc.Err()
+}
+
+func todotxtDateField(fields []string) (time.Time, bool) {
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+	d, err := time.ParseInLocation(todotxtDate, fields[0], time.Local)
+	return d, err == nil
+}
+
+// todotxtPriority maps A and B to high and medium; todo.txt allows letters
+// down to Z, which all count as low here.
+func todotxtPriority(c byte) int {
+	switch {
+	case c == 'A':
+		return priorityHigh
+	case c == 'B':
+		return priorityHigh + 1
+	case c >= 'C' && c <= 'Z':
+		return priorityLow
+	}
+	return priorityNone
+}
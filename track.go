@@ -0,0 +1,249 @@
+// track.go
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// cmdStart marks a task as doing and starts timing it. Only one task is
+// timed at a time, so a session running on another task is stopped first.
+func cmdStart(args []string) error {
+	_ = args
+	if len(args) != 1 {
+		return usageError("start")
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	if ts[i].Done {
+		return fmt.Errorf("task %d is completed; reopen it first", id)
+	}
+	if ts[i].Running() {
+		say("Task %d is already running.\n", id)
+		return nil
+	}
+	now := time.Now()
+	var stopped []string
+	for k := range ts {
+		if ts[k].Running() {
+			d := ts[k].Stop(now)
+			stopped = append(stopped, fmt.Sprintf("Stopped %d after %s", ts[k].ID, formatTracked(d)))
+		}
+	}
+	if ts[i].Waiting != "" {
+		say("Task %d is no longer waiting for %s\n", id, ts[i].Waiting)
+		ts[i].Waiting, ts[i].WaitingSince = "", nil
+	}
+	ts[i].Status = todo.StatusDoing
+	ts[i].Sessions = append(slices.Clone(ts[i].Sessions), todo.Session{Start: now})
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	for _, s := range stopped {
+		say("%s\n", s)
+	}
+	say("Started %d: %s\n", id, ts[i].Title)
+	return nil
+}
+
+var stopFlags = []flagDef{valueFlag("at")}
+
+// cmdStop ends the running session. The task stays in doing until it is
+// completed. A session left running, say over a restart, is still open
+// the next time; --at ends it when the work really stopped.
+func cmdStop(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, stopFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("stop")
+	}
+	now := time.Now()
+	at := now
+	if ca.has("at") {
+		if at, err = parseClock(ca.value("at"), now); err != nil {
+			return usageErrorf("invalid --at time %q: %v", ca.value("at"), err)
+		}
+		if at.After(now) {
+			return usageErrorf("--at %s is in the future", ca.value("at"))
+		}
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(ts, Task.Running)
+	if i == -1 {
+		say("No task is running.\n")
+		return nil
+	}
+	t := &ts[i]
+	if start := t.Sessions[len(t.Sessions)-1].Start; at.Before(start) {
+		return usageErrorf("task %d has only been running since %s", t.ID, formatTime(start))
+	}
+	d := t.Stop(at)
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	say("Stopped %d after %s (%s in total): %s\n", t.ID, formatTracked(d), formatTracked(t.Tracked(now)), t.Title)
+	return nil
+}
+
+// parseClock reads a time of day today, such as 17:30, or a date as
+// parseDate does.
+func parseClock(s string, now time.Time) (time.Time, error) {
+	if c, err := time.ParseInLocation("15:04", strings.TrimSpace(s), now.Location()); err == nil {
+		return startOfDay(now).Add(time.Duration(c.Hour())*time.Hour + time.Duration(c.Minute())*time.Minute), nil
+	}
+	t, err := parseDateAt(s, now)
+	if err != nil {
+		return t, fmt.Errorf("expected HH:MM or %s", strings.TrimPrefix(err.Error(), "expected "))
+	}
+	return t, nil
+}
+
+// formatTracked renders tracked time in hours and minutes, e.g. "26h 5m",
+// as days would hide how much work a week held.
+func formatTracked(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if h := d / time.Hour; h > 0 {
+		return fmt.Sprintf("%dh %dm", h, (d-h*time.Hour)/time.Minute)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+var timesheetFlags = []flagDef{valueFlag("from"), valueFlag("to"), boolFlag("today"), boolFlag("week")}
+
+// cmdTimesheet sums the time tracked in a period per day and per task,
+// including tasks archived since. A session running over midnight counts
+// towards both days.
+func cmdTimesheet(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, timesheetFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 || (ca.has("today") && ca.has("week")) {
+		return usageError("timesheet")
+	}
+	now := time.Now()
+	from, to := startOfDay(now).AddDate(0, 0, -6), now
+	switch {
+	case ca.has("today"):
+		from = startOfDay(now)
+	case ca.has("week"):
+		from = startOfWeek(now)
+	}
+	if ca.has("from") {
+		if from, err = parseDate(ca.value("from")); err != nil {
+			return usageErrorf("invalid --from date %q: %v", ca.value("from"), err)
+		}
+	}
+	if ca.has("to") {
+		if to, err = parseDate(ca.value("to")); err != nil {
+			return usageErrorf("invalid --to date %q: %v", ca.value("to"), err)
+		}
+		if to.Equal(startOfDay(to)) {
+			// a bare date includes the whole day
+			to = to.AddDate(0, 0, 1)
+		}
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	path, err := companionPath("archive")
+	if err != nil {
+		return err
+	}
+	archived, err := readTasksFile(path)
+	if err != nil {
+		return err
+	}
+	type taskTime struct {
+		t Task
+		d time.Duration
+	}
+	var tasks []taskTime
+	perDay := map[time.Time]time.Duration{}
+	var total time.Duration
+	for _, t := range append(ts, archived...) {
+		var sum time.Duration
+		for _, s := range t.Sessions {
+			start, end := s.Start, s.End
+			if end.IsZero() {
+				end = now
+			}
+			// days are counted in local time, whatever zone the session was saved in
+			start, end = later(start, from).In(now.Location()), earlier(end, to)
+			for start.Before(end) {
+				day := startOfDay(start)
+				stop := earlier(end, day.AddDate(0, 0, 1))
+				perDay[day] += stop.Sub(start)
+				sum += stop.Sub(start)
+				start = stop
+			}
+		}
+		if sum > 0 {
+			tasks = append(tasks, taskTime{t, sum})
+			total += sum
+		}
+	}
+	if total == 0 {
+		fmt.Println("No time tracked in this period.")
+		return nil
+	}
+	days := make([]time.Time, 0, len(perDay))
+	for day := range perDay {
+		days = append(days, day)
+	}
+	slices.SortFunc(days, time.Time.Compare)
+	slices.SortStableFunc(tasks, func(a, b taskTime) int { return int(b.d - a.d) })
+	lines := make([]string, len(tasks))
+	w := len("Total")
+	for i, tt := range tasks {
+		lines[i] = taskLine(tt.t)
+		w = max(w, utf8.RuneCountInString(lines[i]), len("Mon 2006-01-02"))
+	}
+	for _, day := range days {
+		fmt.Printf("%-*s  %8s\n", w, day.Format("Mon 2006-01-02"), formatTracked(perDay[day]))
+	}
+	fmt.Println()
+	for i, tt := range tasks {
+		fmt.Printf("%s%s  %8s\n", lines[i], strings.Repeat(" ", w-utf8.RuneCountInString(lines[i])), formatTracked(tt.d))
+	}
+	fmt.Println()
+	fmt.Printf("%-*s  %8s\n", w, "Total", formatTracked(total))
+	return nil
+}
+
+func earlier(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,441 @@
+// ui.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ui is the state of a `todo ui` session. Edits are made to ts, the list as
+// loaded, and saved with saveTasks when the session ends with q.
+type ui struct {
+	ts      Tasks
+	removed Tasks   // deleted tasks, moved to the trash on save
+	view    []int64 // IDs of the tasks shown, in display order
+	cur     int     // index into view of the selected task
+	top     int     // index into view of the first visible row
+	filter  string
+	changes int
+
+	input  *uiInput       // the line being edited, if any
+	answer func(yes bool) // the action waiting on a y/N answer, if any
+	status string         // the message or question on the bottom line
+
+	rows, cols int
+}
+
+// uiInput is a line typed on the bottom row, for add, edit and filter.
+type uiInput struct {
+	prompt string
+	text   []rune
+	done   func(text string)
+}
+
+var uiFlags = []flagDef{colorFlag}
+
+func cmdUI(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, uiFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("ui")
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return errors.New("todo ui needs a terminal")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	u := &ui{ts: ts}
+	u.refresh()
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	// alternate screen, hidden cursor
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	save := u.run()
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+	restore()
+	switch {
+	case u.changes == 0:
+		say("No changes.\n")
+		return nil
+	case !save:
+		say("Discarded %d changes.\n", u.changes)
+		return nil
+	}
+	if len(u.removed) > 0 {
+		if err := moveToTrash(u.removed); err != nil {
+			return err
+		}
+	}
+	if err := saveTasks(u.ts); err != nil {
+		return err
+	}
+	say("Saved %d changes.\n", u.changes)
+	return nil
+}
+
+// run handles keys and resizes until the session ends, and reports whether
+// it ended with q (save) rather than Ctrl-C (discard).
+func (u *ui) run() bool {
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			for _, k := range parseKeys(buf[:n]) {
+				keys <- k
+			}
+		}
+	}()
+	resized := resizeSignal()
+	u.rows, u.cols = terminalSize()
+	for {
+		u.draw()
+		select {
+		case <-resized:
+			u.rows, u.cols = terminalSize()
+		case k, ok := <-keys:
+			if !ok {
+				return true
+			}
+			if quit, save := u.key(k); quit {
+				return save
+			}
+		}
+	}
+}
+
+// parseKeys splits what one read from the terminal returned into keys:
+// named ones such as "up", "enter" or "ctrl-c", or the character typed.
+// todo shell reads its lines with them too.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b && len(b) >= 3 && (b[1] == '[' || b[1] == 'O'):
+			end := 2
+			for end < len(b) && (b[end] >= '0' && b[end] <= '9' || b[end] == ';') {
+				end++
+			}
+			if end == len(b) {
+				end--
+			}
+			names := map[string]string{"A": "up", "B": "down", "C": "right", "D": "left",
+				"H": "home", "F": "end", "1~": "home", "4~": "end", "5~": "pgup", "6~": "pgdn", "3~": "delete"}
+			keys = append(keys, names[string(b[2:end+1])])
+			b = b[end+1:]
+			continue
+		case c == 0x1b:
+			keys = append(keys, "esc")
+		case c == '\r' || c == '\n':
+			keys = append(keys, "enter")
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, "backspace")
+		case c < 0x20:
+			keys = append(keys, "ctrl-"+string(rune('a'+c-1)))
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, string(r))
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// key applies one key press. quit is set when the session should end, and
+// save when its changes should be kept.
+func (u *ui) key(k string) (quit, save bool) {
+	switch {
+	case u.input != nil:
+		u.editInput(k)
+		return false, false
+	case u.answer != nil:
+		answer := u.answer
+		u.answer, u.status = nil, ""
+		answer(k == "y" || k == "Y")
+		return false, false
+	}
+	u.status = ""
+	switch k {
+	case "q":
+		return true, true
+	case "ctrl-c":
+		return true, false
+	case "up", "k":
+		u.move(-1)
+	case "down", "j":
+		u.move(1)
+	case "pgup":
+		u.move(-u.listRows())
+	case "pgdn":
+		u.move(u.listRows())
+	case "home", "g":
+		u.move(-len(u.view))
+	case "end", "G":
+		u.move(len(u.view))
+	case " ":
+		u.toggle()
+	case "d", "delete":
+		u.confirmDelete()
+	case "a":
+		u.prompt("Add: ", "", u.add)
+	case "e":
+		if i := u.selected(); i != -1 {
+			id := u.ts[i].ID
+			u.prompt("Title: ", u.ts[i].Title, func(text string) { u.rename(id, text) })
+		}
+	case "/":
+		u.prompt("Filter: ", u.filter, func(text string) {
+			u.filter = strings.TrimSpace(text)
+			u.refresh()
+		})
+	case "esc":
+		if u.filter != "" {
+			u.filter = ""
+			u.refresh()
+		}
+	}
+	return false, false
+}
+
+func (u *ui) prompt(prompt, text string, done func(string)) {
+	u.input = &uiInput{prompt: prompt, text: []rune(text), done: done}
+}
+
+// editInput applies a key to the line being typed. Enter hands the line
+// over; Esc and Ctrl-C drop it.
+func (u *ui) editInput(k string) {
+	in := u.input
+	switch k {
+	case "enter":
+		u.input = nil
+		in.done(string(in.text))
+	case "esc", "ctrl-c":
+		u.input = nil
+	case "backspace":
+		if len(in.text) > 0 {
+			in.text = in.text[:len(in.text)-1]
+		}
+	default:
+		if utf8.RuneCountInString(k) == 1 {
+			in.text = append(in.text, []rune(k)...)
+		}
+	}
+}
+
+// selected returns the index in ts of the selected task, or -1.
+func (u *ui) selected() int {
+	if u.cur >= len(u.view) {
+		return -1
+	}
+	return u.ts.Index(u.view[u.cur])
+}
+
+func (u *ui) move(by int) {
+	u.cur = max(0, min(u.cur+by, len(u.view)-1))
+}
+
+// refresh rebuilds the view from ts and the filter, keeping the selection
+// on the same task when it is still shown.
+func (u *ui) refresh() {
+	var keep int64 = -1
+	if u.cur < len(u.view) {
+		keep = u.view[u.cur]
+	}
+	u.ts.MarkBlocked()
+	shown := u.ts
+	if q := dupKey(u.filter); q != "" {
+		shown = u.ts.Filter(func(t Task) bool {
+			return strings.Contains(dupKey(t.Title+" "+strings.Join(t.Tags, " ")), q)
+		})
+	} else {
+		shown = slices.Clone(shown)
+	}
+	sortForDisplay(shown)
+	u.view = u.view[:0]
+	for _, t := range shown {
+		u.view = append(u.view, t.ID)
+	}
+	if i := slices.Index(u.view, keep); i != -1 {
+		u.cur = i
+	}
+	u.move(0)
+}
+
+// toggle completes the selected task or reopens it. Completion follows the
+// rules of todo do: open subtasks and pending dependencies refuse it, and a
+// recurring task gets its next occurrence.
+func (u *ui) toggle() {
+	i := u.selected()
+	if i == -1 {
+		return
+	}
+	t := &u.ts[i]
+	if t.Done {
+		t.Done, t.CompletedAt = false, nil
+		u.changes++
+		u.status = fmt.Sprintf("Reopened %d", t.ID)
+		u.refresh()
+		return
+	}
+	if err := checkCompletable(u.ts, t.ID, nil); err != nil {
+		u.status = err.Error()
+		return
+	}
+	now := time.Now()
+	t.Done, t.CompletedAt = true, &now
+	u.changes++
+	u.status = fmt.Sprintf("Marked %d done", t.ID)
+	if t.Repeat != "" {
+		n, err := nextOccurrence(*t, u.ts.NextID(), now)
+		if err != nil {
+			u.status = fmt.Sprintf("task %d: %v", t.ID, err)
+		} else {
+			u.ts = append(u.ts, n)
+			u.status += fmt.Sprintf("; next occurrence: %d due %s", n.ID, formatDate(*n.DueDate))
+		}
+	}
+	u.refresh()
+}
+
+func (u *ui) confirmDelete() {
+	i := u.selected()
+	if i == -1 {
+		return
+	}
+	id, title := u.ts[i].ID, u.ts[i].Title
+	u.status = fmt.Sprintf("Delete %d: %s? [y/N]", id, title)
+	u.answer = func(yes bool) {
+		if !yes {
+			return
+		}
+		i := u.ts.Index(id)
+		removed := Tasks{u.ts[i]}
+		u.ts = append(u.ts[:i], u.ts[i+1:]...)
+		detachRemoved(u.ts, removed)
+		u.removed = append(u.removed, removed...)
+		u.changes++
+		u.status = fmt.Sprintf("Removed %d", id)
+		u.refresh()
+	}
+}
+
+func (u *ui) add(text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	title, err := normalizeTitle(text)
+	if err != nil {
+		u.status = err.Error()
+		return
+	}
+	t := Task{ID: u.ts.NextID(), Title: title, CreatedAt: time.Now()}
+	u.ts = append(u.ts, t)
+	u.changes++
+	u.status = fmt.Sprintf("Added %d: %s", t.ID, title)
+	u.view = append(u.view[:0], t.ID)
+	u.cur = 0
+	u.refresh()
+}
+
+func (u *ui) rename(id int64, text string) {
+	i := u.ts.Index(id)
+	title, err := normalizeTitle(text)
+	switch {
+	case err != nil:
+		u.status = err.Error()
+	case i != -1 && title != u.ts[i].Title:
+		u.ts[i].Title = title
+		u.changes++
+		u.status = fmt.Sprintf("Renamed %d: %s", id, title)
+		u.refresh()
+	}
+}
+
+// listRows is how many tasks fit between the header and the bottom line.
+func (u *ui) listRows() int {
+	return max(1, u.rows-2)
+}
+
+// draw repaints the whole screen: a header, the visible part of the view
+// and a bottom line with the input, the status or the keys.
+func (u *ui) draw() {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	header := fmt.Sprintf("todo: %d tasks", len(u.view))
+	if u.filter != "" {
+		header += fmt.Sprintf(" matching %q (Esc clears)", u.filter)
+	}
+	if u.changes > 0 {
+		header += fmt.Sprintf(", %d unsaved changes", u.changes)
+	}
+	b.WriteString(paint(clip(header, u.cols), ansiBold) + "\r\n")
+
+	n := u.listRows()
+	u.top = max(0, min(u.top, u.cur, len(u.view)-n))
+	if u.cur >= u.top+n {
+		u.top = u.cur - n + 1
+	}
+	switch {
+	case len(u.ts) == 0:
+		b.WriteString(clip("No tasks yet. Press a to add one, or q to quit.", u.cols) + "\r\n")
+	case len(u.view) == 0:
+		b.WriteString(clip(fmt.Sprintf("No tasks match %q. Press / to change the filter or Esc to clear it.", u.filter), u.cols) + "\r\n")
+	}
+	for row := u.top; row < len(u.view) && row < u.top+n; row++ {
+		t := u.ts[u.ts.Index(u.view[row])]
+		line := clip(taskLine(t), u.cols)
+		switch {
+		case row == u.cur:
+			// reverse video marks the selection even without color
+			line = "\x1b[7m" + line + "\x1b[0m"
+		case t.Done:
+			line = paint(line, ansiDim)
+		case t.IsOverdue(time.Now()):
+			line = paint(line, ansiRed)
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	fmt.Fprintf(&b, "\x1b[%d;1H", u.rows)
+	switch {
+	case u.input != nil:
+		b.WriteString(clip(u.input.prompt+string(u.input.text), u.cols-1) + "\x1b[7m \x1b[0m")
+	case u.status != "":
+		b.WriteString(clip(u.status, u.cols))
+	default:
+		b.WriteString(paint(clip("j/k move  space done  a add  e edit  d delete  / filter  q save and quit  ^C discard", u.cols), ansiDim))
+	}
+	fmt.Print(b.String())
+}
+
+// clip cuts s to at most width characters.
+func clip(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if r := []rune(s); len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+// url.go
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// urlRE finds the links in a title. Punctuation that ends a sentence is
+// trimmed from a match by titleURL.
+var urlRE = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// titleURL returns the link in a title that has exactly one, or "".
+func titleURL(title string) string {
+	found := urlRE.FindAllString(title, 2)
+	if len(found) != 1 {
+		return ""
+	}
+	u := strings.TrimRight(found[0], ".,;:!?)]'")
+	if checkURL(u) != nil {
+		return ""
+	}
+	return u
+}
+
+// checkURL accepts the http and https links a task can be opened with.
+func checkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return usageErrorf("invalid URL %q: expected an http or https link", s)
+	}
+	return nil
+}
+
+var openFlags = []flagDef{valueFlag("attachment", "a")}
+
+// cmdOpen opens a task's link in the default browser, or with --attachment
+// one of its files in the application for it, without waiting for either.
+func cmdOpen(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, openFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) != 1 {
+		return usageError("open")
+	}
+	id, err := parseID(ca.pos[0])
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	if ca.has("attachment") {
+		n, err := attachmentIndex(ts[i], ca.value("attachment"))
+		if err != nil {
+			return err
+		}
+		path := ts[i].Attachments[n]
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("%s: %v", path, errorText(err))
+		}
+		if err := openExternal(path); err != nil {
+			return err
+		}
+		say("Opened %s\n", path)
+		return nil
+	}
+	if ts[i].URL == "" {
+		return fmt.Errorf("task %d has no URL; add one with todo edit %d --url <url>", id, id)
+	}
+	if err := openExternal(ts[i].URL); err != nil {
+		return err
+	}
+	say("Opened %s\n", ts[i].URL)
+	return nil
+}
+
+// openExternal starts the desktop's handler for a link or a file: xdg-open,
+// open on macOS or the URL handler of Windows. It returns once the handler
+// has started.
+func openExternal(link string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", link)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+	default:
+		cmd = exec.Command("xdg-open", link)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("could not open %s: %v", link, err)
+	}
+	return cmd.Process.Release()
+}
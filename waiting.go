@@ -0,0 +1,138 @@
+// waiting.go
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// cmdWait records who or what a task is waiting on, which takes it out of
+// the default list until unwait or until it is completed.
+func cmdWait(args []string) error {
+	_ = args
+	if len(args) < 2 {
+		return usageError("wait")
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	who := strings.Join(strings.Fields(strings.Join(args[1:], " ")), " ")
+	if who == "" {
+		return usageError("wait")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	i := ts.Index(id)
+	if i == -1 {
+		return notFoundErrorf("task %d not found", id)
+	}
+	t := &ts[i]
+	if t.Done {
+		return fmt.Errorf("task %d is completed; reopen it first", id)
+	}
+	if t.Waiting == who {
+		say("Task %d is already waiting for %s.\n", id, who)
+		return nil
+	}
+	now := time.Now()
+	t.Waiting, t.WaitingSince = who, &now
+	// nobody works on a task while it is with someone else
+	running := t.Running()
+	d := t.Stop(now)
+	if err := saveTasks(ts); err != nil {
+		return err
+	}
+	if running {
+		say("Stopped %d after %s\n", id, formatTracked(d))
+	}
+	say("Task %d is waiting for %s\n", id, who)
+	return nil
+}
+
+// cmdUnwait brings waiting tasks back to the list, as tasks to do.
+func cmdUnwait(args []string) error {
+	_ = args
+	if len(args) == 0 {
+		return usageError("unwait")
+	}
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	var back, missing []int64
+	for _, id := range ids.ids {
+		i := ts.Index(id)
+		switch {
+		case i == -1:
+			missing = append(missing, id)
+		case ts[i].Waiting == "":
+			say("Task %d is not waiting.\n", id)
+		default:
+			ts[i].Waiting, ts[i].WaitingSince, ts[i].Status = "", nil, ""
+			back = append(back, id)
+		}
+	}
+	if len(back) > 0 {
+		if err := saveTasks(ts); err != nil {
+			return err
+		}
+	}
+	for _, id := range back {
+		say("Task %d is no longer waiting\n", id)
+	}
+	return ids.notFound(missing)
+}
+
+var waitingFlags = []flagDef{jsonFlag, jsonlFlag, colorFlag}
+
+// cmdWaiting lists the waiting tasks, longest waiting first.
+func cmdWaiting(args []string) error {
+	_ = args
+	ca, err := parseArgs(args, waitingFlags...)
+	if err != nil {
+		return err
+	}
+	if len(ca.pos) > 0 {
+		return usageError("waiting")
+	}
+	ts, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	waiting := ts.Filter(func(t Task) bool { return !t.Done && t.Waiting != "" })
+	if wantsJSON(ca) || len(waiting) == 0 {
+		return printTasks(ca, waiting, "No waiting tasks.")
+	}
+	if err := setupColor(ca.value("color")); err != nil {
+		return err
+	}
+	sortForDisplay(waiting)
+	waiting.SortBy("waiting", false)
+	for _, t := range waiting {
+		printTask(t)
+	}
+	return nil
+}
+
+// waitedFor says how long a task has been waiting, in calendar days.
+func waitedFor(t Task, now time.Time) string {
+	if t.WaitingSince == nil {
+		return "since an unknown date"
+	}
+	switch n := calendarDays(*t.WaitingSince, now); n {
+	case 0:
+		return "since today"
+	case 1:
+		return "1 day"
+	default:
+		return fmt.Sprintf("%d days", n)
+	}
+}
@@ -0,0 +1,76 @@
+// watch.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// watchInterval is how often list --watch looks for changes.
+const watchInterval = time.Second
+
+// watchList runs show whenever the list's files change, on a cleared
+// screen, until Ctrl-C. The files are polled by path rather than watched,
+// so one replaced by a rename is still seen.
+func watchList(show func() error) error {
+	paths, err := watchedPaths()
+	if err != nil {
+		return err
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	defer signal.Stop(stop)
+	tick := time.NewTicker(watchInterval)
+	defer tick.Stop()
+	last := ""
+	for {
+		if sig := filesSignature(paths); sig != last {
+			last = sig
+			if isTerminal(os.Stdout) {
+				fmt.Print("\x1b[H\x1b[2J")
+			}
+			// a file caught halfway through a save is read again on the
+			// next change, so errors don't end the watch
+			if err := show(); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+			fmt.Printf("\nLast updated %s. Press Ctrl-C to stop.\n", time.Now().Format("15:04:05"))
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-tick.C:
+		}
+	}
+}
+
+// watchedPaths lists the files a change to the current list can touch, in
+// any backend, along with its archive.
+func watchedPaths() ([]string, error) {
+	path, err := tasksFilePath()
+	if err != nil {
+		return nil, err
+	}
+	archive, err := companionPath("archive")
+	if err != nil {
+		return nil, err
+	}
+	db := sqlitePath(path)
+	return []string{path, db, db + "-wal", journalPath(path), archive}, nil
+}
+
+// filesSignature sums up the size and modification time of each path, so
+// that any change to one of them changes the result.
+func filesSignature(paths []string) string {
+	sig := ""
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err == nil {
+			sig += fmt.Sprintf("%d:%d;", fi.ModTime().UnixNano(), fi.Size())
+		} else {
+			sig += "-;"
+		}
+	}
+	return sig
+}
@@ -0,0 +1,168 @@
+// webhook.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EternalKnight002/todo-cli/todo"
+)
+
+// webhookTimeout bounds the webhook requests of one command all together,
+// so a slow endpoint never holds up the command for longer.
+const webhookTimeout = 2 * time.Second
+
+// webhookParallel is how many events are posted at once.
+const webhookParallel = 4
+
+// webhookEventNames are the events webhook_events can pick from.
+var webhookEventNames = []string{"add", "done", "rm"}
+
+// webhookEvent is the JSON body posted to webhook_url for each event.
+type webhookEvent struct {
+	Event     string    `json:"event"`
+	Task      Task      `json:"task"`
+	Timestamp time.Time `json:"timestamp"`
+	// Text lets a Slack incoming webhook show the event as it is.
+	Text string `json:"text"`
+}
+
+// pendingEvents collects the events of the saves a command makes, to be
+// sent once it has succeeded. noWebhook, set by --no-webhook, stops them.
+var (
+	pendingEvents []webhookEvent
+	noWebhook     bool
+)
+
+// relocatingCommands take tasks out of the list without deleting them, so
+// the tasks they remove are not rm events.
+var relocatingCommands = map[string]bool{"archive": true, "move": true}
+
+// taskEvents lists the events in saving ts: the tasks added, completed and
+// removed since the list was loaded.
+func taskEvents(ts Tasks, now time.Time) []webhookEvent {
+	if loaded == nil || config.WebhookURL == "" || noWebhook {
+		return nil
+	}
+	wanted := strings.Split(config.WebhookEvents, ",")
+	var events []webhookEvent
+	add := func(name, verb string, t Task) {
+		if config.WebhookEvents == "" || slices.Contains(wanted, name) {
+			events = append(events, webhookEvent{name, t, now, fmt.Sprintf("%s %d: %s", verb, t.ID, t.Title)})
+		}
+	}
+	kept := map[string]bool{}
+	for _, t := range ts {
+		key := taskKey(t)
+		kept[key] = true
+		old, ok := loaded[key]
+		switch {
+		case !ok:
+			add("add", "Added", t)
+		case t.Done && !old.done:
+			add("done", "Completed", t)
+		}
+	}
+	for key, old := range loaded {
+		if kept[key] {
+			continue
+		}
+		var t Task
+		if json.Unmarshal(old.data, &t) == nil {
+			t.ID = old.id
+			add("rm", "Removed", t)
+		}
+	}
+	return events
+}
+
+// sendEvents posts the events collected while cmd ran. A failure is only
+// a warning: the command has done its work either way.
+func sendEvents(cmd string) {
+	events := pendingEvents
+	pendingEvents = nil
+	if relocatingCommands[cmd] {
+		events = slices.DeleteFunc(events, func(e webhookEvent) bool { return e.Event == "rm" })
+	}
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	errs := make([]error, len(events))
+	sem := make(chan struct{}, webhookParallel)
+	var wg sync.WaitGroup
+	for i, e := range events {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = postEvent(ctx, config.WebhookURL, e)
+		})
+	}
+	wg.Wait()
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	switch {
+	case len(failed) == 1 && len(events) == 1:
+		fmt.Fprintln(os.Stderr, "Warning: webhook:", failed[0])
+	case len(failed) > 0:
+		fmt.Fprintf(os.Stderr, "Warning: webhook: %d of %d events not sent: %v\n", len(failed), len(events), failed[0])
+	}
+}
+
+func postEvent(ctx context.Context, url string, e webhookEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-cli")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// cmdWebhook runs webhook test, which posts a sample event to webhook_url
+// and, unlike a command's events, fails when it can't.
+func cmdWebhook(args []string) error {
+	_ = args
+	if len(args) != 1 || args[0] != "test" {
+		return usageError("webhook")
+	}
+	if config.WebhookURL == "" {
+		return fmt.Errorf("no webhook_url is set; use todo config set webhook_url <url>")
+	}
+	now := time.Now()
+	t := Task{ID: 1, UID: todo.NewUID(), Title: "Test event from todo webhook test", CreatedAt: now, UpdatedAt: now}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	if err := postEvent(ctx, config.WebhookURL, webhookEvent{"test", t, now, "Test event from todo-cli"}); err != nil {
+		return err
+	}
+	say("Sent a test event to %s\n", config.WebhookURL)
+	return nil
+}